@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling an API while its circuit breaker is open
+var ErrCircuitOpen = errors.New("AI requests paused after repeated failures")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen blocks requests until the cooldown ends
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial request through after the cooldown
+	BreakerHalfOpen
+)
+
+// String returns the state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calling a failing API for a while instead of
+// hammering it on every block. The cooldown doubles each time a trial
+// request fails, up to maxCooldown.
+type CircuitBreaker struct {
+	threshold    int
+	baseCooldown time.Duration
+	maxCooldown  time.Duration
+
+	state     BreakerState
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	trial     bool // a half-open trial request is in flight
+	onChange  func()
+	mu        sync.Mutex
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive failures
+func NewCircuitBreaker(threshold int, baseCooldown, maxCooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold:    threshold,
+		baseCooldown: baseCooldown,
+		maxCooldown:  maxCooldown,
+		cooldown:     baseCooldown,
+	}
+}
+
+// OnStateChange sets a callback invoked after the state changes.
+// It runs on its own goroutine; read the new state with State.
+func (b *CircuitBreaker) OnStateChange(fn func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onChange = fn
+}
+
+// Allow reports whether a request may be sent now
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		// Only one trial request at a time
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a successful request and closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.cooldown = b.baseCooldown
+	b.trial = false
+	if b.state != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+// Failure records a failed request, opening the breaker when the threshold
+// is reached or when a half-open trial fails
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == BreakerHalfOpen {
+		b.cooldown = min(b.cooldown*2, b.maxCooldown)
+		b.open(b.cooldown)
+		return
+	}
+	if b.failures >= b.threshold {
+		b.open(b.cooldown)
+	}
+}
+
+// Abort records a request that ended without telling anything about the
+// API's health, such as one cancelled on shutdown
+func (b *CircuitBreaker) Abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// Trip opens the breaker immediately for d, e.g. for an invalid API key or
+// a quota error with Retry-After
+func (b *CircuitBreaker) Trip(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open(d)
+}
+
+// State returns the current state and, when open, the time requests resume
+func (b *CircuitBreaker) State() (BreakerState, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.openUntil
+}
+
+// open opens the breaker for d. Callers must hold b.mu.
+func (b *CircuitBreaker) open(d time.Duration) {
+	b.trial = false
+	b.openUntil = time.Now().Add(d)
+	b.setState(BreakerOpen)
+}
+
+// setState changes the state and notifies the callback. Callers must hold b.mu.
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	if b.onChange != nil {
+		// Run outside the lock so the callback may query the breaker. Each
+		// callback reads the state when it runs, so the last one always sees
+		// the latest state even if callbacks run out of order.
+		go b.onChange()
+	}
+}
+
+// BreakerReporter is implemented by providers that guard their API with a circuit breaker
+type BreakerReporter interface {
+	Breaker() *CircuitBreaker
+}
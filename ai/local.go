@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Default endpoints for local model servers
+const (
+	DefaultOllamaEndpoint   = "http://localhost:11434"
+	DefaultLlamaCppEndpoint = "http://localhost:8080"
+)
+
+// localTimeout is longer than DefaultTimeout since local models often run on CPU
+const localTimeout = 3 * DefaultTimeout
+
+// OllamaClient talks to a local Ollama server through its native generate API
+type OllamaClient struct {
+	endpoint   string
+	model      string
+	httpClient *http.Client
+}
+
+type ollamaRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+type ollamaResponse struct {
+	Response string `json:"response"`
+}
+
+// NewOllamaClient creates a client for a local Ollama server
+func NewOllamaClient(endpoint, model string) *OllamaClient {
+	if endpoint == "" {
+		endpoint = DefaultOllamaEndpoint
+	}
+
+	return &OllamaClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		model:    model,
+		httpClient: &http.Client{
+			Timeout: localTimeout,
+		},
+	}
+}
+
+// Complete generates a reply without streaming
+func (c *OllamaClient) Complete(ctx context.Context, prompt string) (text string, err error) {
+	start := time.Now()
+	defer func() { ObserveRequest(ProviderOllama, start, err) }()
+	reqBody := ollamaRequest{Model: c.model, Prompt: prompt, Stream: false}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, c.httpClient, c.endpoint+"/api/generate", nil, reqBody, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Response == "" {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return resp.Response, nil
+}
+
+// LlamaCppClient talks to a local llama.cpp server through its completion API
+type LlamaCppClient struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+type llamaCppRequest struct {
+	Prompt   string `json:"prompt"`
+	NPredict int    `json:"n_predict"`
+}
+
+type llamaCppResponse struct {
+	Content string `json:"content"`
+}
+
+// NewLlamaCppClient creates a client for a local llama.cpp server.
+// The server serves a single model, so none is selected here.
+func NewLlamaCppClient(endpoint string) *LlamaCppClient {
+	if endpoint == "" {
+		endpoint = DefaultLlamaCppEndpoint
+	}
+
+	return &LlamaCppClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		httpClient: &http.Client{
+			Timeout: localTimeout,
+		},
+	}
+}
+
+// Complete generates a short reply
+func (c *LlamaCppClient) Complete(ctx context.Context, prompt string) (text string, err error) {
+	start := time.Now()
+	defer func() { ObserveRequest(ProviderLlamaCpp, start, err) }()
+	reqBody := llamaCppRequest{Prompt: prompt, NPredict: 200}
+
+	var resp llamaCppResponse
+	if err := postJSON(ctx, c.httpClient, c.endpoint+"/completion", nil, reqBody, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Content == "" {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return resp.Content, nil
+}
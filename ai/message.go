@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessageClient turns any Completer into a MessageProvider, falling back to
+// the last successful or default message when the model is unavailable
+type MessageClient struct {
+	completer   Completer
+	personality string
+	lastMessage string
+	mu          sync.Mutex
+}
+
+// NewMessageClient creates a message provider backed by completer
+func NewMessageClient(completer Completer, personality string) *MessageClient {
+	return &MessageClient{
+		completer:   completer,
+		personality: personality,
+	}
+}
+
+// Breaker returns the circuit breaker of the completer, or nil if it has
+// none, implementing BreakerReporter
+func (m *MessageClient) Breaker() *CircuitBreaker {
+	if reporter, ok := m.completer.(BreakerReporter); ok {
+		return reporter.Breaker()
+	}
+	return nil
+}
+
+// GetMotivationalMessage asks the model for a message, with fallback
+func (m *MessageClient) GetMotivationalMessage(blockedApp, personality string) string {
+	if personality == "" {
+		personality = m.personality
+	}
+
+	// The completer's HTTP client bounds the request time
+	message, err := m.FetchMessage(context.Background(), BuildPrompt(personality, blockedApp, time.Now()))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		// Fallback to last successful message or default
+		if m.lastMessage != "" {
+			return m.lastMessage
+		}
+		return DefaultMessage(personality, blockedApp)
+	}
+
+	m.lastMessage = message
+	return message
+}
+
+// FetchMessage asks the model for a fresh message without any fallback,
+// implementing MessageFetcher
+func (m *MessageClient) FetchMessage(ctx context.Context, prompt string) (string, error) {
+	message, err := m.completer.Complete(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", fmt.Errorf("empty message")
+	}
+	return message, nil
+}
+
+// TemplateProvider serves built-in messages without any network access
+type TemplateProvider struct {
+	personality string
+}
+
+// NewTemplateProvider creates an offline message provider
+func NewTemplateProvider(personality string) *TemplateProvider {
+	return &TemplateProvider{personality: personality}
+}
+
+// GetMotivationalMessage returns the default message for the personality
+func (t *TemplateProvider) GetMotivationalMessage(blockedApp, personality string) string {
+	if personality == "" {
+		personality = t.personality
+	}
+	return DefaultMessage(personality, blockedApp)
+}
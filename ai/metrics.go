@@ -0,0 +1,30 @@
+package ai
+
+import (
+	"appblock/metrics"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AI request metrics, exposed by the metrics endpoint when enabled
+var (
+	requestDuration = metrics.MustRegister(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "appblock_ai_request_duration_seconds",
+		Help:    "AI API request latency, by provider",
+		Buckets: metrics.DefBuckets,
+	}, []string{"provider"}))
+	requestErrorsTotal = metrics.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appblock_ai_request_errors_total",
+		Help: "Failed AI API requests, by provider",
+	}, []string{"provider"}))
+)
+
+// ObserveRequest records an AI API request to provider that started at
+// start, counting it as an error when err is not nil
+func ObserveRequest(provider string, start time.Time, err error) {
+	requestDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestErrorsTotal.WithLabelValues(provider).Inc()
+	}
+}
@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIEndpoint is used when no endpoint is configured for the openai provider
+const DefaultOpenAIEndpoint = "https://api.openai.com/v1"
+
+// OpenAIClient talks to any OpenAI-compatible chat completions endpoint
+// (OpenAI, OpenRouter, Groq, LM Studio, vLLM, ...)
+type OpenAIClient struct {
+	endpoint   string
+	keyName    string
+	model      string
+	httpClient *http.Client
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// NewOpenAIClient creates a client for an OpenAI-compatible endpoint.
+// keyName names the API key looked up with APIKey on each request;
+// requests are sent without a key when it is not set, for local servers.
+func NewOpenAIClient(endpoint, keyName, model string) *OpenAIClient {
+	if endpoint == "" {
+		endpoint = DefaultOpenAIEndpoint
+	}
+
+	return &OpenAIClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		keyName:  keyName,
+		model:    model,
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+	}
+}
+
+// Complete sends the prompt as a single user message
+func (c *OpenAIClient) Complete(ctx context.Context, prompt string) (text string, err error) {
+	start := time.Now()
+	defer func() { ObserveRequest(ProviderOpenAI, start, err) }()
+	headers := map[string]string{}
+	if apiKey := APIKey(ctx, c.keyName); apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+
+	reqBody := chatRequest{
+		Model:    c.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	}
+
+	var resp chatResponse
+	if err := postJSON(ctx, c.httpClient, c.endpoint+"/chat/completions", headers, reqBody, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
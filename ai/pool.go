@@ -0,0 +1,366 @@
+package ai
+
+import (
+	"appblock/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// poolSize is the number of unseen messages kept ready per personality and app
+	poolSize = 3
+	// poolMaxAge is how long a prefetched message counts as fresh
+	poolMaxAge = 24 * time.Hour
+	// poolHistorySize is the number of shown messages remembered per key,
+	// reused in rotation when offline and used to drop repeated fetches
+	poolHistorySize = 10
+	// poolRetryDelay pauses prefetching after a failed fetch
+	poolRetryDelay = time.Minute
+)
+
+// pooledMessage is a prefetched message
+type pooledMessage struct {
+	Text      string    `json:"text"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// poolEntry holds the messages for one language, personality and blocked app
+type poolEntry struct {
+	Language    string          `json:"language,omitempty"`
+	Personality string          `json:"personality"`
+	App         string          `json:"app"`
+	Ready       []pooledMessage `json:"ready"` // fetched, not shown yet
+	Shown       []pooledMessage `json:"shown"` // shown before, oldest first
+}
+
+// MessagePool serves motivational messages from a prefetched pool so a popup
+// never waits on the network. A background worker refills the pool and the
+// pool is persisted to disk for offline use.
+type MessagePool struct {
+	fetcher     MessageFetcher
+	personality string
+	path        string
+	entries     map[string]*poolEntry
+	refill      chan string
+	pending     map[string]bool
+	live        bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	mu          sync.Mutex
+}
+
+// NewMessagePool creates a pool backed by fetcher, persisted at path
+func NewMessagePool(fetcher MessageFetcher, personality, path string) *MessagePool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &MessagePool{
+		fetcher:     fetcher,
+		personality: personality,
+		path:        path,
+		entries:     make(map[string]*poolEntry),
+		refill:      make(chan string, 64),
+		pending:     make(map[string]bool),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	p.load()
+	return p
+}
+
+// Start starts the background prefetch worker and tops up persisted entries
+func (p *MessagePool) Start() {
+	go p.run()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.entries {
+		p.requestRefill(key)
+	}
+}
+
+// Stop stops the prefetch worker, cancelling any fetch in flight, and saves the pool
+func (p *MessagePool) Stop() {
+	p.cancel()
+	p.save()
+}
+
+// Breaker returns the circuit breaker of the underlying fetcher, or nil if it has none
+func (p *MessagePool) Breaker() *CircuitBreaker {
+	if reporter, ok := p.fetcher.(BreakerReporter); ok {
+		return reporter.Breaker()
+	}
+	return nil
+}
+
+// SetLive makes popups ask the model for a message when the app is
+// blocked, with the block-time details in the prompt. The pool is still
+// filled and used when that request fails.
+func (p *MessagePool) SetLive(live bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.live = live
+}
+
+// Warm queues prefetching for apps that have no pooled messages yet
+func (p *MessagePool) Warm(apps []string, personality string) {
+	if personality == "" {
+		personality = p.personality
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, app := range apps {
+		p.requestRefill(p.entry(personality, app).key())
+	}
+}
+
+// GetMotivationalMessage returns a pooled message without touching the network.
+// It prefers a fresh unseen message, then rotates through previously shown
+// ones (offline), and finally falls back to the default message.
+//
+// With SetLive, the message is fetched now instead, and the pool is only
+// used when that fails.
+func (p *MessagePool) GetMotivationalMessage(blockedApp, personality string) string {
+	if personality == "" {
+		personality = p.personality
+	}
+
+	p.mu.Lock()
+	live := p.live
+	p.mu.Unlock()
+
+	if live {
+		text, err := p.fetchNow(blockedApp, personality)
+		if err == nil {
+			p.mu.Lock()
+			p.entry(personality, blockedApp).remember(pooledMessage{Text: text, FetchedAt: time.Now()})
+			p.mu.Unlock()
+			return text
+		}
+		utils.LogWarning("Failed to fetch message for %s, using the pool: %v", blockedApp, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.entry(personality, blockedApp)
+	defer p.requestRefill(e.key())
+
+	// Drop stale messages, keeping them for offline rotation
+	var ready []pooledMessage
+	for _, m := range e.Ready {
+		if time.Since(m.FetchedAt) < poolMaxAge {
+			ready = append(ready, m)
+		} else {
+			e.remember(m)
+		}
+	}
+	e.Ready = ready
+
+	if len(e.Ready) > 0 {
+		m := e.Ready[0]
+		e.Ready = e.Ready[1:]
+		e.remember(m)
+		return m.Text
+	}
+
+	// Offline: rotate through shown messages, least recently shown first
+	if len(e.Shown) > 1 {
+		m := e.Shown[0]
+		e.remember(m)
+		return m.Text
+	}
+
+	return DefaultMessage(personality, blockedApp)
+}
+
+// fetchNow fetches a message with the details of the block happening now
+func (p *MessagePool) fetchNow(blockedApp, personality string) (string, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, DefaultTimeout)
+	defer cancel()
+
+	text, err := p.fetcher.FetchMessage(ctx, BuildPrompt(personality, blockedApp, time.Now()))
+	if err != nil {
+		return "", err
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", fmt.Errorf("empty message")
+	}
+	return text, nil
+}
+
+// run is the prefetch worker loop
+func (p *MessagePool) run() {
+	for {
+		select {
+		case key := <-p.refill:
+			if !p.fill(key) {
+				// Back off on failure, but stay responsive to Stop
+				select {
+				case <-time.After(poolRetryDelay):
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// fill fetches messages until the entry for key is full.
+// Returns false if a fetch failed.
+func (p *MessagePool) fill(key string) bool {
+	p.mu.Lock()
+	e := p.entries[key]
+	delete(p.pending, key)
+	if e == nil {
+		p.mu.Unlock()
+		return true
+	}
+	personality, app, lang := e.Personality, e.App, e.Language
+	missing := poolSize - len(e.Ready)
+	p.mu.Unlock()
+
+	// Messages are fetched in the current language; entries for another
+	// language are kept for when the user switches back
+	if lang != Language() {
+		return true
+	}
+
+	ok := true
+	for i := 0; i < missing; i++ {
+		ctx, cancel := context.WithTimeout(p.ctx, 3*DefaultTimeout)
+		text, err := p.fetcher.FetchMessage(ctx, BuildPrefetchPrompt(personality, app))
+		cancel()
+
+		if err != nil {
+			if p.ctx.Err() != nil {
+				return false
+			}
+			utils.LogWarning("Failed to prefetch message for %s: %v", app, err)
+			ok = false
+			break
+		}
+
+		p.mu.Lock()
+		e.add(strings.TrimSpace(text))
+		p.mu.Unlock()
+	}
+
+	p.save()
+	return ok
+}
+
+// entry returns the entry for personality and app in the current language,
+// creating it if needed. Callers must hold p.mu.
+func (p *MessagePool) entry(personality, app string) *poolEntry {
+	e := &poolEntry{Language: Language(), Personality: personality, App: strings.ToLower(app)}
+	if existing, ok := p.entries[e.key()]; ok {
+		return existing
+	}
+	p.entries[e.key()] = e
+	return e
+}
+
+// requestRefill queues key for prefetching unless already queued. Callers must hold p.mu.
+func (p *MessagePool) requestRefill(key string) {
+	if p.pending[key] {
+		return
+	}
+	if e := p.entries[key]; e == nil || len(e.Ready) >= poolSize {
+		return
+	}
+
+	select {
+	case p.refill <- key:
+		p.pending[key] = true
+	default:
+		// Queue full - the key is retried on the next request
+	}
+}
+
+// load reads the persisted pool
+func (p *MessagePool) load() {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return
+	}
+
+	var entries []*poolEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		utils.LogWarning("Ignoring corrupt message pool %s: %v", p.path, err)
+		return
+	}
+
+	for _, e := range entries {
+		if e.Language == "" {
+			// Saved before messages had a language
+			e.Language = DefaultLanguage
+		}
+		p.entries[e.key()] = e
+	}
+}
+
+// save persists the pool
+func (p *MessagePool) save() {
+	p.mu.Lock()
+	entries := make([]*poolEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		if len(e.Ready) > 0 || len(e.Shown) > 0 {
+			entries = append(entries, e)
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	p.mu.Unlock()
+
+	if err != nil {
+		utils.LogWarning("Failed to encode message pool: %v", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(p.path, data, 0644); err != nil {
+		utils.LogWarning("Failed to save message pool: %v", err)
+	}
+}
+
+// key identifies the entry
+func (e *poolEntry) key() string {
+	return e.Language + "\x00" + e.Personality + "\x00" + e.App
+}
+
+// add stores a fetched message unless it repeats a ready or recently shown one
+func (e *poolEntry) add(text string) {
+	if text == "" {
+		return
+	}
+	for _, m := range e.Ready {
+		if m.Text == text {
+			return
+		}
+	}
+	for _, m := range e.Shown {
+		if m.Text == text {
+			return
+		}
+	}
+	e.Ready = append(e.Ready, pooledMessage{Text: text, FetchedAt: time.Now()})
+}
+
+// remember moves m to the end of the shown history, trimming the oldest
+func (e *poolEntry) remember(m pooledMessage) {
+	shown := e.Shown[:0:0]
+	for _, s := range e.Shown {
+		if s.Text != m.Text {
+			shown = append(shown, s)
+		}
+	}
+	shown = append(shown, m)
+	if len(shown) > poolHistorySize {
+		shown = shown[len(shown)-poolHistorySize:]
+	}
+	e.Shown = shown
+}
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// promptRecorder is a MessageFetcher that remembers the prompts it was sent
+type promptRecorder struct {
+	prompts []string
+	fail    error
+	mu      sync.Mutex
+}
+
+func (r *promptRecorder) FetchMessage(ctx context.Context, prompt string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail != nil {
+		return "", r.fail
+	}
+	r.prompts = append(r.prompts, prompt)
+	return "message " + string(rune('A'+len(r.prompts)-1)), nil
+}
+
+func (r *promptRecorder) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.prompts...)
+}
+
+// waitSent waits until r was sent n prompts
+func (r *promptRecorder) waitSent(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(r.sent()) < n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	prompts := r.sent()
+	if len(prompts) != n {
+		t.Fatalf("sent %d prompts, want %d", len(prompts), n)
+	}
+	return prompts
+}
+
+// useBundledTemplates loads the bundled English templates and a prompt
+// context as it is at block time
+func useBundledTemplates(t *testing.T) {
+	t.Helper()
+	if err := InitTemplates(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	SetLanguage(LanguageEnglish)
+	SetPromptContext(func(data *TemplateData, now time.Time) {
+		data.Attempts, data.MinutesLeft, data.Goal, data.LastMessage = 3, 20, "finish the thesis", "Earlier message"
+	})
+	t.Cleanup(func() {
+		SetLanguage("")
+		SetPromptContext(nil)
+		templatesMu.Lock()
+		bundles = nil
+		templatesMu.Unlock()
+	})
+}
+
+func TestPoolPrefetchesBundledPrompt(t *testing.T) {
+	useBundledTemplates(t)
+
+	recorder := &promptRecorder{}
+	pool := NewMessagePool(recorder, "programmer", filepath.Join(t.TempDir(), "messages.json"))
+	pool.Start()
+	defer pool.Stop()
+	pool.Warm([]string{"steam.exe"}, "")
+
+	for _, prompt := range recorder.waitSent(t, poolSize) {
+		// The details of when the message is fetched would be wrong when it is shown
+		for _, detail := range []string{"closed at", "attempt number", "minutes of productive time left", "Earlier message"} {
+			if strings.Contains(prompt, detail) {
+				t.Errorf("prefetch prompt contains block-time detail %q:\n%s", detail, prompt)
+			}
+		}
+		if !strings.Contains(prompt, `"steam.exe" was just closed because`) || !strings.Contains(prompt, "finish the thesis") {
+			t.Errorf("prefetch prompt lacks the app or the goal:\n%s", prompt)
+		}
+	}
+
+	// The popup is served from the pool without another request
+	if got := pool.GetMotivationalMessage("steam.exe", ""); got != "message A" {
+		t.Errorf("message = %q, want the first prefetched one", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(recorder.sent()); n > poolSize+1 {
+		t.Errorf("sent %d prompts, want at most one refill", n)
+	}
+}
+
+func TestPoolLiveMessages(t *testing.T) {
+	useBundledTemplates(t)
+
+	recorder := &promptRecorder{}
+	pool := NewMessagePool(recorder, "programmer", filepath.Join(t.TempDir(), "messages.json"))
+	pool.SetLive(true)
+	pool.Start()
+	defer pool.Stop()
+	pool.Warm([]string{"steam.exe"}, "")
+	recorder.waitSent(t, poolSize)
+
+	// Opted in: the message is written at block time, with its details
+	if got := pool.GetMotivationalMessage("steam.exe", ""); got != "message D" {
+		t.Fatalf("message = %q, want the one fetched at block time", got)
+	}
+	live := recorder.sent()[poolSize]
+	if !strings.Contains(live, "attempt number 3 today") || !strings.Contains(live, "Earlier message") {
+		t.Errorf("prompt does not carry the details at block time:\n%s", live)
+	}
+
+	// Offline: the pool is used, not the default message
+	recorder.mu.Lock()
+	recorder.fail = errors.New("offline")
+	recorder.mu.Unlock()
+	if got := pool.GetMotivationalMessage("steam.exe", ""); got != "message A" {
+		t.Errorf("message when the request fails = %q, want the first prefetched one", got)
+	}
+}
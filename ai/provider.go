@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"appblock/paths"
+	"appblock/secret"
+	"appblock/utils"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider names accepted in AIConfig.Provider
+const (
+	ProviderGemini   = "gemini"
+	ProviderOpenAI   = "openai"
+	ProviderOllama   = "ollama"
+	ProviderLlamaCpp = "llamacpp"
+	ProviderTemplate = "template"
+)
+
+// DefaultTimeout bounds a single request to a language model
+const DefaultTimeout = 8 * time.Second
+
+// MessageProvider produces the motivational message shown when an app is blocked.
+// Implementations never fail: they fall back to a default message instead.
+type MessageProvider interface {
+	// GetMotivationalMessage returns a message for the blocked app.
+	// An empty personality uses the provider's default personality.
+	GetMotivationalMessage(blockedApp, personality string) string
+}
+
+// MessageFetcher sends a message prompt to a model and returns the reply,
+// returning an error instead of falling back. Used by MessagePool, which
+// builds the prompts.
+type MessageFetcher interface {
+	FetchMessage(ctx context.Context, prompt string) (string, error)
+}
+
+// Completer sends a prompt to a language model and returns its reply
+type Completer interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// CompleterFunc adapts a function to a Completer
+type CompleterFunc func(ctx context.Context, prompt string) (string, error)
+
+// Complete calls f
+func (f CompleterFunc) Complete(ctx context.Context, prompt string) (string, error) {
+	return f(ctx, prompt)
+}
+
+// envWarning logs the .env deprecation warning once per run
+var envWarning sync.Once
+
+// LookupAPIKey returns the value of the named key from the environment,
+// the secret store, or (deprecated) a plaintext .env file in the config directory
+func LookupAPIKey(name string) string {
+	// 1. Try environment variable first
+	if key := os.Getenv(name); key != "" {
+		utils.RegisterSecret(key)
+		return key
+	}
+
+	// 2. Try the OS keyring or encrypted secrets file
+	key, err := secret.Get(name)
+	if err == nil {
+		utils.RegisterSecret(key)
+		return key
+	}
+	if !errors.Is(err, secret.ErrNotFound) {
+		utils.LogWarning("Failed to read %s from secret store: %v", name, err)
+	}
+
+	// 3. Try .env file in the config directory
+	envPath := filepath.Join(paths.ConfigDir(), ".env")
+
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		return ""
+	}
+
+	// Parse .env file (simple format: NAME=your-key-here)
+	prefix := name + "="
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, prefix) {
+			key := strings.TrimPrefix(line, prefix)
+			key = strings.TrimSpace(strings.Trim(key, "\"'")) // Remove quotes if present
+			utils.RegisterSecret(key)
+			envWarning.Do(func() {
+				utils.LogWarning("%s read from plaintext %s - move it to the secret store with: appblock key set %s", name, envPath, name)
+			})
+			return key
+		}
+	}
+
+	return ""
+}
+
+// requestKeysKey is the context key of the requestKeys installed by WithAPIKeys
+type requestKeysKey struct{}
+
+// requestKeys holds the API keys already looked up for one request
+type requestKeys struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// WithAPIKeys returns a context in which APIKey looks each key up at most
+// once, so a request and its retries do not read the keyring or decrypt the
+// secrets file again. ctx is returned as is when it already has one.
+func WithAPIKeys(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestKeysKey{}).(*requestKeys); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestKeysKey{}, &requestKeys{keys: make(map[string]string)})
+}
+
+// APIKey is LookupAPIKey, reusing the value already looked up in ctx when
+// it comes from WithAPIKeys
+func APIKey(ctx context.Context, name string) string {
+	rk, ok := ctx.Value(requestKeysKey{}).(*requestKeys)
+	if !ok {
+		return LookupAPIKey(name)
+	}
+
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	key, ok := rk.keys[name]
+	if !ok {
+		key = LookupAPIKey(name)
+		rk.keys[name] = key
+	}
+	return key
+}
@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures RetryingCompleter
+type RetryPolicy struct {
+	Attempts      int           // Tries per request, including the first
+	BaseDelay     time.Duration // Base of the exponential backoff between tries
+	MaxDelay      time.Duration // Cap of the backoff between tries
+	MaxRetryAfter time.Duration // Longest Retry-After worth waiting for in-request; longer ones open the breaker
+
+	BreakerThreshold   int           // Consecutive failed requests that open the breaker
+	BreakerCooldown    time.Duration // First time the breaker stays open
+	BreakerMaxCooldown time.Duration // Longest time the breaker stays open, also used for rejected keys
+}
+
+// DefaultRetryPolicy is used for every provider
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:      3,
+	BaseDelay:     500 * time.Millisecond,
+	MaxDelay:      4 * time.Second,
+	MaxRetryAfter: 10 * time.Second,
+
+	BreakerThreshold:   3,
+	BreakerCooldown:    time.Minute,
+	BreakerMaxCooldown: 30 * time.Minute,
+}
+
+// APIError is a non-200 response from a model API
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// temporary reports whether the request may succeed if retried
+func (e *APIError) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// NewAPIError builds the error for a non-200 response with its body
+func NewAPIError(resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// RetryingCompleter retries temporary failures of a Completer with jittered
+// exponential backoff, honoring Retry-After, and skips requests entirely
+// while its circuit breaker is open. Every provider's model goes through one.
+type RetryingCompleter struct {
+	completer Completer
+	policy    RetryPolicy
+	breaker   *CircuitBreaker
+}
+
+// NewRetryingCompleter wraps completer with retries and a circuit breaker
+func NewRetryingCompleter(completer Completer, policy RetryPolicy) *RetryingCompleter {
+	return &RetryingCompleter{
+		completer: completer,
+		policy:    policy,
+		breaker:   NewCircuitBreaker(policy.BreakerThreshold, policy.BreakerCooldown, policy.BreakerMaxCooldown),
+	}
+}
+
+// Breaker returns the circuit breaker guarding the model, implementing BreakerReporter
+func (r *RetryingCompleter) Breaker() *CircuitBreaker {
+	return r.breaker
+}
+
+// Complete sends the prompt, retrying temporary failures. The attempts share
+// the API keys looked up for the first one, see WithAPIKeys.
+func (r *RetryingCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	if !r.breaker.Allow() {
+		return "", ErrCircuitOpen
+	}
+	ctx = WithAPIKeys(ctx)
+
+	var err error
+	for attempt := 0; attempt < r.policy.Attempts; attempt++ {
+		var text string
+		text, err = r.completer.Complete(ctx, prompt)
+		if err == nil {
+			r.breaker.Success()
+			return text, nil
+		}
+
+		wait, retry := r.retryDelay(ctx, err, attempt)
+		if !retry || attempt == r.policy.Attempts-1 {
+			break
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	// A cancelled caller says nothing about the API's health
+	if errors.Is(ctx.Err(), context.Canceled) {
+		r.breaker.Abort()
+		return "", err
+	}
+
+	r.recordFailure(err)
+	return "", err
+}
+
+// retryDelay returns how long to wait before retrying after err, and whether
+// a retry is worthwhile at all
+func (r *RetryingCompleter) retryDelay(ctx context.Context, err error, attempt int) (time.Duration, bool) {
+	var wait time.Duration
+
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		if !apiErr.temporary() || apiErr.RetryAfter > r.policy.MaxRetryAfter {
+			return 0, false
+		}
+		wait = apiErr.RetryAfter
+	case ctx.Err() != nil:
+		// Out of time for this request
+		return 0, false
+	case errors.As(err, &netErr) && netErr.Timeout():
+		// A model that did not answer in time, e.g. a local one on a busy
+		// CPU, will not answer faster on the next try
+		return 0, false
+	}
+
+	if wait == 0 {
+		// Full jitter: a random delay up to the exponential backoff
+		backoff := min(r.policy.BaseDelay<<attempt, r.policy.MaxDelay)
+		wait = rand.N(backoff) + 1
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+	return wait, true
+}
+
+// recordFailure updates the circuit breaker after a request finally failed
+func (r *RetryingCompleter) recordFailure(err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			// A bad key will not fix itself quickly
+			r.breaker.Trip(r.policy.BreakerMaxCooldown)
+			return
+		case apiErr.RetryAfter > 0:
+			r.breaker.Trip(apiErr.RetryAfter)
+			return
+		}
+	}
+	r.breaker.Failure()
+}
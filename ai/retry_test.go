@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fastRetries retries without noticeable delays
+func fastRetries() RetryPolicy {
+	policy := DefaultRetryPolicy
+	policy.BaseDelay = time.Millisecond
+	return policy
+}
+
+// scriptedCompleter returns the queued errors in order, then "ok"
+type scriptedCompleter struct {
+	errs  []error
+	calls int
+	mu    sync.Mutex
+}
+
+func (s *scriptedCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return "", err
+	}
+	return "ok", nil
+}
+
+func TestRetryingCompleter(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	tests := []struct {
+		name    string
+		errs    []error
+		wantErr bool
+		calls   int
+		state   BreakerState
+	}{
+		{"success", nil, false, 1, BreakerClosed},
+		{"temporary failures", []error{unavailable, &APIError{StatusCode: http.StatusBadGateway}}, false, 3, BreakerClosed},
+		{"gives up after the attempts", []error{unavailable, unavailable, unavailable}, true, 3, BreakerClosed},
+		{"client error", []error{&APIError{StatusCode: http.StatusBadRequest}}, true, 1, BreakerClosed},
+		{"rejected key", []error{&APIError{StatusCode: http.StatusUnauthorized}}, true, 1, BreakerOpen},
+		{"long Retry-After", []error{&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Minute}}, true, 1, BreakerOpen},
+		{"connection refused", []error{errors.New("connection refused")}, false, 2, BreakerClosed},
+		{"timeout", []error{&timeoutError{}}, true, 1, BreakerClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &scriptedCompleter{errs: tt.errs}
+			r := NewRetryingCompleter(model, fastRetries())
+
+			_, err := r.Complete(context.Background(), "prompt")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if model.calls != tt.calls {
+				t.Errorf("model called %d times, want %d", model.calls, tt.calls)
+			}
+			if state, _ := r.Breaker().State(); state != tt.state {
+				t.Errorf("breaker state = %v, want %v", state, tt.state)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error for a request that ran out of time
+type timeoutError struct{}
+
+func (*timeoutError) Error() string   { return "Client.Timeout exceeded" }
+func (*timeoutError) Timeout() bool   { return true }
+func (*timeoutError) Temporary() bool { return true }
+
+func TestRetryingCompleterOpensBreaker(t *testing.T) {
+	failing := CompleterFunc(func(ctx context.Context, prompt string) (string, error) {
+		return "", &APIError{StatusCode: http.StatusInternalServerError}
+	})
+	r := NewRetryingCompleter(failing, fastRetries())
+
+	for i := 0; i < DefaultRetryPolicy.BreakerThreshold; i++ {
+		if _, err := r.Complete(context.Background(), "prompt"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("breaker opened after %d failures, want %d", i, DefaultRetryPolicy.BreakerThreshold)
+		}
+	}
+	if _, err := r.Complete(context.Background(), "prompt"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestRetryingCompleterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	model := &scriptedCompleter{errs: []error{context.Canceled}}
+	r := NewRetryingCompleter(model, fastRetries())
+
+	if _, err := r.Complete(ctx, "prompt"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if model.calls != 1 {
+		t.Errorf("model called %d times after cancel, want 1", model.calls)
+	}
+	if state, _ := r.Breaker().State(); state != BreakerClosed {
+		t.Errorf("a cancelled request changed the breaker to %v", state)
+	}
+}
+
+// Every OpenAI-compatible and local server goes through the same retries
+// and reports its breaker for the tray status
+func TestOpenAIClientRetries(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		if n == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Back to work!"}}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewMessageClient(NewRetryingCompleter(NewOpenAIClient(srv.URL, "", "model"), fastRetries()), "programmer")
+	if got, err := client.FetchMessage(context.Background(), BuildPrompt("programmer", "steam.exe", time.Now())); err != nil || got != "Back to work!" {
+		t.Fatalf("FetchMessage = %q, %v", got, err)
+	}
+	if requests != 2 {
+		t.Errorf("server received %d requests, want 2", requests)
+	}
+	if client.Breaker() == nil {
+		t.Error("message client does not report the breaker of its completer")
+	}
+	if NewMessageClient(NewOllamaClient(srv.URL, "model"), "").Breaker() != nil {
+		t.Error("breaker reported for a completer without one")
+	}
+}
+
+func TestRetriesReuseAPIKey(t *testing.T) {
+	t.Setenv("APPBLOCK_TEST_KEY", "first")
+
+	var mu sync.Mutex
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth = append(auth, r.Header.Get("Authorization"))
+		n := len(auth)
+		mu.Unlock()
+		if n == 1 {
+			// A key changed mid-request only applies to the next request
+			os.Setenv("APPBLOCK_TEST_KEY", "second")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	completer := NewRetryingCompleter(NewOpenAIClient(srv.URL, "APPBLOCK_TEST_KEY", "model"), fastRetries())
+	for range 2 {
+		if _, err := completer.Complete(context.Background(), "prompt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"Bearer first", "Bearer first", "Bearer second"}
+	if !slices.Equal(auth, want) {
+		t.Errorf("Authorization headers = %q, want %q", auth, want)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	t.Setenv("APPBLOCK_TEST_KEY", "first")
+	ctx := WithAPIKeys(context.Background())
+	if got := APIKey(ctx, "APPBLOCK_TEST_KEY"); got != "first" {
+		t.Fatalf("APIKey = %q", got)
+	}
+
+	os.Setenv("APPBLOCK_TEST_KEY", "second")
+	if got := APIKey(WithAPIKeys(ctx), "APPBLOCK_TEST_KEY"); got != "first" {
+		t.Errorf("APIKey in the same request = %q, want first", got)
+	}
+	if got := APIKey(context.Background(), "APPBLOCK_TEST_KEY"); got != "second" {
+		t.Errorf("APIKey without a request = %q, want second", got)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond, time.Second)
+
+	b.Failure()
+	if b.Allow() {
+		t.Fatal("open breaker allowed a request")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("breaker did not allow a trial after the cooldown")
+	}
+	if b.Allow() {
+		t.Error("half-open breaker allowed a second concurrent trial")
+	}
+
+	// A failed trial reopens with a longer cooldown
+	b.Failure()
+	if state, until := b.State(); state != BreakerOpen || time.Until(until) <= 10*time.Millisecond {
+		t.Errorf("after failed trial: state = %v, open for %v, want open with doubled cooldown", state, time.Until(until))
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("breaker did not allow a second trial")
+	}
+	b.Success()
+	if state, _ := b.State(); state != BreakerClosed {
+		t.Errorf("state after successful trial = %v, want closed", state)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := ParseRetryAfter("30"); got != 30*time.Second {
+		t.Errorf("seconds: got %v", got)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := ParseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("http date: got %v", got)
+	}
+	for _, value := range []string{"", "soon", "-5"} {
+		if got := ParseRetryAfter(value); got != 0 {
+			t.Errorf("ParseRetryAfter(%q) = %v, want 0", value, got)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package ai
+
+import (
+	"appblock/utils"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Languages with bundled templates
+const (
+	LanguageIndonesian = "id"
+	LanguageEnglish    = "en"
+	// DefaultLanguage is used when AIConfig.Language is empty
+	DefaultLanguage = LanguageIndonesian
+)
+
+// Template files in each language bundle
+const (
+	promptFile   = "prompt.tmpl"
+	messagesFile = "messages.tmpl"
+	classifyFile = "classify.tmpl"
+)
+
+// templateParsers validates each template file of a bundle
+var templateParsers = map[string]func(string) (*template.Template, error){
+	promptFile:   ParsePromptTemplate,
+	messagesFile: ParseMessageTemplate,
+	classifyFile: ParseClassifyTemplate,
+	weeklyFile:   ParseWeeklyTemplate,
+	upcomingFile: ParseUpcomingTemplate,
+}
+
+// bundledTemplates holds the templates shipped with the binary, one
+// directory per language. They are copied to the templates directory next
+// to the config on first run so users can edit them.
+//
+//go:embed templates
+var bundledTemplates embed.FS
+
+// TemplateData is the data available to prompt and message templates
+type TemplateData struct {
+	App         string // Blocked process name, e.g. steam.exe
+	Personality string // AI personality from the config
+	Time        string // Time the app was blocked, HH:MM; empty in prompts fetched ahead of the block
+	Language    string // Language code of the template, e.g. id or en
+	Attempts    int    // Times the app was blocked today, including this one (prompts only)
+	MinutesLeft int    // Minutes left in the current productive window, 0 outside one (prompts only)
+	Goal        string // The user's goal for the session, may be empty (prompts only)
+	LastMessage string // Message shown the last time the app was blocked, may be empty (prompts only)
+}
+
+// ClassifyData is the data available to the classification prompt template
+type ClassifyData struct {
+	Name     string // Process name, e.g. game.exe
+	Path     string // Executable path, may be empty
+	Title    string // Main window title, may be empty
+	Language string // Language code of the template, e.g. id or en
+}
+
+// PromptContextFunc fills in block history and schedule details for a prompt
+type PromptContextFunc func(data *TemplateData, now time.Time)
+
+// bundle is the parsed templates of one language, keyed by file name
+type bundle map[string]*template.Template
+
+var (
+	templatesDir  string
+	bundles       map[string]bundle // user templates, keyed by language
+	builtin       = mustLoadBuiltin()
+	language      = DefaultLanguage
+	promptContext PromptContextFunc
+	templatesMu   sync.RWMutex
+)
+
+// InitTemplates sets up the editable templates in dir/templates, copying any
+// missing bundled files there, and loads them
+func InitTemplates(dir string) error {
+	templatesMu.Lock()
+	templatesDir = filepath.Join(dir, "templates")
+	templatesMu.Unlock()
+
+	// Earlier versions kept a single Indonesian prompt next to the config
+	legacy := filepath.Join(dir, promptFile)
+	migrated := filepath.Join(templatesDir, DefaultLanguage, promptFile)
+	if _, err := os.Stat(legacy); err == nil {
+		if _, err := os.Stat(migrated); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(migrated), 0755); err == nil {
+				if err := os.Rename(legacy, migrated); err != nil {
+					utils.LogWarning("Failed to move %s to %s: %v", legacy, migrated, err)
+				}
+			}
+		}
+	}
+
+	err := fs.WalkDir(bundledTemplates, "templates", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(target); !os.IsNotExist(err) {
+			return nil
+		}
+
+		data, err := bundledTemplates.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return utils.WriteFileAtomic(target, data, 0644)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write default templates: %w", err)
+	}
+
+	return LoadTemplates()
+}
+
+// LoadTemplates (re)loads the templates of every language in the templates
+// directory. A file that fails to parse is reported and the bundled version
+// is used until it is fixed.
+func LoadTemplates() error {
+	templatesMu.RLock()
+	dir := templatesDir
+	templatesMu.RUnlock()
+
+	loaded := make(map[string]bundle)
+	var errs []error
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		lang := entry.Name()
+		b := make(bundle)
+		for file, parse := range templateParsers {
+			tmpl, err := loadTemplateFile(filepath.Join(dir, lang, file), parse)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if tmpl != nil {
+				b[file] = tmpl
+			}
+		}
+		loaded[lang] = b
+	}
+
+	templatesMu.Lock()
+	bundles = loaded
+	templatesMu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w - using the bundled templates instead", errors.Join(errs...))
+	}
+	utils.LogInfo("Templates loaded from %s (%d languages)", dir, len(loaded))
+	return nil
+}
+
+// loadTemplateFile parses a template file. A missing file is not an error.
+func loadTemplateFile(name string, parse func(string) (*template.Template, error)) (*template.Template, error) {
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// sampleData is used to check that templates render before they are used
+var sampleData = TemplateData{
+	App:         "app.exe",
+	Personality: "programmer",
+	Time:        "09:00",
+	Language:    DefaultLanguage,
+	Attempts:    2,
+	MinutesLeft: 30,
+	Goal:        "goal",
+	LastMessage: "message",
+}
+
+// ParsePromptTemplate parses a prompt template and checks that it renders
+func ParsePromptTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(promptFile).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Catch references to unknown fields now rather than on every block
+	if err := tmpl.Execute(&bytes.Buffer{}, sampleData); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// ParseMessageTemplate parses a default message template and checks that it
+// defines the "default" message and that every message renders
+func ParseMessageTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(messagesFile).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if tmpl.Lookup("default") == nil {
+		return nil, fmt.Errorf(`no "default" message defined`)
+	}
+	for _, t := range tmpl.Templates() {
+		if err := t.Execute(&bytes.Buffer{}, sampleData); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// ParseClassifyTemplate parses a classification prompt template and checks that it renders
+func ParseClassifyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(classifyFile).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := ClassifyData{Name: "app.exe", Path: `C:\app\app.exe`, Title: "App", Language: DefaultLanguage}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// SetLanguage sets the language of prompts and default messages. Languages
+// without templates fall back to the default language.
+func SetLanguage(lang string) {
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	language = strings.ToLower(lang)
+}
+
+// Language returns the language of prompts and default messages
+func Language() string {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	return language
+}
+
+// SetPromptContext sets the function that adds block history and schedule
+// details to prompts. Without one, prompts only mention the app and time.
+func SetPromptContext(fn PromptContextFunc) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	promptContext = fn
+}
+
+// BuildPrompt returns the prompt asking for a motivational message
+func BuildPrompt(personality, blockedApp string, now time.Time) string {
+	data := newTemplateData(personality, blockedApp, now)
+
+	templatesMu.RLock()
+	addContext := promptContext
+	templatesMu.RUnlock()
+
+	if addContext != nil {
+		addContext(&data, now)
+	}
+
+	return render(data.Language, promptFile, "", data)
+}
+
+// BuildPrefetchPrompt returns the prompt for a message fetched ahead of the
+// block. The block-time details (time, attempts, minutes left and last
+// message) are left empty, so the prompt does not describe a moment other
+// than the one the message is shown at; the session goal is kept.
+func BuildPrefetchPrompt(personality, blockedApp string) string {
+	data := newTemplateData(personality, blockedApp, time.Now())
+
+	templatesMu.RLock()
+	addContext := promptContext
+	templatesMu.RUnlock()
+
+	if addContext != nil {
+		addContext(&data, time.Now())
+	}
+	data.Time, data.Attempts, data.MinutesLeft, data.LastMessage = "", 0, 0, ""
+
+	return render(data.Language, promptFile, "", data)
+}
+
+// BuildClassifyPrompt returns the prompt asking whether a process is a
+// distraction. The reply is expected to be a JSON object, see classify.tmpl.
+func BuildClassifyPrompt(name, exePath, title string) string {
+	data := ClassifyData{Name: name, Path: exePath, Title: title, Language: Language()}
+	return render(data.Language, classifyFile, "", data)
+}
+
+// DefaultMessage returns the built-in message for the personality, used
+// when no AI message is available
+func DefaultMessage(personality, blockedApp string) string {
+	data := newTemplateData(personality, blockedApp, time.Now())
+	return render(data.Language, messagesFile, messageStyle(personality), data)
+}
+
+// BlockedMessage returns the message shown when AI messages are disabled
+func BlockedMessage(blockedApp string) string {
+	data := newTemplateData("", blockedApp, time.Now())
+	return render(data.Language, messagesFile, "disabled", data)
+}
+
+// messageStyle picks the default message template for a personality
+func messageStyle(personality string) string {
+	lowerPersonality := strings.ToLower(personality)
+
+	switch {
+	case strings.Contains(lowerPersonality, "programmer") || strings.Contains(lowerPersonality, "developer"):
+		return "programmer"
+	case strings.Contains(lowerPersonality, "student") || strings.Contains(lowerPersonality, "pelajar"):
+		return "student"
+	case strings.Contains(lowerPersonality, "designer") || strings.Contains(lowerPersonality, "creative"):
+		return "designer"
+	case strings.Contains(lowerPersonality, "writer") || strings.Contains(lowerPersonality, "content"):
+		return "writer"
+	case strings.Contains(lowerPersonality, "entrepreneur") || strings.Contains(lowerPersonality, "business"):
+		return "entrepreneur"
+	default:
+		return "default"
+	}
+}
+
+// newTemplateData returns the data shared by prompts and messages
+func newTemplateData(personality, blockedApp string, now time.Time) TemplateData {
+	return TemplateData{
+		App:         blockedApp,
+		Personality: personality,
+		Time:        now.Format("15:04"),
+		Language:    Language(),
+	}
+}
+
+// render executes the named template (or the root template when name is
+// empty) of file from the first bundle that has it: the user's templates in
+// lang, the bundled ones in lang, then the bundled default language.
+// Missing message styles fall back to "default".
+func render(lang, file, name string, data any) string {
+	templatesMu.RLock()
+	candidates := []bundle{bundles[lang], builtin[lang], builtin[DefaultLanguage]}
+	templatesMu.RUnlock()
+
+	names := []string{name}
+	if name != "" && name != "default" {
+		names = append(names, "default")
+	}
+
+	for _, b := range candidates {
+		tmpl := b[file]
+		if tmpl == nil {
+			continue
+		}
+
+		for _, n := range names {
+			t := tmpl
+			if n != "" {
+				if t = tmpl.Lookup(n); t == nil {
+					continue
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				utils.LogWarning("Template %s failed: %v", t.Name(), err)
+				continue
+			}
+			return strings.TrimSpace(buf.String())
+		}
+	}
+
+	return ""
+}
+
+// mustLoadBuiltin parses the bundled templates of every language
+func mustLoadBuiltin() map[string]bundle {
+	entries, err := bundledTemplates.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]bundle)
+	for _, entry := range entries {
+		dir := path.Join("templates", entry.Name())
+		b := make(bundle)
+		for file, parse := range templateParsers {
+			b[file] = template.Must(parse(mustRead(path.Join(dir, file))))
+		}
+		result[entry.Name()] = b
+	}
+	return result
+}
+
+// mustRead reads a bundled template file
+func mustRead(name string) string {
+	data, err := bundledTemplates.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// upcomingFile holds the warning shown before productive time starts:
+// "title" is the popup title and "message" its text
+const upcomingFile = "upcoming.tmpl"
+
+// UpcomingData is the data available to the upcoming warning templates
+type UpcomingData struct {
+	Language string   // Language code of the template, e.g. id or en
+	Start    string   // Time productive time starts, HH:MM
+	Minutes  int      // Minutes until the start, 0 when it starts now
+	Apps     []string // Running apps that will be closed
+}
+
+// sampleUpcoming is used to check that upcoming templates render before they are used
+var sampleUpcoming = UpcomingData{
+	Language: DefaultLanguage,
+	Start:    "09:00",
+	Minutes:  5,
+	Apps:     []string{"app.exe"},
+}
+
+// ParseUpcomingTemplate parses an upcoming warning template and checks that
+// it defines the "title" and "message" templates and that both render
+func ParseUpcomingTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(upcomingFile).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"title", "message"} {
+		t := tmpl.Lookup(name)
+		if t == nil {
+			return nil, fmt.Errorf("no %q template defined", name)
+		}
+		if err := t.Execute(&bytes.Buffer{}, sampleUpcoming); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// UpcomingWarning returns the title and text of the warning that productive
+// time starts at start and apps will be closed then
+func UpcomingWarning(start, now time.Time, apps []string) (title, message string) {
+	data := UpcomingData{
+		Language: Language(),
+		Start:    start.Format("15:04"),
+		Minutes:  max(int(start.Sub(now).Round(time.Minute)/time.Minute), 0),
+		Apps:     apps,
+	}
+	return render(data.Language, upcomingFile, "title", data), render(data.Language, upcomingFile, "message", data)
+}
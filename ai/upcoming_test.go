@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpcomingWarning(t *testing.T) {
+	defer SetLanguage(Language())
+
+	now := time.Date(2024, 3, 11, 8, 55, 0, 0, time.UTC)
+	start := now.Add(5 * time.Minute)
+	apps := []string{"steam.exe", "discord.exe"}
+
+	tests := []struct {
+		lang, title, message string
+		want                 []string
+	}{
+		{LanguageEnglish, "Productive Time Starts Soon", "at 09:00 (in 5 minutes)", []string{"• steam.exe\n• discord.exe\n\nSave"}},
+		{LanguageIndonesian, "Jam Produktif Segera Dimulai", "pukul 09:00 (5 menit lagi)", []string{"• steam.exe\n• discord.exe\n\nSimpan"}},
+		// A language without its own bundle falls back to the default one
+		{"ms", "Jam Produktif Segera Dimulai", "pukul 09:00", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			SetLanguage(tt.lang)
+			title, message := UpcomingWarning(start, now, apps)
+			if !strings.Contains(title, tt.title) {
+				t.Errorf("title = %q, want it to contain %q", title, tt.title)
+			}
+			for _, want := range append(tt.want, tt.message) {
+				if !strings.Contains(message, want) {
+					t.Errorf("message = %q, want it to contain %q", message, want)
+				}
+			}
+		})
+	}
+
+	SetLanguage(LanguageEnglish)
+	if _, message := UpcomingWarning(now, now, apps); !strings.HasPrefix(message, "Productive time starts now.") {
+		t.Errorf("message when starting now = %q", message)
+	}
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// weeklyFile holds the weekly summary templates: "prompt" asks the model
+// for a summary, "summary" is the offline summary used without one
+const weeklyFile = "weekly.tmpl"
+
+// AppCount is how often an app was blocked
+type AppCount struct {
+	App   string
+	Count int
+}
+
+// SlotCount is how often apps were blocked in one hour of a weekday
+type SlotCount struct {
+	Day   string // Weekday name in the template language, e.g. Tuesday
+	Hour  string // Start of the hour, e.g. 14:00
+	Count int
+}
+
+// WeeklyData is the data available to the weekly summary templates
+type WeeklyData struct {
+	Language      string      // Language code of the template, e.g. id or en
+	Personality   string      // AI personality from the config
+	Goal          string      // The user's session goal, may be empty
+	From          string      // First day of the week, YYYY-MM-DD
+	To            string      // Last day of the week, YYYY-MM-DD
+	Total         int         // Block attempts during the week
+	PreviousTotal int         // Block attempts during the week before
+	Apps          []AppCount  // Most blocked apps, most first (at most 5)
+	Peaks         []SlotCount // Weekday hours with the most attempts, most first (at most 3)
+	BusiestDay    string      // Weekday with the most attempts, empty without any
+	Schedule      string      // Active days and productive windows, e.g. Mon, Tue 09:00-12:00
+}
+
+// weekdayNames translates weekday names for languages other than English
+var weekdayNames = map[string][7]string{
+	LanguageIndonesian: {"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"},
+}
+
+// WeekdayName returns the name of day in the current template language
+func WeekdayName(day time.Weekday) string {
+	if names, ok := weekdayNames[Language()]; ok {
+		return names[day]
+	}
+	return day.String()
+}
+
+// sampleWeekly is used to check that weekly templates render before they are used
+var sampleWeekly = WeeklyData{
+	Language:      DefaultLanguage,
+	Personality:   "programmer",
+	Goal:          "goal",
+	From:          "2024-01-01",
+	To:            "2024-01-07",
+	Total:         12,
+	PreviousTotal: 9,
+	Apps:          []AppCount{{App: "app.exe", Count: 8}},
+	Peaks:         []SlotCount{{Day: "Tuesday", Hour: "14:00", Count: 5}},
+	BusiestDay:    "Tuesday",
+	Schedule:      "Mon 09:00-17:00",
+}
+
+// ParseWeeklyTemplate parses a weekly summary template and checks that it
+// defines the "prompt" and "summary" templates and that both render
+func ParseWeeklyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(weeklyFile).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"prompt", "summary"} {
+		t := tmpl.Lookup(name)
+		if t == nil {
+			return nil, fmt.Errorf("no %q template defined", name)
+		}
+		if err := t.Execute(&bytes.Buffer{}, sampleWeekly); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// BuildWeeklyPrompt returns the prompt asking for a weekly coaching summary
+func BuildWeeklyPrompt(data WeeklyData) string {
+	data.Language = Language()
+	return render(data.Language, weeklyFile, "prompt", data)
+}
+
+// WeeklySummary returns the offline weekly summary, used when no model is available
+func WeeklySummary(data WeeklyData) string {
+	data.Language = Language()
+	return render(data.Language, weeklyFile, "summary", data)
+}
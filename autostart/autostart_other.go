@@ -0,0 +1,89 @@
+//go:build !windows
+// +build !windows
+
+package autostart
+
+import (
+	"appblock/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// desktopEntry starts APPBlock headless when the user logs in
+const desktopEntry = `[Desktop Entry]
+Type=Application
+Name=APPBlock
+Comment=Block distracting apps during productive hours
+Exec="%s" --headless
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`
+
+// entryPath returns the XDG autostart entry, ~/.config/autostart/appblock.desktop
+func entryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "autostart", "appblock.desktop"), nil
+}
+
+// Enable adds the application to the desktop session's autostart entries
+func Enable() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	path, err := entryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create autostart directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, []byte(fmt.Sprintf(desktopEntry, exePath)), 0644); err != nil {
+		return fmt.Errorf("failed to write autostart entry: %w", err)
+	}
+
+	utils.LogInfo("Autostart enabled: %s", path)
+	return nil
+}
+
+// Disable removes the autostart entry
+func Disable() error {
+	path, err := entryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove autostart entry: %w", err)
+	}
+
+	utils.LogInfo("Autostart disabled")
+	return nil
+}
+
+// IsEnabled checks if autostart is currently enabled
+func IsEnabled() bool {
+	path, err := entryPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Sync synchronizes autostart state with config
+func Sync(shouldEnable bool) error {
+	isCurrentlyEnabled := IsEnabled()
+
+	if shouldEnable && !isCurrentlyEnabled {
+		return Enable()
+	} else if !shouldEnable && isCurrentlyEnabled {
+		return Disable()
+	}
+
+	return nil
+}
@@ -1,11 +1,16 @@
 package blocker
 
 import (
+	"appblock/ai"
+	"appblock/catalog"
+	"appblock/classifier"
 	"appblock/config"
-	"appblock/gemini"
+	"appblock/events"
+	"appblock/history"
 	"appblock/popup"
 	"appblock/scheduler"
 	"appblock/utils"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,19 +22,22 @@ import (
 type Blocker struct {
 	config        *config.Config
 	scheduler     *scheduler.Scheduler
-	geminiClient  *gemini.Client
+	provider      ai.MessageProvider
+	classifier    *classifier.Classifier
 	ticker        *time.Ticker
 	stopChan      chan bool
+	unsubscribe   func()
 	lastPopupTime time.Time
 	mu            sync.Mutex
 }
 
-// NewBlocker creates a new blocker instance
-func NewBlocker(cfg *config.Config, sched *scheduler.Scheduler, geminiClient *gemini.Client) *Blocker {
+// NewBlocker creates a new blocker instance.
+// provider may be nil, in which case a fixed message is shown.
+func NewBlocker(cfg *config.Config, sched *scheduler.Scheduler, provider ai.MessageProvider) *Blocker {
 	return &Blocker{
 		config:        cfg,
 		scheduler:     sched,
-		geminiClient:  geminiClient,
+		provider:      provider,
 		stopChan:      make(chan bool),
 		lastPopupTime: time.Time{}, // Zero time
 	}
@@ -37,10 +45,16 @@ func NewBlocker(cfg *config.Config, sched *scheduler.Scheduler, geminiClient *ge
 
 // Start starts the blocker loop
 func (b *Blocker) Start() {
-	scanInterval := time.Duration(b.config.ScanIntervalSeconds) * time.Second
+	cfg := b.getConfig()
+	scanInterval := time.Duration(cfg.ScanIntervalSeconds) * time.Second
 	b.ticker = time.NewTicker(scanInterval)
 	
-	utils.LogInfo("Blocker started with scan interval: %d seconds", b.config.ScanIntervalSeconds)
+	utils.LogInfo("Blocker started with scan interval: %d seconds", cfg.ScanIntervalSeconds)
+
+	// Warn before productive time starts about the apps that will be closed
+	b.unsubscribe = events.On(func(e events.ProductiveUpcoming) {
+		b.WarnUpcoming(e.Start)
+	})
 	
 	go func() {
 		for {
@@ -58,6 +72,9 @@ func (b *Blocker) Start() {
 
 // Stop stops the blocker
 func (b *Blocker) Stop() {
+	if b.unsubscribe != nil {
+		b.unsubscribe()
+	}
 	if b.ticker != nil {
 		b.stopChan <- true
 	}
@@ -65,15 +82,22 @@ func (b *Blocker) Stop() {
 
 // scanAndBlock scans for blocked processes and terminates them
 func (b *Blocker) scanAndBlock() {
+	// Use the blocklist and popup settings of the profile in effect right now
+	cfg := b.getConfig().Effective(time.Now())
 	isProductive := b.scheduler.IsProductive()
-	utils.LogInfo("Scan triggered - IsProductive: %v, Config.Enabled: %v", isProductive, b.config.Enabled)
+	utils.LogDebug("Scan triggered - IsProductive: %v, Config.Enabled: %v", isProductive, cfg.Enabled)
 	
 	// Only block if we're in productive time
 	if !isProductive {
 		return
 	}
 
-	utils.LogInfo("Scanning for blocked processes...")
+	utils.LogDebug("Scanning for blocked processes...")
+	start := time.Now()
+	defer func() {
+		scansTotal.Inc()
+		scanDuration.Observe(time.Since(start).Seconds())
+	}()
 
 	// Get all running processes
 	processes, err := process.Processes()
@@ -82,7 +106,11 @@ func (b *Blocker) scanAndBlock() {
 		return
 	}
 
+	// Expand categories into process names once per scan
+	blocklist := catalog.Resolve(cfg)
+
 	foundBlocked := false
+	var unblocked []*process.Process
 	// Check each process against blocklist
 	for _, proc := range processes {
 		name, err := proc.Name()
@@ -91,22 +119,81 @@ func (b *Blocker) scanAndBlock() {
 		}
 
 		// Check if process is in blocklist (case-insensitive)
-		if b.isBlocked(name) {
+		if isBlocked(name, blocklist) {
 			foundBlocked = true
-			b.terminateProcess(proc, name)
+			b.terminateProcess(proc, name, cfg)
+		} else {
+			unblocked = append(unblocked, proc)
 		}
 	}
+
+	// Let the classifier look for distractions missing from the blocklist
+	b.mu.Lock()
+	classify := b.classifier
+	b.mu.Unlock()
+	if classify != nil {
+		classify.Observe(unblocked)
+	}
 	
 	if !foundBlocked {
-		utils.LogInfo("Scan complete - no blocked apps found")
+		utils.LogDebug("Scan complete - no blocked apps found")
+	}
+}
+
+// RunningBlockedAt returns the names of running processes that the
+// blocklist in effect at t would close, sorted and without duplicates
+func (b *Blocker) RunningBlockedAt(t time.Time) ([]string, error) {
+	blocklist := catalog.Resolve(b.getConfig().Effective(t))
+
+	processes, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, proc := range processes {
+		name, err := proc.Name()
+		if err != nil || seen[strings.ToLower(name)] || !isBlocked(name, blocklist) {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names, nil
+}
+
+// WarnUpcoming warns that productive time starts at start, listing the
+// running apps that will be closed then. Nothing is shown if there are none.
+func (b *Blocker) WarnUpcoming(start time.Time) {
+	apps, err := b.RunningBlockedAt(start)
+	if err != nil {
+		utils.LogError("Failed to get processes: %v", err)
+		return
 	}
+	if len(apps) == 0 {
+		utils.LogDebug("No running apps to warn about before %s", start.Format("15:04"))
+		return
+	}
+
+	utils.LogInfo("Warning that %s will be closed at %s", strings.Join(apps, ", "), start.Format("15:04"))
+	go func() {
+		title, message := ai.UpcomingWarning(start, time.Now(), apps)
+		if err := popup.Show(title, message); err != nil {
+			utils.LogError("Failed to show popup: %v", err)
+		}
+	}()
 }
 
 // isBlocked checks if a process name is in the blocklist
-func (b *Blocker) isBlocked(processName string) bool {
+func isBlocked(processName string, blocklist []string) bool {
 	processLower := strings.ToLower(processName)
 	
-	for _, blocked := range b.config.Blocklist {
+	for _, blocked := range blocklist {
 		if strings.ToLower(blocked) == processLower {
 			return true
 		}
@@ -116,46 +203,53 @@ func (b *Blocker) isBlocked(processName string) bool {
 }
 
 // terminateProcess terminates a process and shows notification
-func (b *Blocker) terminateProcess(proc *process.Process, name string) {
+func (b *Blocker) terminateProcess(proc *process.Process, name string, cfg *config.Config) {
 	// Try to terminate the process
 	err := proc.Terminate()
 	if err != nil {
 		// If terminate fails, try kill
 		err = proc.Kill()
 		if err != nil {
-			utils.LogError("Failed to kill process %s (PID: %d): %v", name, proc.Pid, err)
+			utils.Logger().Error("Failed to kill process "+name, "event", "kill_failed", "app", name, "pid", proc.Pid, "err", err)
+			killFailuresTotal.WithLabelValues(strings.ToLower(name)).Inc()
+			events.Publish(events.KillFailed{Time: time.Now(), App: name, PID: proc.Pid, Error: err.Error()})
 			return
 		}
 	}
 
 	utils.LogBlocked(name)
+	killedTotal.WithLabelValues(strings.ToLower(name)).Inc()
+	events.Publish(events.ProcessBlocked{Time: time.Now(), App: name, PID: proc.Pid})
 	
 	// Show popup notification with cooldown
-	b.showBlockedNotification(name)
+	b.showBlockedNotification(name, cfg)
 }
 
 // showBlockedNotification shows a notification for blocked app
-func (b *Blocker) showBlockedNotification(appName string) {
+func (b *Blocker) showBlockedNotification(appName string, cfg *config.Config) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	// Check cooldown
-	cooldown := time.Duration(b.config.PopupCooldownSeconds) * time.Second
+	cooldown := time.Duration(cfg.PopupCooldownSeconds) * time.Second
 	if time.Since(b.lastPopupTime) < cooldown {
+		popupsSuppressedTotal.Inc()
 		return
 	}
 
 	b.lastPopupTime = time.Now()
+	provider := b.provider
 
 	// Get AI message in goroutine to not block
 	go func() {
 		var message string
 		
-		if b.config.AI.Enabled && b.geminiClient != nil {
-			message = b.geminiClient.GetMotivationalMessage(appName)
+		if cfg.AI.Enabled && provider != nil {
+			message = provider.GetMotivationalMessage(appName, cfg.AI.Personality)
 		} else {
-			message = "Tetap fokus! Ini waktu produktif untuk belajar. Matikan distraksi dan kerjakan tugasmu."
+			message = ai.BlockedMessage(appName)
 		}
+		history.RecordMessage(appName, message)
 
 		// Show popup (this will block until user closes it, but we're in a goroutine)
 		err := popup.ShowBlocked(appName, message)
@@ -165,6 +259,28 @@ func (b *Blocker) showBlockedNotification(appName string) {
 	}()
 }
 
+// SetProvider replaces the message provider, e.g. after the AI settings changed
+func (b *Blocker) SetProvider(provider ai.MessageProvider) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.provider = provider
+}
+
+// SetClassifier sets the classifier that is shown the processes left
+// running during productive time. nil disables classification.
+func (b *Blocker) SetClassifier(c *classifier.Classifier) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.classifier = c
+}
+
+// getConfig returns the current config snapshot
+func (b *Blocker) getConfig() *config.Config {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
 // UpdateConfig updates the blocker configuration
 func (b *Blocker) UpdateConfig(cfg *config.Config) {
 	b.mu.Lock()
@@ -172,6 +288,10 @@ func (b *Blocker) UpdateConfig(cfg *config.Config) {
 	
 	b.config = cfg
 	
+	if unknown := catalog.UnknownReferences(cfg); len(unknown) > 0 {
+		utils.LogWarning("Config references unknown %s - they will be ignored", strings.Join(unknown, ", "))
+	}
+	
 	// Restart ticker with new interval if changed
 	if b.ticker != nil {
 		b.ticker.Reset(time.Duration(cfg.ScanIntervalSeconds) * time.Second)
@@ -0,0 +1,32 @@
+package blocker
+
+import (
+	"appblock/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Blocker metrics, exposed by the metrics endpoint when enabled
+var (
+	scansTotal = metrics.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "appblock_scans_total",
+		Help: "Process scans performed during productive time",
+	}))
+	scanDuration = metrics.MustRegister(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "appblock_scan_duration_seconds",
+		Help:    "Time taken to list processes and close blocked ones",
+		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}))
+	killedTotal = metrics.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appblock_processes_killed_total",
+		Help: "Blocked processes closed, by executable name",
+	}, []string{"app"}))
+	killFailuresTotal = metrics.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appblock_kill_failures_total",
+		Help: "Blocked processes that could not be closed, by executable name",
+	}, []string{"app"}))
+	popupsSuppressedTotal = metrics.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "appblock_popups_suppressed_total",
+		Help: "Block popups not shown because of the popup cooldown",
+	}))
+)
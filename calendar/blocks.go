@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Interval is a focus block from the calendar
+type Interval struct {
+	Start time.Time
+	End   time.Time
+	Title string
+}
+
+// Contains reports whether t falls in the block
+func (i Interval) Contains(t time.Time) bool {
+	return !t.Before(i.Start) && t.Before(i.End)
+}
+
+// Read parses iCalendar data and returns the focus blocks overlapping
+// [from, to): instances of events whose title contains one of patterns
+// (ignoring case), merged where they overlap. Events that could not be read
+// are described in skipped.
+func Read(data []byte, patterns []string, from, to time.Time) (blocks []Interval, skipped []string, err error) {
+	events, skipped, err := parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return focusBlocks(events, patterns, from, to), skipped, nil
+}
+
+// focusBlocks expands the matching events into merged intervals
+func focusBlocks(events []event, patterns []string, from, to time.Time) []Interval {
+	// Edited instances of recurring events replace the original instance
+	edited := make(map[string]map[int64]bool)
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			if edited[e.UID] == nil {
+				edited[e.UID] = make(map[int64]bool)
+			}
+			edited[e.UID][e.RecurrenceID.Unix()] = true
+		}
+	}
+
+	var blocks []Interval
+	for i := range events {
+		e := &events[i]
+		if e.Cancelled || !matches(e.Summary, patterns) {
+			continue
+		}
+
+		var skip map[int64]bool
+		if e.RecurrenceID.IsZero() {
+			skip = edited[e.UID]
+		}
+		for _, start := range e.occurrences(from, to, skip) {
+			blocks = append(blocks, Interval{Start: start, End: start.Add(e.End.Sub(e.Start)), Title: e.Summary})
+		}
+	}
+
+	return merge(blocks)
+}
+
+// matches reports whether title contains one of the patterns, ignoring case
+func matches(title string, patterns []string) bool {
+	title = strings.ToLower(title)
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" && strings.Contains(title, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge sorts blocks and joins those that overlap or touch
+func merge(blocks []Interval) []Interval {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start.Before(blocks[j].Start) })
+
+	var merged []Interval
+	for _, b := range blocks {
+		if !b.End.After(b.Start) {
+			continue
+		}
+		if n := len(merged); n > 0 && !b.Start.After(merged[n-1].End) {
+			if b.End.After(merged[n-1].End) {
+				merged[n-1].End = b.End
+			}
+			continue
+		}
+		merged = append(merged, b)
+	}
+	return merged
+}
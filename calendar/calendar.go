@@ -0,0 +1,229 @@
+// Package calendar reads focus blocks from an iCalendar file, feed or CalDAV
+// calendar. Events whose title matches the configured patterns count as
+// productive time, next to the schedule's time windows.
+package calendar
+
+import (
+	"appblock/config"
+	"appblock/utils"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Horizon is how far ahead focus blocks are read
+const Horizon = 8 * 24 * time.Hour
+
+// fetchTimeout bounds reading the calendar once
+const fetchTimeout = 30 * time.Second
+
+// Calendar keeps the focus blocks of the configured calendar up to date
+type Calendar struct {
+	cfg      config.CalendarConfig
+	blocks   []Interval
+	skipped  []string // Events that could not be read, logged when they change
+	onChange func()
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	mu       sync.RWMutex
+}
+
+// New creates a calendar without a source
+func New() *Calendar {
+	return &Calendar{
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// OnChange sets the function called after the focus blocks changed
+func (c *Calendar) OnChange(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = fn
+}
+
+// Apply replaces the calendar settings; a changed calendar is read again
+// right away by the running loop. Calendar URLs are kept out of the log, as
+// private feed addresses contain a token.
+func (c *Calendar) Apply(cfg config.CalendarConfig) {
+	if cfg.IsURL() {
+		utils.RegisterSecret(cfg.Source)
+	}
+
+	c.mu.Lock()
+	changed := !calendarEqual(c.cfg, cfg)
+	c.cfg = cfg
+	c.mu.Unlock()
+
+	if changed {
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start reads the calendar now and then every refresh interval
+func (c *Calendar) Start() {
+	go func() {
+		defer close(c.done)
+		for {
+			c.refresh()
+
+			c.mu.RLock()
+			interval := c.cfg.Refresh()
+			c.mu.RUnlock()
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-c.wake:
+				timer.Stop()
+			case <-c.stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops reading the calendar
+func (c *Calendar) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+// refresh reads the calendar, logging failures; the last focus blocks
+// read are kept until it succeeds again
+func (c *Calendar) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	if err := c.Refresh(ctx); err != nil {
+		utils.LogWarning("Failed to read calendar: %v", err)
+	}
+}
+
+// Refresh reads the focus blocks from now until Horizon
+func (c *Calendar) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	cfg := c.cfg
+	c.mu.RUnlock()
+
+	if !cfg.Enabled() {
+		c.setBlocks(nil)
+		return nil
+	}
+
+	now := time.Now()
+	blocks, skipped, err := load(ctx, cfg, now.Add(-24*time.Hour), now.Add(Horizon))
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if len(skipped) > 0 && !slices.Equal(skipped, c.skipped) {
+		utils.LogWarning("Ignoring calendar events that could not be read: %s", strings.Join(skipped, "; "))
+	}
+	c.skipped = skipped
+	c.mu.Unlock()
+
+	if c.setBlocks(blocks) {
+		utils.LogInfo("Calendar has %d focus blocks in the next %d days", len(blocks), int(Horizon.Hours()/24))
+	}
+	return nil
+}
+
+// load fetches the calendar and returns its focus blocks between from and to
+func load(ctx context.Context, cfg config.CalendarConfig, from, to time.Time) (blocks []Interval, skipped []string, err error) {
+	docs, err := fetch(ctx, cfg, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, doc := range docs {
+		found, skip, err := Read(doc, cfg.Patterns(), from, to)
+		if err != nil {
+			// A CalDAV calendar is one document per event: skip only that one
+			if len(docs) == 1 {
+				return nil, nil, err
+			}
+			skipped = append(skipped, fmt.Sprintf("event %d: %v", i+1, err))
+			continue
+		}
+		blocks = append(blocks, found...)
+		skipped = append(skipped, skip...)
+	}
+	return merge(blocks), skipped, nil
+}
+
+// setBlocks replaces the focus blocks, calling onChange when they differ
+func (c *Calendar) setBlocks(blocks []Interval) bool {
+	c.mu.Lock()
+	changed := !slices.EqualFunc(c.blocks, blocks, func(a, b Interval) bool {
+		return a.Start.Equal(b.Start) && a.End.Equal(b.End) && a.Title == b.Title
+	})
+	c.blocks = blocks
+	onChange := c.onChange
+	c.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange()
+	}
+	return changed
+}
+
+// Blocks returns the focus blocks read last, in order
+func (c *Calendar) Blocks() []Interval {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.blocks)
+}
+
+// Skipped describes the events left out of the last read as unreadable
+func (c *Calendar) Skipped() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.skipped)
+}
+
+// Contains reports whether t falls in a focus block
+func (c *Calendar) Contains(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, b := range c.blocks {
+		if b.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Boundaries returns the starts and ends of focus blocks after from and
+// up to to, in order
+func (c *Calendar) Boundaries(from, to time.Time) []time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var times []time.Time
+	for _, b := range c.blocks {
+		for _, t := range []time.Time{b.Start, b.End} {
+			if t.After(from) && !t.After(to) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// calendarEqual reports whether two calendar settings read the same blocks
+func calendarEqual(a, b config.CalendarConfig) bool {
+	return a.Source == b.Source && a.Username == b.Username &&
+		a.RefreshMinutes == b.RefreshMinutes && slices.Equal(a.Match, b.Match)
+}
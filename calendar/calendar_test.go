@@ -0,0 +1,307 @@
+package calendar
+
+import (
+	"appblock/config"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Floating times and all-day events are local; pin the zone so the
+	// expected blocks do not depend on the machine
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		panic(err)
+	}
+	time.Local = loc
+	os.Exit(m.Run())
+}
+
+var (
+	from = time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	to   = time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)
+)
+
+func utc(day, hour, minute int) time.Time {
+	return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+}
+
+func local(day, hour int) time.Time {
+	return time.Date(2024, 3, day, hour, 0, 0, 0, time.Local)
+}
+
+// focusBlocksWant returns the focus blocks of testdata/focus.ics between from
+// and to; it is a function so local times use the zone set in TestMain
+func focusBlocksWant() []Interval {
+	return []Interval{
+		{Start: utc(11, 8, 0), End: utc(11, 10, 0), Title: "Focus: writing"}, // Europe/Berlin
+		{Start: utc(11, 14, 0), End: utc(11, 15, 0), Title: "Focus block"},
+		{Start: utc(12, 13, 0), End: utc(12, 16, 0), Title: "DEEP WORK"}, // Merged with the overlapping event
+		{Start: local(15, 8), End: local(15, 10), Title: "Deep work, no meetings; phone off"},
+		{Start: local(16, 0), End: local(17, 0), Title: "Focus day"},
+		{Start: utc(18, 16, 0), End: utc(18, 17, 0), Title: "Focus block (moved)"},
+		{Start: utc(20, 14, 0), End: utc(20, 15, 0), Title: "Focus block"},
+	}
+}
+
+func checkBlocks(t *testing.T, got, want []Interval) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d blocks, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) || got[i].Title != want[i].Title {
+			t.Errorf("block %d = %v - %v %q, want %v - %v %q", i,
+				got[i].Start, got[i].End, got[i].Title, want[i].Start, want[i].End, want[i].Title)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	data, err := os.ReadFile("testdata/focus.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, skipped, err := Read(data, []string{"focus", "Deep Work"}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBlocks(t, blocks, focusBlocksWant())
+	if len(skipped) != 1 || !strings.Contains(skipped[0], "Focus sprint") || !strings.Contains(skipped[0], "HOURLY") {
+		t.Errorf("skipped = %v", skipped)
+	}
+
+	// Outlook and other Windows exports start with a byte order mark
+	withBOM, _, err := Read(append([]byte("\xef\xbb\xbf"), data...), []string{"focus", "Deep Work"}, from, to)
+	if err != nil {
+		t.Fatalf("with byte order mark: %v", err)
+	}
+	checkBlocks(t, withBOM, focusBlocksWant())
+
+	if _, _, err := Read([]byte("<html></html>"), []string{"Focus"}, from, to); err == nil {
+		t.Error("expected an error for a non-calendar file")
+	}
+}
+
+func TestRecurrence(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{"daily interval until", "FREQ=DAILY;INTERVAL=2;UNTIL=20240315T090000Z", utc(11, 9, 0),
+			[]time.Time{utc(11, 9, 0), utc(13, 9, 0), utc(15, 9, 0)}},
+		{"weekly from the start day", "FREQ=WEEKLY;BYDAY=TU,FR;COUNT=3", utc(12, 9, 0),
+			[]time.Time{utc(12, 9, 0), utc(15, 9, 0), utc(19, 9, 0)}},
+		{"monthly last friday", "FREQ=MONTHLY;BYDAY=-1FR", time.Date(2024, 1, 26, 9, 0, 0, 0, time.UTC),
+			[]time.Time{time.Date(2024, 3, 29, 9, 0, 0, 0, time.UTC)}},
+		{"monthly day", "FREQ=MONTHLY;BYMONTHDAY=15", time.Date(2023, 12, 15, 9, 0, 0, 0, time.UTC),
+			[]time.Time{utc(15, 9, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := event{Start: tt.start, End: tt.start.Add(time.Hour), Rule: r}
+			got := e.occurrences(from, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), nil)
+			if len(got) != len(tt.want) {
+				t.Fatalf("occurrences = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	for _, invalid := range []string{"FREQ=SECONDLY", "BYDAY=MO", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;BYHOUR=9"} {
+		if _, err := parseRule(invalid); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestLoadFeed(t *testing.T) {
+	data, err := os.ReadFile("testdata/focus.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	cfg := config.CalendarConfig{Source: srv.URL + "/private.ics"}
+	blocks, _, err := load(context.Background(), cfg, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBlocks(t, blocks, focusBlocksWant())
+}
+
+func TestLoadCalDAV(t *testing.T) {
+	t.Setenv(config.CalendarPasswordName, "hunter2-caldav")
+
+	event := func(summary, start, end string) string {
+		return "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:" + summary + "\r\nDTSTART:" + start +
+			"\r\nDTEND:" + end + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "hunter2-caldav" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>calendar collection</html>"))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "REPORT" || r.Header.Get("Depth") != "1" ||
+			!strings.Contains(string(body), `start="20240311T000000Z" end="20240321T000000Z"`) {
+			t.Errorf("unexpected request %s depth %q: %s", r.Method, r.Header.Get("Depth"), body)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+  <d:response>
+    <d:href>/cal/1.ics</d:href>
+    <d:propstat>
+      <d:prop><cal:calendar-data>` + event("Focus", "20240311T090000Z", "20240311T100000Z") + `</cal:calendar-data></d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/cal/2.ics</d:href>
+    <d:propstat>
+      <d:prop><cal:calendar-data>` + event("Standup", "20240311T100000Z", "20240311T101500Z") + `</cal:calendar-data></d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/cal/3.ics</d:href>
+    <d:propstat>
+      <d:prop><cal:calendar-data>` + event("Deep Work", "20240312T090000Z", "20240312T110000Z") + `</cal:calendar-data></d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+</d:multistatus>`))
+	}))
+	defer srv.Close()
+
+	cfg := config.CalendarConfig{Source: srv.URL + "/cal/", Username: "me"}
+	blocks, _, err := load(context.Background(), cfg, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBlocks(t, blocks, []Interval{
+		{Start: utc(11, 9, 0), End: utc(11, 10, 0), Title: "Focus"},
+		{Start: utc(12, 9, 0), End: utc(12, 11, 0), Title: "Deep Work"},
+	})
+
+	cfg.Username = "someone-else"
+	if _, _, err := load(context.Background(), cfg, from, to); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("wrong login: err = %v", err)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	c := New()
+	changes := 0
+	c.OnChange(func() { changes++ })
+
+	c.setBlocks(focusBlocksWant()[:3])
+	c.setBlocks(focusBlocksWant()[:3])
+	if changes != 1 {
+		t.Errorf("onChange called %d times, want 1", changes)
+	}
+
+	if !c.Contains(utc(11, 9, 0)) || c.Contains(utc(11, 10, 0)) || c.Contains(utc(11, 12, 0)) {
+		t.Error("Contains does not match the blocks")
+	}
+
+	got := c.Boundaries(utc(11, 8, 0), utc(12, 13, 0))
+	want := []time.Time{utc(11, 10, 0), utc(11, 14, 0), utc(11, 15, 0), utc(12, 13, 0)}
+	if len(got) != len(want) {
+		t.Fatalf("Boundaries = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("boundary %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Turning the calendar off clears the blocks
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Blocks()) != 0 || changes != 2 {
+		t.Errorf("blocks after clearing the source = %v, changes = %d", c.Blocks(), changes)
+	}
+}
+
+func TestTimeZones(t *testing.T) {
+	doc := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VTIMEZONE",
+		"TZID:(UTC+07:00) Bangkok\\, Hanoi\\, Jakarta",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T000000",
+		"TZOFFSETFROM:+0700",
+		"TZOFFSETTO:+0700",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"SUMMARY:Focus outlook",
+		"DTSTART;TZID=W. Europe Standard Time:20240311T090000",
+		"DTEND;TZID=W. Europe Standard Time:20240311T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Focus offset",
+		`DTSTART;TZID="(UTC+07:00) Bangkok\, Hanoi\, Jakarta":20240312T090000`,
+		`DTEND;TZID="(UTC+07:00) Bangkok\, Hanoi\, Jakarta":20240312T100000`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Focus somewhere",
+		"DTSTART;TZID=Middle Earth Standard Time:20240313T090000",
+		"DTEND;TZID=Middle Earth Standard Time:20240313T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	blocks, skipped, err := Read([]byte(doc), []string{"Focus"}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBlocks(t, blocks, []Interval{
+		{Start: utc(11, 8, 0), End: utc(11, 9, 0), Title: "Focus outlook"},
+		{Start: utc(12, 2, 0), End: utc(12, 3, 0), Title: "Focus offset"},
+	})
+	if len(skipped) != 1 || !strings.Contains(skipped[0], "Focus somewhere") || !strings.Contains(skipped[0], "Middle Earth") {
+		t.Errorf("skipped = %v, want the event in an unknown zone", skipped)
+	}
+
+	for windows, iana := range windowsZones {
+		if _, err := time.LoadLocation(iana); err != nil {
+			t.Errorf("%s: %v", windows, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package calendar
+
+import (
+	"appblock/config"
+	"appblock/secret"
+	"appblock/utils"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxCalendarSize bounds a downloaded calendar
+const maxCalendarSize = 16 << 20
+
+// client fetches calendars; requests are bounded by the caller's context.
+// A redirect from https to http is not followed with the password.
+var client = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		if req.URL.Scheme == "http" && via[0].URL.Scheme == "https" && req.Header.Get("Authorization") != "" {
+			return fmt.Errorf("calendar server redirected to an unencrypted address")
+		}
+		return nil
+	},
+}
+
+// fetch returns the iCalendar documents of the source: the file, the feed
+// at the URL, or the events between from and to of a CalDAV calendar
+func fetch(ctx context.Context, cfg config.CalendarConfig, from, to time.Time) ([][]byte, error) {
+	if !cfg.IsURL() {
+		data, err := os.ReadFile(cfg.Source)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{data}, nil
+	}
+
+	source := cfg.Source
+	if rest, ok := strings.CutPrefix(source, "webcal://"); ok {
+		source = "https://" + rest
+	}
+
+	// A feed, e.g. a calendar's secret iCal address or a .ics export
+	status, body, err := request(ctx, cfg, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusOK && isICalendar(body) {
+		return [][]byte{body}, nil
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return nil, fmt.Errorf("calendar server refused access (%d); check the username and the %s secret", status, config.CalendarPasswordName)
+	}
+
+	// A CalDAV calendar collection: ask for the events in the range
+	status, body, err = request(ctx, cfg, "REPORT", source, []byte(calendarQuery(from, to)))
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusMultiStatus {
+		return nil, fmt.Errorf("not an iCalendar feed or CalDAV calendar (server responded %d)", status)
+	}
+	return parseMultistatus(body)
+}
+
+// request sends a request with the configured login and returns the status and body
+func request(ctx context.Context, cfg config.CalendarConfig, method, url string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("User-Agent", "APPBlock")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		req.Header.Set("Depth", "1")
+	}
+	if cfg.Username != "" {
+		req.SetBasicAuth(cfg.Username, password())
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCalendarSize))
+	return resp.StatusCode, data, err
+}
+
+// password returns the CalDAV password from the environment or the secret store
+func password() string {
+	value := os.Getenv(config.CalendarPasswordName)
+	if value == "" {
+		value, _ = secret.Get(config.CalendarPasswordName)
+	}
+	utils.RegisterSecret(value)
+	return value
+}
+
+// isICalendar reports whether data starts like an iCalendar document
+func isICalendar(data []byte) bool {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\xef\xbb\xbf"))
+	return len(data) >= 15 && strings.EqualFold(string(data[:15]), "BEGIN:VCALENDAR")
+}
+
+// calendarQuery is a CalDAV REPORT body asking for the events between from and to
+func calendarQuery(from, to time.Time) string {
+	const layout = "20060102T150405Z"
+	return `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="` + from.UTC().Format(layout) + `" end="` + to.UTC().Format(layout) + `"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+}
+
+// multistatus is the part of a CalDAV REPORT response holding the events
+type multistatus struct {
+	Responses []struct {
+		Propstats []struct {
+			Status       string `xml:"status"`
+			CalendarData string `xml:"prop>calendar-data"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// parseMultistatus returns the calendar data of each event in a REPORT response
+func parseMultistatus(data []byte) ([][]byte, error) {
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("invalid CalDAV response: %w", err)
+	}
+
+	var docs [][]byte
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstats {
+			if strings.Contains(ps.Status, " 200 ") && strings.TrimSpace(ps.CalendarData) != "" {
+				docs = append(docs, []byte(ps.CalendarData))
+			}
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,268 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "time/tzdata" // TZID names must resolve on Windows too
+)
+
+// event is a VEVENT, with its recurrence if it repeats
+type event struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	Duration     time.Duration // From DURATION, used when there is no DTEND
+	AllDay       bool
+	Cancelled    bool
+	Rule         *rule
+	ExDates      []time.Time
+	RecurrenceID time.Time // Set on an edited instance of a recurring event
+	err          error     // First problem found, which leaves the event out
+}
+
+// property is one content line: NAME;PARAM=value:VALUE
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// parse reads the events of an iCalendar document. Events it cannot read
+// are left out and described in skipped, so one odd event does not hide
+// the rest of the calendar.
+func parse(data []byte) (events []event, skipped []string, err error) {
+	lines := unfold(data)
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, nil, fmt.Errorf("not an iCalendar file")
+	}
+	zones := timezones(lines)
+
+	var current *event
+	depth := 0 // Components nested in the current VEVENT, e.g. VALARM
+	for i, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VEVENT") && current == nil:
+			current = &event{}
+			continue
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VEVENT") && current != nil && depth == 0:
+			if current.err == nil {
+				current.err = current.finish()
+			}
+			if current.err != nil {
+				skipped = append(skipped, fmt.Sprintf("%q: %v", current.Summary, current.err))
+			} else {
+				events = append(events, *current)
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		case prop.Name == "BEGIN":
+			depth++
+			continue
+		case prop.Name == "END":
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		if err := current.set(prop, zones); err != nil && current.err == nil {
+			current.err = fmt.Errorf("line %d: %s: %w", i+1, prop.Name, err)
+		}
+	}
+
+	return events, skipped, nil
+}
+
+// set applies a property to the event; zones are the document's VTIMEZONEs
+func (e *event) set(p property, zones map[string]*time.Location) error {
+	var err error
+	switch p.Name {
+	case "UID":
+		e.UID = p.Value
+	case "SUMMARY":
+		e.Summary = unescape(p.Value)
+	case "STATUS":
+		e.Cancelled = strings.EqualFold(p.Value, "CANCELLED")
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseTime(p, zones)
+	case "DTEND":
+		e.End, _, err = parseTime(p, zones)
+	case "DURATION":
+		e.Duration, err = parseDuration(p.Value)
+	case "RRULE":
+		e.Rule, err = parseRule(p.Value)
+	case "EXDATE":
+		for _, value := range strings.Split(p.Value, ",") {
+			var t time.Time
+			if t, _, err = parseTime(property{Params: p.Params, Value: value}, zones); err != nil {
+				return err
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		e.RecurrenceID, _, err = parseTime(p, zones)
+	}
+	return err
+}
+
+// finish checks the event and fills in a missing end
+func (e *event) finish() error {
+	if e.Start.IsZero() {
+		return fmt.Errorf("missing DTSTART")
+	}
+	switch {
+	case !e.End.IsZero():
+	case e.Duration != 0:
+		e.End = e.Start.Add(e.Duration)
+	case e.AllDay:
+		e.End = e.Start.AddDate(0, 0, 1)
+	default:
+		e.End = e.Start
+	}
+	if e.End.Before(e.Start) {
+		return fmt.Errorf("ends before it starts")
+	}
+	return nil
+}
+
+// unfold joins continuation lines (starting with a space or tab) to the
+// line before them. A UTF-8 byte order mark, as written by Outlook and other
+// Windows exports, is dropped.
+func unfold(data []byte) []string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseProperty splits a content line into name, parameters and value
+func parseProperty(line string) (property, bool) {
+	// The value starts at the first colon outside quoted parameter values
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	p := property{Name: strings.ToUpper(parts[0]), Params: make(map[string]string), Value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			p.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return p, true
+}
+
+// parseTime parses a DATE or DATE-TIME value. UTC times end in Z, times
+// with a TZID are in that zone, and others are local (floating) time.
+// allDay is true for dates without a time.
+func parseTime(p property, zones map[string]*time.Location) (t time.Time, allDay bool, err error) {
+	value := strings.TrimSpace(p.Value)
+
+	loc := time.Local
+	if tzid := p.Params["TZID"]; tzid != "" && !strings.HasSuffix(value, "Z") {
+		if loc, err = location(tzid, zones); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+
+	if p.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err = time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration parses an iCalendar duration such as PT1H30M or P1D
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "-")
+	negative := strings.HasPrefix(value, "-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	inTime := false
+	number := ""
+	for _, r := range s {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9':
+			number += string(r)
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			number = ""
+
+			switch {
+			case r == 'W' && !inTime:
+				d += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				d += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				d += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				d += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				d += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+// unescape decodes an iCalendar TEXT value
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,252 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods bounds the expansion of a recurring event
+const maxPeriods = 100000
+
+// rule is a recurrence rule (RRULE). Supported are FREQ DAILY, WEEKLY,
+// MONTHLY and YEARLY with INTERVAL, COUNT, UNTIL, BYDAY and BYMONTHDAY.
+type rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []weekdayNum
+	ByMonthDay []int
+}
+
+// weekdayNum is a BYDAY entry: MO, or 2MO and -1FR for the nth weekday of a month
+type weekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRule parses an RRULE value such as FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10
+func parseRule(value string) (*rule, error) {
+	r := &rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			r.Until, _, err = parseTime(property{Value: val}, nil)
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				day = strings.ToUpper(strings.TrimSpace(day))
+				if len(day) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				weekday, ok := weekdays[day[len(day)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				n := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					if n, err = strconv.Atoi(prefix); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q", day)
+					}
+				}
+				r.ByDay = append(r.ByDay, weekdayNum{N: n, Day: weekday})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", day)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			// Weeks start on Monday; other week starts only matter for
+			// rules with INTERVAL > 1 and are rare
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", key, val, err)
+		}
+	}
+
+	switch r.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, fmt.Errorf("missing FREQ")
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %s", r.Freq)
+	}
+	return r, nil
+}
+
+// occurrences returns the starts of the event's instances that overlap
+// [from, to), leaving out those in skip (keyed by Unix time)
+func (e *event) occurrences(from, to time.Time, skip map[int64]bool) []time.Time {
+	duration := e.End.Sub(e.Start)
+	overlaps := func(start time.Time) bool {
+		return start.Before(to) && start.Add(duration).After(from)
+	}
+
+	if e.Rule == nil {
+		if overlaps(e.Start) {
+			return []time.Time{e.Start}
+		}
+		return nil
+	}
+
+	excluded := make(map[int64]bool, len(e.ExDates))
+	for _, t := range e.ExDates {
+		excluded[t.Unix()] = true
+	}
+
+	var starts []time.Time
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		begin, candidates := e.Rule.period(e.Start, period)
+		if !begin.Before(to) {
+			break
+		}
+
+		for _, start := range candidates {
+			if start.Before(e.Start) {
+				continue
+			}
+			if !e.Rule.Until.IsZero() && start.After(e.Rule.Until) {
+				return starts
+			}
+			count++
+			if e.Rule.Count > 0 && count > e.Rule.Count {
+				return starts
+			}
+			if !start.Before(to) {
+				return starts
+			}
+			if excluded[start.Unix()] || skip[start.Unix()] {
+				continue
+			}
+			if overlaps(start) {
+				starts = append(starts, start)
+			}
+		}
+	}
+	return starts
+}
+
+// period returns the beginning of the nth period (day, week, month or year)
+// of the rule and the instance starts in it, in order
+func (r *rule) period(first time.Time, n int) (begin time.Time, starts []time.Time) {
+	year, month, day := first.Date()
+	hour, minute, second := first.Clock()
+	loc := first.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, minute, second, 0, loc)
+	}
+	step := n * r.Interval
+
+	switch r.Freq {
+	case "DAILY":
+		t := at(year, month, day+step)
+		begin = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if len(r.ByDay) == 0 || r.hasWeekday(t.Weekday()) {
+			starts = append(starts, t)
+		}
+
+	case "WEEKLY":
+		// Weeks start on Monday
+		monday := day - (int(first.Weekday())+6)%7 + 7*step
+		begin = time.Date(year, month, monday, 0, 0, 0, 0, loc)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []weekdayNum{{Day: first.Weekday()}}
+		}
+		for _, wd := range days {
+			starts = append(starts, at(year, month, monday+(int(wd.Day)+6)%7))
+		}
+
+	case "MONTHLY":
+		begin = time.Date(year, month+time.Month(step), 1, 0, 0, 0, 0, loc)
+		y, m := begin.Year(), begin.Month()
+		switch {
+		case len(r.ByMonthDay) > 0:
+			for _, d := range r.ByMonthDay {
+				if d < 0 {
+					d = daysIn(y, m) + d + 1
+				}
+				if d >= 1 && d <= daysIn(y, m) {
+					starts = append(starts, at(y, m, d))
+				}
+			}
+		case len(r.ByDay) > 0:
+			for _, wd := range r.ByDay {
+				for _, d := range weekdaysInMonth(y, m, wd) {
+					starts = append(starts, at(y, m, d))
+				}
+			}
+		case day <= daysIn(y, m):
+			starts = append(starts, at(y, m, day))
+		}
+
+	case "YEARLY":
+		begin = time.Date(year+step, 1, 1, 0, 0, 0, 0, loc)
+		if day <= daysIn(year+step, month) {
+			starts = append(starts, at(year+step, month, day))
+		}
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return begin, starts
+}
+
+// hasWeekday reports whether BYDAY includes d
+func (r *rule) hasWeekday(d time.Weekday) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days in the month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// weekdaysInMonth returns the days of the month matching a BYDAY entry:
+// every such weekday, or only the nth (from the end when negative)
+func weekdaysInMonth(year int, month time.Month, wd weekdayNum) []int {
+	var days []int
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	for d := 1 + (int(wd.Day)-int(first)+7)%7; d <= daysIn(year, month); d += 7 {
+		days = append(days, d)
+	}
+
+	switch {
+	case wd.N > 0 && wd.N <= len(days):
+		return days[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(days):
+		return days[len(days)+wd.N : len(days)+wd.N+1]
+	case wd.N != 0:
+		return nil
+	}
+	return days
+}
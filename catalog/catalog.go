@@ -0,0 +1,247 @@
+package catalog
+
+import (
+	"appblock/config"
+	"appblock/utils"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// bundledData is the catalog shipped with the binary
+//
+//go:embed catalog.json
+var bundledData []byte
+
+// App maps a popular application to its executable names on each platform
+type App struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Category    string              `json:"category"`
+	Executables map[string][]string `json:"executables"` // keyed by GOOS: windows, linux, darwin
+}
+
+// Catalog is a versioned list of known applications
+type Catalog struct {
+	Version int   `json:"version"`
+	Apps    []App `json:"apps"`
+}
+
+var (
+	overridePath string
+	instance     *Catalog
+	mu           sync.RWMutex
+)
+
+// Init initializes the catalog. An updated catalog.json in dir replaces the
+// bundled one when its version is the same or newer.
+func Init(dir string) error {
+	overridePath = filepath.Join(dir, "catalog.json")
+	return Load()
+}
+
+// Load loads the bundled catalog and the updated catalog file if present
+func Load() error {
+	bundled, err := Parse(bundledData)
+	if err != nil {
+		return fmt.Errorf("failed to parse bundled catalog: %w", err)
+	}
+
+	selected := bundled
+	if data, err := os.ReadFile(overridePath); err == nil {
+		updated, err := Parse(data)
+		if err != nil {
+			utils.LogWarning("Ignoring invalid catalog file %s: %v", overridePath, err)
+		} else if updated.Version >= bundled.Version {
+			selected = updated
+		} else {
+			utils.LogInfo("Catalog file version %d is older than bundled version %d - using bundled catalog", updated.Version, bundled.Version)
+		}
+	}
+
+	mu.Lock()
+	instance = selected
+	mu.Unlock()
+
+	utils.LogInfo("App catalog loaded (version %d, %d apps)", selected.Version, len(selected.Apps))
+	return nil
+}
+
+// Get returns the current catalog
+func Get() *Catalog {
+	mu.RLock()
+	defer mu.RUnlock()
+	return instance
+}
+
+// Parse parses and validates catalog data
+func Parse(data []byte) (*Catalog, error) {
+	c := &Catalog{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog: %w", err)
+	}
+
+	if c.Version < 1 {
+		return nil, fmt.Errorf("catalog version must be at least 1")
+	}
+
+	seen := make(map[string]bool)
+	for _, app := range c.Apps {
+		id := strings.ToLower(app.ID)
+		if id == "" {
+			return nil, fmt.Errorf("catalog app %q has no id", app.Name)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate catalog app id %q", app.ID)
+		}
+		seen[id] = true
+	}
+
+	return c, nil
+}
+
+// Install validates data as a catalog, saves it as the updated catalog file and reloads
+func Install(data []byte) error {
+	c, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	if current := Get(); current != nil && c.Version < current.Version {
+		return fmt.Errorf("catalog version %d is older than current version %d", c.Version, current.Version)
+	}
+
+	if err := utils.WriteFileAtomic(overridePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+
+	return Load()
+}
+
+// Find returns the app with the given id (case-insensitive), or nil
+func (c *Catalog) Find(id string) *App {
+	for i := range c.Apps {
+		if strings.EqualFold(c.Apps[i].ID, id) {
+			return &c.Apps[i]
+		}
+	}
+	return nil
+}
+
+// AppsInCategory returns the catalog apps in the given category (case-insensitive)
+func (c *Catalog) AppsInCategory(category string) []App {
+	var apps []App
+	for _, app := range c.Apps {
+		if strings.EqualFold(app.Category, category) {
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+// Categories returns the sorted names of all catalog categories
+func (c *Catalog) Categories() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, app := range c.Apps {
+		if app.Category != "" && !seen[app.Category] {
+			seen[app.Category] = true
+			names = append(names, app.Category)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentExecutables returns the app's executable names on this platform
+func (a App) CurrentExecutables() []string {
+	return a.Executables[runtime.GOOS]
+}
+
+// Resolve returns the process names to block for cfg: its blocklist plus the
+// executables of every category in BlockCategories, without duplicates.
+// Categories defined in the config take precedence over catalog categories.
+func Resolve(cfg *config.Config) []string {
+	c := Get()
+
+	var result []string
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			key := strings.ToLower(name)
+			if name != "" && !seen[key] {
+				seen[key] = true
+				result = append(result, name)
+			}
+		}
+	}
+
+	add(cfg.Blocklist...)
+
+	for _, name := range cfg.BlockCategories {
+		if category := cfg.FindCategory(name); category != nil {
+			if c != nil {
+				for _, id := range category.Apps {
+					if app := c.Find(id); app != nil {
+						add(app.CurrentExecutables()...)
+					}
+				}
+			}
+			add(category.Processes...)
+			continue
+		}
+
+		if c != nil {
+			for _, app := range c.AppsInCategory(name) {
+				add(app.CurrentExecutables()...)
+			}
+		}
+	}
+
+	return result
+}
+
+// UnknownReferences returns category names and app ids referenced by cfg
+// (including its profiles) that neither the config nor the catalog defines
+func UnknownReferences(cfg *config.Config) []string {
+	c := Get()
+	if c == nil {
+		return nil
+	}
+
+	var unknown []string
+	seen := make(map[string]bool)
+	report := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			unknown = append(unknown, ref)
+		}
+	}
+
+	names := append([]string(nil), cfg.BlockCategories...)
+	for _, p := range cfg.Profiles {
+		names = append(names, p.BlockCategories...)
+	}
+
+	for _, name := range names {
+		if cfg.FindCategory(name) == nil && len(c.AppsInCategory(name)) == 0 {
+			report("category " + name)
+		}
+	}
+
+	for _, category := range cfg.Categories {
+		for _, id := range category.Apps {
+			if c.Find(id) == nil {
+				report("app " + id)
+			}
+		}
+	}
+
+	return unknown
+}
@@ -0,0 +1,402 @@
+// Package classifier asks the configured language model whether programs
+// seen during productive time are distractions, and keeps the answers as
+// blocklist suggestions for the user to approve
+package classifier
+
+import (
+	"appblock/ai"
+	"appblock/config"
+	"appblock/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// retryDelay pauses classification after a failed request
+const retryDelay = time.Minute
+
+// Decisions the user can make about a suggestion
+const (
+	DecisionApproved  = "approved"
+	DecisionDismissed = "dismissed"
+)
+
+// Classification is the model's verdict on one executable
+type Classification struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Distraction  bool      `json:"distraction"`
+	Category     string    `json:"category,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Decision     string    `json:"decision,omitempty"` // approved, dismissed, or empty while pending
+	ClassifiedAt time.Time `json:"classified_at"`
+}
+
+// candidate is a process waiting to be classified
+type candidate struct {
+	name  string
+	path  string
+	title string
+}
+
+// Classifier classifies unknown processes in the background. Each
+// executable is classified once; results are persisted.
+type Classifier struct {
+	completer ai.Completer
+	path      string
+	results   map[string]*Classification // keyed by lower-case process name
+	queued    map[string]bool
+	queue     chan candidate
+	onChange  func()
+	selfName  string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+}
+
+// New creates a classifier persisted at path. completer may be nil, in
+// which case nothing new is classified but earlier suggestions remain.
+func New(completer ai.Completer, path string) *Classifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Classifier{
+		completer: completer,
+		path:      path,
+		results:   make(map[string]*Classification),
+		queued:    make(map[string]bool),
+		queue:     make(chan candidate, 32),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	if exe, err := os.Executable(); err == nil {
+		c.selfName = strings.ToLower(filepath.Base(exe))
+	}
+	c.load()
+	return c
+}
+
+// Start starts the background classification worker
+func (c *Classifier) Start() {
+	go c.run()
+}
+
+// Stop stops the worker, cancelling any request in flight
+func (c *Classifier) Stop() {
+	c.cancel()
+}
+
+// SetCompleter replaces the model used for classification, e.g. after the
+// AI settings changed. nil pauses classification.
+func (c *Classifier) SetCompleter(completer ai.Completer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.completer = completer
+}
+
+// OnSuggestionsChanged sets a callback invoked when suggestions are added or resolved
+func (c *Classifier) OnSuggestionsChanged(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = fn
+}
+
+// Observe queues processes that were not classified before. procs should be
+// the running processes that are not blocked already.
+func (c *Classifier) Observe(procs []*process.Process) {
+	c.mu.Lock()
+	enabled := c.completer != nil
+	c.mu.Unlock()
+	if !enabled {
+		return
+	}
+
+	// Window titles are fetched lazily, only when there is something new
+	var titles map[int32]string
+	titlesLoaded := false
+
+	for _, proc := range procs {
+		name, err := proc.Name()
+		if err != nil || name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+
+		c.mu.Lock()
+		known := c.results[key] != nil || c.queued[key] || key == c.selfName
+		c.mu.Unlock()
+		if known {
+			continue
+		}
+
+		if !titlesLoaded {
+			titles = windowTitles()
+			titlesLoaded = true
+		}
+
+		// Only programs with a visible window are worth asking about; this
+		// skips services and helpers. Without window information, fall back
+		// to the executable location.
+		title := titles[proc.Pid]
+		if titles != nil && title == "" {
+			continue
+		}
+
+		exe, _ := proc.Exe()
+		if isSystemPath(exe) {
+			continue
+		}
+
+		c.mu.Lock()
+		select {
+		case c.queue <- candidate{name: name, path: exe, title: title}:
+			c.queued[key] = true
+		default:
+			// Queue full - the process is picked up on a later scan
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Suggestions returns the pending suggestions: distractions the user has
+// neither approved nor dismissed, sorted by name
+func (c *Classifier) Suggestions() []Classification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result []Classification
+	for _, r := range c.results {
+		if r.Distraction && r.Decision == "" {
+			result = append(result, *r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// Approve adds the suggested process to the blocklist in effect, i.e. the
+// active profile's if it has its own
+func (c *Classifier) Approve(name string) error {
+	if err := c.decide(name, DecisionApproved); err != nil {
+		return err
+	}
+
+	var profile string
+	err := config.Update(func(cfg *config.Config) {
+		profile = cfg.BlockApp(name, time.Now())
+	})
+	if err == nil {
+		utils.LogInfo("Added %s to the blocklist of profile %s", name, profile)
+	}
+	return err
+}
+
+// Dismiss hides the suggestion without blocking the process
+func (c *Classifier) Dismiss(name string) error {
+	return c.decide(name, DecisionDismissed)
+}
+
+// decide records the user's decision about a suggestion
+func (c *Classifier) decide(name, decision string) error {
+	c.mu.Lock()
+	r := c.results[strings.ToLower(name)]
+	if r == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("no suggestion for %s", name)
+	}
+	r.Decision = decision
+	c.mu.Unlock()
+
+	utils.LogInfo("Suggestion to block %s %s", name, decision)
+	c.save()
+	c.notify()
+	return nil
+}
+
+// run is the classification worker loop
+func (c *Classifier) run() {
+	for {
+		select {
+		case cand := <-c.queue:
+			if !c.classify(cand) {
+				// Back off on failure, but stay responsive to Stop
+				select {
+				case <-time.After(retryDelay):
+				case <-c.ctx.Done():
+					return
+				}
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// classify asks the model about one process. Returns false if the request failed.
+func (c *Classifier) classify(cand candidate) bool {
+	key := strings.ToLower(cand.name)
+
+	c.mu.Lock()
+	completer := c.completer
+	c.mu.Unlock()
+
+	// Forget the candidate on failure so a later scan retries it
+	done := func() {
+		c.mu.Lock()
+		delete(c.queued, key)
+		c.mu.Unlock()
+	}
+	defer done()
+
+	if completer == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 3*ai.DefaultTimeout)
+	reply, err := completer.Complete(ctx, ai.BuildClassifyPrompt(cand.name, cand.path, cand.title))
+	cancel()
+	if err != nil {
+		if c.ctx.Err() == nil {
+			utils.LogWarning("Failed to classify %s: %v", cand.name, err)
+		}
+		return false
+	}
+
+	result, err := parseReply(reply)
+	if err != nil {
+		// Still cache it, so a model that cannot answer is not asked again
+		utils.LogWarning("Unexpected classification reply for %s: %v", cand.name, err)
+	}
+	result.Name = cand.name
+	result.Path = cand.path
+	result.Title = cand.title
+	result.ClassifiedAt = time.Now()
+
+	c.mu.Lock()
+	c.results[key] = &result
+	c.mu.Unlock()
+
+	if result.Distraction {
+		utils.LogInfo("Suggesting to block %s (%s): %s", cand.name, result.Category, result.Reason)
+	} else {
+		utils.LogDebug("Classified %s as not a distraction", cand.name)
+	}
+
+	c.save()
+	if result.Distraction {
+		c.notify()
+	}
+	return true
+}
+
+// parseReply extracts the JSON verdict from the model's reply, which may
+// wrap it in prose or a code block
+func parseReply(reply string) (Classification, error) {
+	var result Classification
+
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return result, errors.New("no JSON object in reply")
+	}
+
+	var verdict struct {
+		Distraction bool   `json:"distraction"`
+		Category    string `json:"category"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &verdict); err != nil {
+		return result, err
+	}
+
+	result.Distraction = verdict.Distraction
+	result.Category = strings.TrimSpace(verdict.Category)
+	result.Reason = strings.TrimSpace(verdict.Reason)
+	return result, nil
+}
+
+// isSystemPath reports whether exe belongs to the operating system
+func isSystemPath(exe string) bool {
+	if exe == "" {
+		// Kernel threads and processes we may not inspect
+		return runtime.GOOS != "windows"
+	}
+
+	lower := strings.ToLower(exe)
+	if runtime.GOOS == "windows" {
+		systemRoot := strings.ToLower(os.Getenv("SystemRoot"))
+		if systemRoot == "" {
+			systemRoot = `c:\windows`
+		}
+		return strings.HasPrefix(lower, systemRoot+`\`)
+	}
+
+	for _, dir := range []string{"/usr/lib/", "/usr/libexec/", "/usr/sbin/", "/sbin/", "/lib/", "/snap/core"} {
+		if strings.HasPrefix(lower, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// notify invokes the change callback
+func (c *Classifier) notify() {
+	c.mu.Lock()
+	fn := c.onChange
+	c.mu.Unlock()
+
+	if fn != nil {
+		fn()
+	}
+}
+
+// load reads the persisted classifications
+func (c *Classifier) load() {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+
+	var results []*Classification
+	if err := json.Unmarshal(data, &results); err != nil {
+		utils.LogWarning("Ignoring corrupt classifications file %s: %v", c.path, err)
+		return
+	}
+
+	for _, r := range results {
+		c.results[strings.ToLower(r.Name)] = r
+	}
+}
+
+// save persists the classifications
+func (c *Classifier) save() {
+	c.mu.Lock()
+	results := make([]*Classification, 0, len(c.results))
+	for _, r := range c.results {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+	data, err := json.MarshalIndent(results, "", "  ")
+	c.mu.Unlock()
+
+	if err != nil {
+		utils.LogWarning("Failed to encode classifications: %v", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(c.path, data, 0644); err != nil {
+		utils.LogWarning("Failed to save classifications: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCompleter returns a fixed reply and records the prompts it received
+type fakeCompleter struct {
+	reply   string
+	prompts []string
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	return f.reply, nil
+}
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		reply       string
+		distraction bool
+		category    string
+		wantErr     bool
+	}{
+		{`{"distraction": true, "category": "Games", "reason": "A game launcher"}`, true, "Games", false},
+		{"```json\n{\"distraction\": false, \"category\": \"Work\", \"reason\": \"IDE\"}\n```", false, "Work", false},
+		{`Sure! {"distraction": true, "category": " Video ", "reason": "Streaming"} Hope this helps.`, true, "Video", false},
+		{"I am not sure.", false, "", true},
+		{`{"distraction": maybe}`, false, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReply(tt.reply)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseReply(%q) error = %v, wantErr %v", tt.reply, err, tt.wantErr)
+			continue
+		}
+		if got.Distraction != tt.distraction || got.Category != tt.category {
+			t.Errorf("parseReply(%q) = %+v, want distraction %v category %q", tt.reply, got, tt.distraction, tt.category)
+		}
+	}
+}
+
+func TestClassifySuggestsAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "classifications.json")
+	completer := &fakeCompleter{reply: `{"distraction": true, "category": "Games", "reason": "Game launcher"}`}
+	c := New(completer, path)
+
+	if !c.classify(candidate{name: "Steam.exe", path: `C:\Steam\steam.exe`, title: "Steam"}) {
+		t.Fatal("classify failed")
+	}
+	if len(completer.prompts) != 1 || !strings.Contains(completer.prompts[0], "Steam.exe") {
+		t.Fatalf("prompts = %q, want one mentioning Steam.exe", completer.prompts)
+	}
+
+	suggestions := c.Suggestions()
+	if len(suggestions) != 1 || suggestions[0].Name != "Steam.exe" || suggestions[0].Category != "Games" {
+		t.Fatalf("Suggestions() = %+v, want Steam.exe (Games)", suggestions)
+	}
+
+	// Classifications survive a restart and are looked up case-insensitively
+	reloaded := New(nil, path)
+	if len(reloaded.Suggestions()) != 1 {
+		t.Fatalf("reloaded Suggestions() = %+v, want 1", reloaded.Suggestions())
+	}
+	if err := reloaded.Dismiss("steam.exe"); err != nil {
+		t.Fatalf("Dismiss: %v", err)
+	}
+	if got := reloaded.Suggestions(); len(got) != 0 {
+		t.Fatalf("Suggestions() after Dismiss = %+v, want none", got)
+	}
+	if got := New(nil, path).Suggestions(); len(got) != 0 {
+		t.Fatalf("dismissed suggestion came back after restart: %+v", got)
+	}
+
+	if err := reloaded.Dismiss("unknown.exe"); err == nil {
+		t.Error("Dismiss of an unknown process succeeded")
+	}
+}
+
+func TestClassifyNotDistraction(t *testing.T) {
+	completer := &fakeCompleter{reply: `{"distraction": false, "category": "Work", "reason": "Code editor"}`}
+	c := New(completer, filepath.Join(t.TempDir(), "classifications.json"))
+
+	c.classify(candidate{name: "code.exe"})
+	if got := c.Suggestions(); len(got) != 0 {
+		t.Fatalf("Suggestions() = %+v, want none", got)
+	}
+	if c.results["code.exe"] == nil {
+		t.Fatal("classification was not cached")
+	}
+}
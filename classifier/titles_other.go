@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package classifier
+
+// windowTitles is not available outside Windows; processes are filtered by
+// executable location only
+func windowTitles() map[int32]string {
+	return nil
+}
@@ -0,0 +1,54 @@
+//go:build windows
+// +build windows
+
+package classifier
+
+import (
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procGetWindowTextW = windows.NewLazySystemDLL("user32.dll").NewProc("GetWindowTextW")
+
+	// Callbacks are a limited resource, so one is created for the lifetime of the process
+	enumWindowsCallback = windows.NewCallback(collectWindowTitle)
+
+	titlesMu sync.Mutex
+	titles   map[int32]string
+)
+
+// windowTitles returns the title of a visible top-level window of each
+// process that has one, keyed by process ID
+func windowTitles() map[int32]string {
+	titlesMu.Lock()
+	defer titlesMu.Unlock()
+
+	titles = make(map[int32]string)
+	windows.EnumWindows(enumWindowsCallback, nil)
+	return titles
+}
+
+// collectWindowTitle is the EnumWindows callback
+func collectWindowTitle(hwnd windows.HWND, _ uintptr) uintptr {
+	if !windows.IsWindowVisible(hwnd) {
+		return 1
+	}
+
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return 1
+	}
+	if titles[int32(pid)] != "" {
+		return 1
+	}
+
+	buf := make([]uint16, 256)
+	n, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if n > 0 {
+		titles[int32(pid)] = windows.UTF16ToString(buf[:n])
+	}
+	return 1 // continue enumeration
+}
@@ -0,0 +1,604 @@
+package main
+
+import (
+	"appblock/ai"
+	"appblock/calendar"
+	"appblock/catalog"
+	"appblock/config"
+	"appblock/history"
+	"appblock/logview"
+	"appblock/paths"
+	"appblock/report"
+	"appblock/secret"
+	"appblock/utils"
+	"appblock/web"
+	"appblock/website"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const cliUsage = `Usage: appblock [command]
+
+Without a command APPBlock starts in the system tray. With --headless it runs
+without the tray and serves the web dashboard instead (the default on Linux).
+
+Commands:
+  profile              List profiles and show which one is active
+  profile <name>       Switch to a profile ("Default" returns to schedule-based switching)
+  catalog              List app categories and the executables they block
+  catalog update <src> Install a newer app catalog from a URL or file
+  calendar             Read the configured calendar and list the upcoming focus blocks
+  dashboard            Print the link that opens the web dashboard
+  goal                 Show the session goal included in AI prompts
+  goal <text>          Set the session goal, e.g. appblock goal "Finish chapter 3"
+  goal --clear         Clear the session goal
+  key                  Show where API keys are stored and which are set
+  key set [name]       Store an API key (read from the terminal or stdin)
+  key test [name]      Send a test request with the configured AI provider
+  key delete [name]    Delete a stored API key
+  key rotate           Re-encrypt the encrypted secrets file under a new key
+                       [name] defaults to the key of the configured provider
+  logs                 Print the log; options:
+                         --since <1h|2d|2006-01-02>  only newer entries (default 24h)
+                         --level <debug|info|warn|error>  minimum level (default info)
+                         --app <name.exe>  only entries about this app
+                         --follow  keep printing new entries
+  logs export [file]   Zip the logs, config (secrets removed) and version for a bug report
+  paths                Show where config, data and logs are kept
+  report               Write the weekly summary of last week to the reports folder
+  report current       Write the summary of the current week so far
+  websites restore     Remove APPBlock's blocked domains from the hosts file
+  help                 Show this help
+`
+
+// runCLI runs a command-line subcommand and returns the process exit code.
+// Changes are written to config.json and picked up by the running tray app.
+func runCLI(args []string) int {
+	attachConsole()
+
+	if err := paths.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	for _, moved := range paths.Migrated() {
+		fmt.Fprintf(os.Stderr, "Moved %s from the executable directory\n", moved)
+	}
+
+	switch args[0] {
+	case "profile", "profiles":
+		return cliProfile(args[1:])
+	case "catalog":
+		return cliCatalog(args[1:])
+	case "calendar":
+		return cliCalendar()
+	case "dashboard":
+		return cliDashboard()
+	case "goal":
+		return cliGoal(args[1:])
+	case "key", "keys":
+		return cliKey(args[1:])
+	case "logs", "log":
+		return cliLogs(args[1:])
+	case "paths":
+		return cliPaths()
+	case "report":
+		return cliReport(args[1:])
+	case "websites":
+		return cliWebsites(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+}
+
+// cliProfile lists profiles or switches the active profile
+func cliProfile(args []string) int {
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	if len(args) == 0 {
+		cfg := config.Get()
+		selected := cfg.ActiveProfile
+		if selected == "" {
+			selected = config.DefaultProfileName
+		}
+
+		for _, name := range cfg.ProfileNames() {
+			marker := "  "
+			if strings.EqualFold(name, selected) {
+				marker = "* "
+			}
+			fmt.Println(marker + name)
+		}
+		return 0
+	}
+
+	name := strings.Join(args, " ")
+	if err := config.SetActiveProfile(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to switch profile: %v\n", err)
+		return 1
+	}
+
+	// Print the profile as configured, not as typed
+	selected := config.Get().ActiveProfile
+	if selected == "" {
+		selected = config.DefaultProfileName
+	}
+	fmt.Printf("Switched to profile %q\n", selected)
+	return 0
+}
+
+// cliGoal shows, sets or clears the session goal
+func cliGoal(args []string) int {
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	if len(args) == 0 {
+		if goal := config.Get().AI.Goal; goal != "" {
+			fmt.Println(goal)
+		} else {
+			fmt.Println("No session goal set")
+		}
+		return 0
+	}
+
+	goal := strings.TrimSpace(strings.Join(args, " "))
+	if goal == "--clear" {
+		goal = ""
+	}
+	if err := config.SetGoal(goal); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save goal: %v\n", err)
+		return 1
+	}
+
+	if goal == "" {
+		fmt.Println("Session goal cleared")
+	} else {
+		fmt.Printf("Session goal set to %q\n", goal)
+	}
+	return 0
+}
+
+// cliKey manages API keys in the secret store. Key values are never printed.
+func cliKey(args []string) int {
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	secret.Init(paths.ConfigDir())
+	aiCfg := config.Get().AI
+
+	if len(args) == 0 {
+		fmt.Printf("Secret store: %s\n", secret.Backend())
+		names := []string{"GEMINI_API_KEY", "OPENAI_API_KEY"}
+		if name := aiCfg.APIKeyName(); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+		if config.Get().Calendar.Username != "" {
+			names = append(names, config.CalendarPasswordName)
+		}
+
+		for _, name := range names {
+			status := "not set"
+			if _, err := secret.Get(name); err == nil {
+				status = "stored"
+			} else if !errors.Is(err, secret.ErrNotFound) {
+				status = "unreadable: " + err.Error()
+			} else if os.Getenv(name) != "" {
+				status = "set in environment"
+			}
+
+			marker := "  "
+			if name == aiCfg.APIKeyName() {
+				marker = "* "
+			}
+			fmt.Printf("%s%-16s %s\n", marker, name, status)
+		}
+		return 0
+	}
+
+	name := aiCfg.APIKeyName()
+	if len(args) > 1 {
+		name = args[1]
+	}
+	if args[0] == "rotate" {
+		if err := secret.RotateKey(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to rotate key: %v\n", err)
+			return 1
+		}
+		fmt.Println("Encrypted secrets file now uses a new key")
+		return 0
+	}
+	if name == "" && args[0] != "test" {
+		fmt.Fprintf(os.Stderr, "AI provider %q does not use an API key - pass a key name\n", aiCfg.Provider)
+		return 2
+	}
+
+	switch args[0] {
+	case "set":
+		value, err := readKey(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read key: %v\n", err)
+			return 1
+		}
+		if value == "" {
+			fmt.Fprintln(os.Stderr, "No key entered")
+			return 1
+		}
+		if err := secret.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to store key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s stored in %s\n", name, secret.Backend())
+		return 0
+
+	case "test":
+		if err := testAPIKey(aiCfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Test failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s works with provider %s\n", name, providerName(aiCfg.Provider))
+		return 0
+
+	case "delete":
+		if err := secret.Delete(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s deleted from %s\n", name, secret.Backend())
+		return 0
+
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+}
+
+// readKey reads a key without echoing it when stdin is a terminal, or the
+// first line of stdin otherwise (e.g. piped from a password manager)
+func readKey(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Printf("Enter %s: ", name)
+		value, err := term.ReadPassword(fd)
+		fmt.Println()
+		return strings.TrimSpace(string(value)), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// cliCatalog lists the app catalog or installs an updated one
+func cliCatalog(args []string) int {
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	if err := catalog.Init(paths.ConfigDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load catalog: %v\n", err)
+		return 1
+	}
+
+	if len(args) == 0 {
+		c := catalog.Get()
+		fmt.Printf("App catalog version %d\n", c.Version)
+		for _, category := range c.Categories() {
+			fmt.Printf("\n%s\n", category)
+			for _, app := range c.AppsInCategory(category) {
+				fmt.Printf("  %-14s %s (%s)\n", app.ID, app.Name, strings.Join(app.CurrentExecutables(), ", "))
+			}
+		}
+		return 0
+	}
+
+	if args[0] != "update" || len(args) != 2 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	data, err := readSource(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read catalog: %v\n", err)
+		return 1
+	}
+	if err := catalog.Install(data); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to install catalog: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Catalog updated to version %d - use Reload Config in the tray to apply\n", catalog.Get().Version)
+	return 0
+}
+
+// readSource reads a URL (http/https) or a local file
+func readSource(src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// cliReport writes and prints a weekly summary, using the AI provider when
+// its key is available
+func cliReport(args []string) int {
+	start := report.LastWeek(time.Now())
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "current":
+		start = report.WeekStart(time.Now())
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	cfg := config.Get()
+
+	secret.Init(paths.ConfigDir())
+	if err := history.Init(paths.DataDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load block history: %v\n", err)
+		return 1
+	}
+	if err := ai.InitTemplates(paths.ConfigDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	ai.SetLanguage(cfg.AI.Language)
+	report.Init(paths.DataDir())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*ai.DefaultTimeout)
+	defer cancel()
+
+	r, err := report.Generate(ctx, reportCompleter(cfg.AI), cfg, start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(r.Summary)
+	fmt.Printf("\nSaved to %s\n", r.Path)
+	return 0
+}
+
+// cliPaths prints the directories APPBlock keeps its files in
+func cliPaths() int {
+	d := paths.Get()
+	mode := "per-user"
+	if d.Portable {
+		mode = "portable"
+	}
+
+	fmt.Printf("Mode:    %s\n", mode)
+	fmt.Printf("Config:  %s\n", d.Config)
+	fmt.Printf("Data:    %s\n", d.Data)
+	fmt.Printf("Logs:    %s\n", d.State)
+	fmt.Printf("Lock:    %s\n", d.Runtime)
+	return 0
+}
+
+// cliCalendar reads the calendar once and prints the focus blocks it found
+func cliCalendar() int {
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	secret.Init(paths.ConfigDir())
+
+	cfg := config.Get().Calendar
+	if !cfg.Enabled() {
+		fmt.Println("No calendar configured; set \"calendar\": {\"source\": ...} in config.json.")
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cal := calendar.New()
+	cal.Apply(cfg)
+	if err := cal.Refresh(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read calendar: %v\n", err)
+		return 1
+	}
+
+	now := time.Now()
+	fmt.Printf("Focus blocks matching %s in the next %d days:\n", strings.Join(cfg.Patterns(), ", "), int(calendar.Horizon.Hours()/24))
+	found := false
+	for _, b := range cal.Blocks() {
+		if !b.End.After(now) {
+			continue
+		}
+		found = true
+		start, end := b.Start.Local(), b.End.Local()
+		endLayout := "15:04"
+		if start.YearDay() != end.YearDay() || start.Year() != end.Year() {
+			endLayout = "Mon 02 Jan 15:04"
+		}
+		fmt.Printf("  %s - %s  %s\n", start.Format("Mon 02 Jan 15:04"), end.Format(endLayout), b.Title)
+	}
+	if !found {
+		fmt.Println("  (none)")
+	}
+	for _, skipped := range cal.Skipped() {
+		fmt.Fprintf(os.Stderr, "Ignored unreadable event %s\n", skipped)
+	}
+	return 0
+}
+
+// cliDashboard prints the dashboard link with its access token
+func cliDashboard() int {
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	token, err := web.LoadToken(paths.ConfigDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	cfg := config.Get()
+	fmt.Println(web.DashboardURL(cfg.Dashboard.Addr(), token))
+	if !cfg.Dashboard.Enabled && trayAvailable {
+		fmt.Fprintln(os.Stderr, "The dashboard is off; enable \"dashboard\" in config.json or start APPBlock with --headless.")
+	}
+	return 0
+}
+
+// cliLogs prints or follows the log, or exports a diagnostic bundle
+func cliLogs(args []string) int {
+	if len(args) > 0 && args[0] == "export" {
+		return cliLogsExport(args[1:])
+	}
+
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	since := flags.String("since", "24h", "only entries newer than a duration (1h, 2d) or date")
+	level := flags.String("level", "info", "minimum level: debug, info, warn or error")
+	app := flags.String("app", "", "only entries about this app")
+	follow := flags.Bool("follow", false, "keep printing new entries")
+	flags.BoolVar(follow, "f", false, "shorthand for --follow")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var filter logview.Filter
+	var err error
+	if filter.Since, err = parseSince(*since, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --since: %v\n", err)
+		return 2
+	}
+	if filter.Level, err = logview.ParseLevel(*level); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --level: %v\n", err)
+		return 2
+	}
+	filter.App = *app
+
+	path, err := utils.DefaultLogPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find the log: %v\n", err)
+		return 1
+	}
+
+	entries, err := logview.Read(utils.LogFiles(path), filter)
+	for _, e := range entries {
+		fmt.Println(e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the log: %v\n", err)
+		return 1
+	}
+
+	if !*follow {
+		return 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = logview.Follow(ctx, path, filter, func(e logview.Entry) {
+		fmt.Println(e)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to follow the log: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cliLogsExport writes a diagnostic bundle
+func cliLogsExport(args []string) int {
+	path := logview.DefaultBundleName()
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	// A broken config is still worth exporting
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	secret.Init(paths.ConfigDir())
+
+	if err := logview.ExportDiagnostics(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export diagnostic bundle: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Diagnostic bundle saved to %s\n", path)
+	return 0
+}
+
+// parseSince parses a duration before now (e.g. 30m, 1h, 2d), a date or an
+// RFC 3339 time. "" or "all" means no limit.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" || value == "all" {
+		return time.Time{}, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		if _, err := fmt.Sscanf(days, "%d", &n); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration (1h, 2d) or date (2006-01-02)", value)
+}
+
+// cliWebsites restores the hosts file, e.g. after APPBlock was killed while blocking
+func cliWebsites(args []string) int {
+	if len(args) != 1 || args[0] != "restore" {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	if err := website.Restore(paths.DataDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to restore hosts file: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("Hosts file restored")
+	return 0
+}
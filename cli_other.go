@@ -0,0 +1,7 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// attachConsole is a no-op outside Windows, where stdout is always available
+func attachConsole() {}
@@ -0,0 +1,29 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// attachConsole attaches to the console of the parent process so CLI output
+// is visible even though the binary is built as a GUI application
+func attachConsole() {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	attach := kernel32.NewProc("AttachConsole")
+
+	const attachParentProcess = ^uintptr(0) // (DWORD)-1
+	if ret, _, _ := attach.Call(attachParentProcess); ret == 0 {
+		return
+	}
+
+	if out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = out
+		os.Stderr = out
+	}
+	if in, err := os.OpenFile("CONIN$", os.O_RDWR, 0); err == nil {
+		os.Stdin = in
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// CalendarPasswordName is the secret holding the CalDAV password
+const CalendarPasswordName = "CALDAV_PASSWORD"
+
+// defaultCalendarMatch are the title patterns used without calendar.match
+var defaultCalendarMatch = []string{"Focus", "Deep Work"}
+
+// CalendarConfig reads productive time from a calendar, in addition to the
+// time windows. Leaving source empty turns it off.
+type CalendarConfig struct {
+	Source         string   `json:"source,omitempty"`          // Path of a .ics file, or an iCalendar or CalDAV URL
+	Match          []string `json:"match,omitempty"`           // Events whose title contains one of these are productive time (default Focus, Deep Work)
+	Username       string   `json:"username,omitempty"`        // CalDAV login; the password is the CALDAV_PASSWORD secret
+	RefreshMinutes int      `json:"refresh_minutes,omitempty"` // How often the calendar is read again (default 15)
+}
+
+// Enabled reports whether a calendar is configured
+func (c CalendarConfig) Enabled() bool {
+	return c.Source != ""
+}
+
+// Patterns returns the title patterns of focus blocks
+func (c CalendarConfig) Patterns() []string {
+	if len(c.Match) == 0 {
+		return defaultCalendarMatch
+	}
+	return c.Match
+}
+
+// Refresh returns how often the calendar is read
+func (c CalendarConfig) Refresh() time.Duration {
+	if c.RefreshMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.RefreshMinutes) * time.Minute
+}
+
+// IsURL reports whether the source is fetched over HTTP rather than read from a file
+func (c CalendarConfig) IsURL() bool {
+	u, err := url.Parse(c.Source)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "webcal")
+}
+
+// validate checks the calendar settings
+func (c CalendarConfig) validate() error {
+	if c.RefreshMinutes < 0 {
+		return fmt.Errorf("calendar refresh_minutes must not be negative, got %d", c.RefreshMinutes)
+	}
+	if c.IsURL() {
+		u, _ := url.Parse(c.Source)
+		if u.Host == "" {
+			return fmt.Errorf("calendar source %q has no host", c.Source)
+		}
+		// The password would be sent in clear text; only this machine may see it
+		if c.Username != "" && u.Scheme == "http" && !isLoopback(u.Hostname()) {
+			return fmt.Errorf("calendar username needs an https source, the password would be sent unencrypted")
+		}
+	}
+	return nil
+}
+
+// isLoopback reports whether host names this machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
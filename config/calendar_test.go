@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestCalendarValidate(t *testing.T) {
+	tests := []struct {
+		cfg   CalendarConfig
+		valid bool
+	}{
+		{CalendarConfig{}, true},
+		{CalendarConfig{Source: "/home/me/focus.ics"}, true},
+		{CalendarConfig{Source: "https://cal.example.com/dav/", Username: "me"}, true},
+		{CalendarConfig{Source: "webcal://cal.example.com/feed.ics"}, true},
+		{CalendarConfig{Source: "http://cal.example.com/feed.ics"}, true},
+		{CalendarConfig{Source: "http://cal.example.com/dav/", Username: "me"}, false},
+		{CalendarConfig{Source: "http://localhost:5232/me/", Username: "me"}, true},
+		{CalendarConfig{Source: "http://127.0.0.1:5232/me/", Username: "me"}, true},
+		{CalendarConfig{Source: "https:///feed.ics"}, false},
+		{CalendarConfig{Source: "/home/me/focus.ics", RefreshMinutes: -1}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: err = %v, want valid %v", tt.cfg, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category is a named group of process rules (e.g. "Games", "Social").
+// Apps refers to entries in the app catalog; Processes lists extra executable names.
+// A category name that is not defined here falls back to the catalog category of the same name.
+type Category struct {
+	Name      string   `json:"name"`
+	Apps      []string `json:"apps,omitempty"`
+	Processes []string `json:"processes,omitempty"`
+}
+
+// clone returns a deep copy of the category
+func (c Category) clone() Category {
+	c.Apps = cloneSlice(c.Apps)
+	c.Processes = cloneSlice(c.Processes)
+	return c
+}
+
+// FindCategory returns the category with the given name (case-insensitive), or nil
+func (c *Config) FindCategory(name string) *Category {
+	for i := range c.Categories {
+		if strings.EqualFold(c.Categories[i].Name, name) {
+			return &c.Categories[i]
+		}
+	}
+	return nil
+}
+
+// validateCategories checks category names
+func (c *Config) validateCategories() error {
+	seen := make(map[string]bool)
+	for _, cat := range c.Categories {
+		name := strings.ToLower(strings.TrimSpace(cat.Name))
+		if name == "" {
+			return fmt.Errorf("category name must not be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate category name %q", cat.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
@@ -1,14 +1,21 @@
 package config
 
 import (
+	"appblock/paths"
+	"appblock/utils"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 )
 
-// TimeWindow represents a time range for productive hours
+// TimeWindow represents a time range for productive hours. Both ends are
+// inclusive minutes: 09:00-12:00 is productive until 12:00:59 and ends at
+// 12:01, and 23:59 ends at the next midnight. Windows cannot span midnight;
+// split an overnight window into 22:00-23:59 and 00:00-06:00.
 type TimeWindow struct {
 	Start string `json:"start"` // Format: "HH:MM"
 	End   string `json:"end"`   // Format: "HH:MM"
@@ -19,24 +26,118 @@ type AIConfig struct {
 	Enabled     bool   `json:"enabled"`
 	Personality string `json:"personality"`
 	Model       string `json:"model"`
+	Provider    string `json:"provider,omitempty"`    // gemini (default), openai, ollama, llamacpp, template
+	Endpoint    string `json:"endpoint,omitempty"`    // Base URL override for the provider API
+	APIKeyEnv   string `json:"api_key_env,omitempty"` // Env/.env variable holding the openai key (default OPENAI_API_KEY)
+	Goal        string `json:"goal,omitempty"`        // What the user wants to get done this session, included in prompts
+	Language    string `json:"language,omitempty"`    // Language of prompts and default messages: id (default), en
+	// LiveMessages asks the model when an app is blocked, so the message
+	// knows the time and today's attempts; the popup waits for the reply.
+	// Otherwise messages come from the prefetched pool.
+	LiveMessages bool `json:"live_messages,omitempty"`
+	// ClassifyUnknown asks the model whether unknown programs running during
+	// productive time are distractions, and suggests blocking them
+	ClassifyUnknown bool `json:"classify_unknown,omitempty"`
+}
+
+// APIKeyName returns the name of the secret (or environment variable)
+// holding the provider's API key, or "" for providers that need none
+func (a AIConfig) APIKeyName() string {
+	switch a.Provider {
+	case "", "gemini":
+		return "GEMINI_API_KEY"
+	case "openai":
+		if a.APIKeyEnv != "" {
+			return a.APIKeyEnv
+		}
+		return "OPENAI_API_KEY"
+	default:
+		return ""
+	}
+}
+
+// LoggingConfig represents log file settings. Zero values use the defaults.
+type LoggingConfig struct {
+	Level      string `json:"level,omitempty"`        // debug, info (default), warn, error
+	Format     string `json:"format,omitempty"`       // text (default) or json
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`  // Rotate app.log past this size (default 5)
+	MaxAgeDays int    `json:"max_age_days,omitempty"` // Delete rotated logs older than this (default 14)
+	MaxBackups int    `json:"max_backups,omitempty"`  // Rotated logs to keep (default 10)
+}
+
+// Options converts the settings for utils.ConfigureLogger
+func (l LoggingConfig) Options() utils.LogOptions {
+	return utils.LogOptions{
+		Level:      l.Level,
+		Format:     l.Format,
+		MaxSizeMB:  l.MaxSizeMB,
+		MaxAgeDays: l.MaxAgeDays,
+		MaxBackups: l.MaxBackups,
+	}
+}
+
+// DefaultMetricsAddress is where metrics are served when enabled without an
+// address. It is loopback only, so a central Prometheus cannot scrape it;
+// the address must be set to a LAN or wildcard address for that.
+const DefaultMetricsAddress = "127.0.0.1:9464"
+
+// MetricsConfig represents the optional Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address,omitempty"` // host:port to listen on (default 127.0.0.1:9464)
+}
+
+// Addr returns the address to listen on
+func (m MetricsConfig) Addr() string {
+	if m.Address == "" {
+		return DefaultMetricsAddress
+	}
+	return m.Address
+}
+
+// DefaultDashboardAddress is where the web dashboard is served when enabled without an address
+const DefaultDashboardAddress = "127.0.0.1:9465"
+
+// DashboardConfig represents the local web dashboard
+type DashboardConfig struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address,omitempty"` // host:port to listen on (default 127.0.0.1:9465)
+}
+
+// Addr returns the address to listen on
+func (d DashboardConfig) Addr() string {
+	if d.Address == "" {
+		return DefaultDashboardAddress
+	}
+	return d.Address
 }
 
 // Config represents the application configuration
 type Config struct {
-	Enabled               bool         `json:"enabled"`
-	Autostart             bool         `json:"autostart"`
-	ScanIntervalSeconds   int          `json:"scan_interval_seconds"`
-	PopupCooldownSeconds  int          `json:"popup_cooldown_seconds"`
-	ActiveDays            []string     `json:"active_days"`
-	TimeWindows           []TimeWindow `json:"time_windows"`
-	Blocklist             []string     `json:"blocklist"`
-	AI                    AIConfig     `json:"ai"`
-	FirstRunCompleted     bool         `json:"first_run_completed"`
+	Enabled              bool            `json:"enabled"`
+	Autostart            bool            `json:"autostart"`
+	ScanIntervalSeconds  int             `json:"scan_interval_seconds"`
+	PopupCooldownSeconds int             `json:"popup_cooldown_seconds"`
+	WarnMinutesBefore    int             `json:"warn_minutes_before"` // Warn this long before blocking starts, listing apps that will be closed (0 = off)
+	ActiveDays           []string        `json:"active_days"`
+	TimeWindows          []TimeWindow    `json:"time_windows"`
+	Blocklist            []string        `json:"blocklist"`
+	BlockCategories      []string        `json:"block_categories,omitempty"`
+	Categories           []Category      `json:"categories,omitempty"`
+	BlockedDomains       []string        `json:"blocked_domains,omitempty"`
+	AI                   AIConfig        `json:"ai"`
+	FirstRunCompleted    bool            `json:"first_run_completed"`
+	Profiles             []Profile       `json:"profiles,omitempty"`
+	ActiveProfile        string          `json:"active_profile,omitempty"`
+	Logging              LoggingConfig   `json:"logging,omitempty"`
+	Metrics              MetricsConfig   `json:"metrics,omitempty"`
+	Dashboard            DashboardConfig `json:"dashboard,omitempty"`
+	Hooks                []Hook          `json:"hooks,omitempty"`
+	Calendar             CalendarConfig  `json:"calendar,omitempty"`
 }
 
 var (
 	configPath string
-	instance   *Config
 )
 
 // Default configuration
@@ -46,6 +147,7 @@ func defaultConfig() *Config {
 		Autostart:            true,
 		ScanIntervalSeconds:  5,
 		PopupCooldownSeconds: 60,
+		WarnMinutesBefore:    5,
 		ActiveDays:           []string{"Mon", "Tue", "Wed", "Thu", "Fri"},
 		TimeWindows: []TimeWindow{
 			{Start: "09:00", End: "12:00"},
@@ -62,25 +164,28 @@ func defaultConfig() *Config {
 	}
 }
 
-// Init initializes the config system
+// maxWarnMinutes bounds WarnMinutesBefore
+const maxWarnMinutes = 120
+
+// Init initializes the config system from config.json in the config directory
 func Init() error {
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-	exeDir := filepath.Dir(exePath)
-	configPath = filepath.Join(exeDir, "config.json")
-	
+	configPath = filepath.Join(paths.ConfigDir(), "config.json")
+
 	return Load()
 }
 
-// Load loads configuration from file or creates default
+// Load loads configuration from file or creates default. An invalid file
+// is an error and leaves the current configuration in effect.
 func Load() error {
 	// Check if file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		// Create default config
-		instance = defaultConfig()
-		return Save()
+		cfg := defaultConfig()
+		if err := save(cfg); err != nil {
+			return err
+		}
+		apply(cfg)
+		return nil
 	}
 
 	// Read existing config
@@ -89,70 +194,245 @@ func Load() error {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 
-	instance = &Config{}
-	if err := json.Unmarshal(data, instance); err != nil {
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
 
+	apply(cfg)
 	return nil
 }
 
 // Save saves current configuration to file
 func Save() error {
-	if instance == nil {
+	cfg := current()
+	if cfg == nil {
 		return fmt.Errorf("config not initialized")
 	}
 
-	data, err := json.MarshalIndent(instance, "", "  ")
+	return save(cfg)
+}
+
+// save writes the given configuration to file
+func save(cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := utils.WriteFileAtomic(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
 	return nil
 }
 
-// Get returns the current configuration
-func Get() *Config {
-	return instance
-}
-
 // GetPath returns the config file path
 func GetPath() string {
 	return configPath
 }
 
+// Validate checks the configuration for values the scheduler and blocker cannot work with
+func (c *Config) Validate() error {
+	if c.ScanIntervalSeconds < 1 {
+		return fmt.Errorf("scan_interval_seconds must be at least 1, got %d", c.ScanIntervalSeconds)
+	}
+	if c.PopupCooldownSeconds < 0 {
+		return fmt.Errorf("popup_cooldown_seconds must not be negative, got %d", c.PopupCooldownSeconds)
+	}
+	if c.WarnMinutesBefore < 0 || c.WarnMinutesBefore > maxWarnMinutes {
+		return fmt.Errorf("warn_minutes_before must be between 0 and %d, got %d", maxWarnMinutes, c.WarnMinutesBefore)
+	}
+
+	if err := validateSchedule(c.ActiveDays, c.TimeWindows); err != nil {
+		return err
+	}
+	if err := validateDomains(c.BlockedDomains); err != nil {
+		return err
+	}
+
+	switch c.AI.Provider {
+	case "", "gemini", "openai", "ollama", "llamacpp", "template":
+	default:
+		return fmt.Errorf("unknown ai provider %q (use gemini, openai, ollama, llamacpp or template)", c.AI.Provider)
+	}
+
+	if _, err := utils.ParseLogLevel(c.Logging.Level); err != nil {
+		return err
+	}
+	switch c.Logging.Format {
+	case "", utils.LogFormatText, utils.LogFormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", c.Logging.Format)
+	}
+	if c.Logging.MaxSizeMB < 0 || c.Logging.MaxAgeDays < 0 || c.Logging.MaxBackups < 0 {
+		return fmt.Errorf("logging limits must not be negative")
+	}
+
+	if c.Metrics.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Address); err != nil {
+			return fmt.Errorf("invalid metrics address %q: %w", c.Metrics.Address, err)
+		}
+	}
+	if c.Dashboard.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Dashboard.Address); err != nil {
+			return fmt.Errorf("invalid dashboard address %q: %w", c.Dashboard.Address, err)
+		}
+	}
+
+	if err := c.validateCategories(); err != nil {
+		return err
+	}
+	if err := c.validateHooks(); err != nil {
+		return err
+	}
+	if err := c.Calendar.validate(); err != nil {
+		return err
+	}
+
+	return c.validateProfiles()
+}
+
+// validateSchedule checks day names and HH:MM window bounds
+func validateSchedule(days []string, windows []TimeWindow) error {
+	validDays := map[string]bool{"Mon": true, "Tue": true, "Wed": true, "Thu": true, "Fri": true, "Sat": true, "Sun": true}
+	for _, day := range days {
+		if !validDays[day] {
+			return fmt.Errorf("invalid active day %q (use Mon, Tue, ... Sun)", day)
+		}
+	}
+
+	for i, window := range windows {
+		start, err := time.Parse("15:04", window.Start)
+		if err != nil {
+			return fmt.Errorf("time window %d: invalid start %q (use HH:MM)", i+1, window.Start)
+		}
+		end, err := time.Parse("15:04", window.End)
+		if err != nil {
+			return fmt.Errorf("time window %d: invalid end %q (use HH:MM)", i+1, window.End)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("time window %d: end %s is before start %s (windows cannot span midnight)", i+1, window.End, window.Start)
+		}
+	}
+
+	return nil
+}
+
 // IsProductiveTime checks if current time is within productive hours
 func (c *Config) IsProductiveTime() bool {
+	return c.IsProductiveAt(time.Now())
+}
+
+// IsProductiveAt checks if the given time is within productive hours,
+// using the schedule of whichever profile is active at that time
+func (c *Config) IsProductiveAt(now time.Time) bool {
 	if !c.Enabled {
 		return false
 	}
 
-	now := time.Now()
-	
+	effective := c.Effective(now)
+	return inSchedule(effective.ActiveDays, effective.TimeWindows, now)
+}
+
+// WindowEndAt returns when the productive window containing now ends, using
+// the schedule of the profile active at that time. ok is false outside productive time.
+func (c *Config) WindowEndAt(now time.Time) (end time.Time, ok bool) {
+	if !c.Enabled {
+		return time.Time{}, false
+	}
+
+	effective := c.Effective(now)
+	return windowEnd(effective.ActiveDays, effective.TimeWindows, now)
+}
+
+// transitionHorizon is how far ahead NextTransitionAt looks; a weekly
+// schedule repeats within it
+const transitionHorizon = 8
+
+// NextTransitionAt returns the next time after now at which productive time
+// starts or ends, taking auto-switching profiles into account. ok is false
+// when the state never changes, e.g. blocking is disabled or no window is set.
+func (c *Config) NextTransitionAt(now time.Time) (next time.Time, ok bool) {
+	if !c.Enabled {
+		return time.Time{}, false
+	}
+
+	// The state can only change at midnight, at a window start or just
+	// after a window end, in any of the schedules
+	minutes := map[int]bool{0: true}
+	addWindows := func(windows []TimeWindow) {
+		for _, w := range windows {
+			if start, err := time.Parse("15:04", w.Start); err == nil {
+				minutes[start.Hour()*60+start.Minute()] = true
+			}
+			if end, err := time.Parse("15:04", w.End); err == nil {
+				minutes[end.Hour()*60+end.Minute()+1] = true
+			}
+		}
+	}
+	addWindows(c.TimeWindows)
+	for _, p := range c.Profiles {
+		addWindows(p.TimeWindows)
+	}
+
+	sorted := make([]int, 0, len(minutes))
+	for m := range minutes {
+		sorted = append(sorted, m)
+	}
+	sort.Ints(sorted)
+
+	productive := c.IsProductiveAt(now)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for day := 0; day < transitionHorizon; day++ {
+		for _, m := range sorted {
+			t := time.Date(midnight.Year(), midnight.Month(), midnight.Day()+day, 0, m, 0, 0, now.Location())
+			if !t.After(now) {
+				continue
+			}
+			if c.IsProductiveAt(t) != productive {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// inSchedule checks if now falls on one of the days and inside one of the windows
+func inSchedule(days []string, windows []TimeWindow, now time.Time) bool {
+	_, ok := windowEnd(days, windows, now)
+	return ok
+}
+
+// windowEnd returns the end of the window containing now, if now falls on
+// one of the days and inside one of the windows. Windows include their end
+// minute, so a window ending at 12:00 ends at 12:01. The end is a wall clock
+// time, so it stays right on days when daylight saving time changes.
+func windowEnd(days []string, windows []TimeWindow, now time.Time) (time.Time, bool) {
 	// Check if today is an active day
 	currentDay := now.Weekday().String()[:3] // Mon, Tue, etc.
 	dayActive := false
-	for _, day := range c.ActiveDays {
+	for _, day := range days {
 		if day == currentDay {
 			dayActive = true
 			break
 		}
 	}
-	
+
 	if !dayActive {
-		return false
+		return time.Time{}, false
 	}
 
 	// Check if current time is in any time window
 	currentHour := now.Hour()
 	currentMinute := now.Minute()
 	currentTimeInMinutes := currentHour*60 + currentMinute
-	
-	for _, window := range c.TimeWindows {
+
+	for _, window := range windows {
 		startTime, err := time.Parse("15:04", window.Start)
 		if err != nil {
 			continue
@@ -161,33 +441,43 @@ func (c *Config) IsProductiveTime() bool {
 		if err != nil {
 			continue
 		}
-		
+
 		startMinutes := startTime.Hour()*60 + startTime.Minute()
 		endMinutes := endTime.Hour()*60 + endTime.Minute()
-		
+
 		// Check if current time is within the window
 		if currentTimeInMinutes >= startMinutes && currentTimeInMinutes <= endMinutes {
-			return true
+			return time.Date(now.Year(), now.Month(), now.Day(), 0, endMinutes+1, 0, 0, now.Location()), true
 		}
 	}
 
-	return false
+	return time.Time{}, false
 }
 
 // ToggleEnabled toggles the enabled state
-func (c *Config) ToggleEnabled() error {
-	c.Enabled = !c.Enabled
-	return Save()
+func ToggleEnabled() error {
+	return Update(func(c *Config) {
+		c.Enabled = !c.Enabled
+	})
 }
 
 // SetAutostart sets autostart value
-func (c *Config) SetAutostart(value bool) error {
-	c.Autostart = value
-	return Save()
+func SetAutostart(value bool) error {
+	return Update(func(c *Config) {
+		c.Autostart = value
+	})
 }
 
 // MarkFirstRunCompleted marks the first run as completed
-func (c *Config) MarkFirstRunCompleted() error {
-	c.FirstRunCompleted = true
-	return Save()
+func MarkFirstRunCompleted() error {
+	return Update(func(c *Config) {
+		c.FirstRunCompleted = true
+	})
+}
+
+// SetGoal sets the session goal included in AI prompts. An empty goal clears it.
+func SetGoal(goal string) error {
+	return Update(func(c *Config) {
+		c.AI.Goal = goal
+	})
 }
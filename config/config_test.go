@@ -0,0 +1,155 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	_ "time/tzdata"
+)
+
+// day returns a time in March 2024 in UTC; 11 March is a Monday
+func day(d, hour, minute int) time.Time {
+	return time.Date(2024, 3, d, hour, minute, 0, 0, time.UTC)
+}
+
+var weekdays = []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+
+func TestNextTransitionAt(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inBerlin := func(month time.Month, d, hour, minute int) time.Time {
+		return time.Date(2024, month, d, hour, minute, 0, 0, berlin)
+	}
+
+	office := Config{Enabled: true, ActiveDays: weekdays, TimeWindows: []TimeWindow{{Start: "09:00", End: "12:00"}}}
+	lateShift := Config{Enabled: true, ActiveDays: weekdays, TimeWindows: []TimeWindow{{Start: "20:00", End: "23:59"}}}
+	overnight := Config{Enabled: true, ActiveDays: weekdays, TimeWindows: []TimeWindow{
+		{Start: "22:00", End: "23:59"}, {Start: "00:00", End: "02:00"},
+	}}
+	withProfiles := office.Clone()
+	withProfiles.Profiles = []Profile{
+		{Name: "Study", TimeWindows: []TimeWindow{{Start: "11:00", End: "14:00"}}, AutoSwitch: true},
+		{Name: "Evening", ActiveDays: []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+			TimeWindows: []TimeWindow{{Start: "19:00", End: "21:00"}}, AutoSwitch: true},
+	}
+	manual := withProfiles.Clone()
+	manual.ActiveProfile = "Evening"
+	sundays := Config{Enabled: true, ActiveDays: []string{"Sun"}, TimeWindows: []TimeWindow{{Start: "01:00", End: "04:00"}}}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		now  time.Time
+		want time.Time // Zero when the state never changes
+	}{
+		{"before window", office, day(11, 8, 0), day(11, 9, 0)},
+		{"at window start", office, day(11, 9, 0), day(11, 12, 1)},
+		{"end minute is inclusive", office, day(11, 12, 0), day(11, 12, 1)},
+		{"after window", office, day(11, 12, 1), day(12, 9, 0)},
+		{"friday skips the weekend", office, day(15, 13, 0), day(18, 9, 0)},
+		{"inactive day", office, day(16, 10, 0), day(18, 9, 0)},
+		{"window ending 23:59", lateShift, day(11, 21, 0), day(12, 0, 0)},
+		{"overnight continues past midnight", overnight, day(11, 23, 0), day(12, 2, 1)},
+		{"overnight before start", overnight, day(11, 3, 0), day(11, 22, 0)},
+		{"overnight into an inactive day", overnight, day(15, 23, 0), day(16, 0, 0)},
+		{"overnight on an inactive day", overnight, day(16, 1, 0), day(18, 0, 0)},
+		{"disabled", Config{ActiveDays: weekdays, TimeWindows: office.TimeWindows}, day(11, 8, 0), time.Time{}},
+		{"no windows", Config{Enabled: true, ActiveDays: weekdays}, day(11, 8, 0), time.Time{}},
+		{"no active days", Config{Enabled: true, TimeWindows: office.TimeWindows}, day(11, 8, 0), time.Time{}},
+		{"auto-switch profile extends the window", *withProfiles, day(11, 10, 0), day(11, 14, 1)},
+		{"auto-switch profile starts", *withProfiles, day(11, 15, 0), day(11, 19, 0)},
+		{"auto-switch profile ends", *withProfiles, day(11, 20, 0), day(11, 21, 1)},
+		{"auto-switch profile on its own days", *withProfiles, day(16, 10, 0), day(16, 19, 0)},
+		{"manual profile replaces the schedule", *manual, day(11, 10, 0), day(11, 19, 0)},
+		{"daylight saving starts", sundays, inBerlin(time.March, 31, 1, 30), inBerlin(time.March, 31, 4, 1)},
+		{"daylight saving ends", sundays, inBerlin(time.October, 27, 0, 30), inBerlin(time.October, 27, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := tt.cfg.NextTransitionAt(tt.now)
+			if ok != !tt.want.IsZero() || ok && !next.Equal(tt.want) {
+				t.Errorf("NextTransitionAt(%s) = %v, %v, want %v", tt.now.Format("Mon 15:04"), next, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowEndAt(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		window TimeWindow
+		now    time.Time
+		want   time.Time
+	}{
+		{"end minute is inclusive", TimeWindow{"09:00", "12:00"}, day(11, 12, 0), day(11, 12, 1)},
+		{"outside", TimeWindow{"09:00", "12:00"}, day(11, 12, 1), time.Time{}},
+		{"ends at midnight", TimeWindow{"20:00", "23:59"}, day(11, 23, 59), day(12, 0, 0)},
+		{"one-minute window", TimeWindow{"09:00", "09:00"}, day(11, 9, 0), day(11, 9, 1)},
+		// 01:30 CET to 04:01 CEST is two and a half hours, not three
+		{"daylight saving starts", TimeWindow{"01:00", "04:00"},
+			time.Date(2024, 3, 31, 1, 30, 0, 0, berlin), time.Date(2024, 3, 31, 4, 1, 0, 0, berlin)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Enabled: true, ActiveDays: []string{"Mon", "Sun"}, TimeWindows: []TimeWindow{tt.window}}
+			end, ok := cfg.WindowEndAt(tt.now)
+			if ok != !tt.want.IsZero() || ok && !end.Equal(tt.want) {
+				t.Errorf("WindowEndAt(%s) = %v, %v, want %v", tt.now.Format("Mon 15:04"), end, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockApp(t *testing.T) {
+	base := Config{Enabled: true, ActiveDays: weekdays, Blocklist: []string{"steam.exe"}, Profiles: []Profile{
+		{Name: "Study", Blocklist: []string{"discord.exe"}},
+		{Name: "Deep Work"},
+		{Name: "Evening", TimeWindows: []TimeWindow{{Start: "19:00", End: "21:00"}}, Blocklist: []string{}, AutoSwitch: true},
+	}}
+
+	tests := []struct {
+		name          string
+		activeProfile string
+		now           time.Time
+		app           string
+		wantProfile   string
+		wantBlocklist []string // Blocklist of wantProfile afterwards
+	}{
+		{"default profile", "", day(11, 10, 0), "game.exe", DefaultProfileName, []string{"steam.exe", "game.exe"}},
+		{"profile with its own blocklist", "study", day(11, 10, 0), "game.exe", "Study", []string{"discord.exe", "game.exe"}},
+		{"profile inheriting the blocklist", "Deep Work", day(11, 10, 0), "game.exe", DefaultProfileName, []string{"steam.exe", "game.exe"}},
+		{"auto-switch profile", "", day(11, 20, 0), "game.exe", "Evening", []string{"game.exe"}},
+		{"already blocked", "Study", day(11, 10, 0), "Discord.exe", "Study", []string{"discord.exe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base.Clone()
+			cfg.ActiveProfile = tt.activeProfile
+
+			if profile := cfg.BlockApp(tt.app, tt.now); profile != tt.wantProfile {
+				t.Errorf("BlockApp added to %q, want %q", profile, tt.wantProfile)
+			}
+			blocklist := cfg.Blocklist
+			if p := cfg.FindProfile(tt.wantProfile); p != nil {
+				blocklist = p.Blocklist
+			}
+			if !slices.Equal(blocklist, tt.wantBlocklist) {
+				t.Errorf("blocklist = %v, want %v", blocklist, tt.wantBlocklist)
+			}
+			if !slices.Equal(base.Blocklist, []string{"steam.exe"}) || !slices.Equal(base.Profiles[0].Blocklist, []string{"discord.exe"}) {
+				t.Fatal("BlockApp changed the config it was cloned from")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeDomain returns the host name of a blocked_domains entry such as
+// "https://www.YouTube.com/feed" or "*.reddit.com": lowercase, without
+// scheme, path, port or wildcard prefix. It returns "" unless the result is
+// a valid host name, since the name is written to the system hosts file.
+func NormalizeDomain(entry string) string {
+	host := strings.ToLower(strings.TrimSpace(entry))
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	host = strings.TrimPrefix(host, "*.")
+	host = strings.Trim(host, ".")
+
+	if !validHostname(host) {
+		return ""
+	}
+	return host
+}
+
+// validHostname reports whether host consists of dot-separated labels of 1
+// to 63 letters, digits and hyphens
+func validHostname(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if len(label) < 1 || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateDomains checks that every blocked_domains entry names a host
+func validateDomains(domains []string) error {
+	for _, entry := range domains {
+		if NormalizeDomain(entry) == "" {
+			return fmt.Errorf("blocked_domains: invalid domain %q", entry)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := map[string]string{
+		"youtube.com":                    "youtube.com",
+		"  YouTube.COM  ":                "youtube.com",
+		"https://www.youtube.com/watch":  "www.youtube.com",
+		"http://example.com:8080/?q=1":   "example.com",
+		"example.com#top":                "example.com",
+		"*.reddit.com":                   "reddit.com",
+		"reddit.com.":                    "reddit.com",
+		"ftp://files.example.org/pub/x/": "files.example.org",
+		"xn--bcher-kva.example":          "xn--bcher-kva.example",
+		"":                               "",
+		"not a domain":                   "",
+		"#youtube.com":                   "",
+		"a..b":                           "",
+		"under_score.com":                "",
+		"bücher.example":                 "",
+		"[::1]":                          "",
+		"x.com\n1.2.3.4 bank.example":    "",
+		"x.com\r\n1.2.3.4 bank.example":  "",
+		"x.com\r1.2.3.4 bank.example":    "",
+		"x.com\x00":                      "",
+		strings.Repeat("a", 64) + ".com": "",
+		strings.Repeat("a", 63) + ".com": strings.Repeat("a", 63) + ".com",
+	}
+
+	for entry, want := range tests {
+		if got := NormalizeDomain(entry); got != want {
+			t.Errorf("NormalizeDomain(%q) = %q, want %q", entry, got, want)
+		}
+	}
+}
+
+func TestValidateDomains(t *testing.T) {
+	valid := func() *Config {
+		cfg := defaultConfig()
+		cfg.BlockedDomains = []string{"youtube.com", "https://www.reddit.com/r/all"}
+		cfg.Profiles = []Profile{{Name: "Study", BlockedDomains: []string{"*.tiktok.com"}}}
+		return cfg
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid domains rejected: %v", err)
+	}
+
+	for _, entry := range []string{"x.com\n0.0.0.0 bank.example", "x.com\r\n:: bank.example", "# comment", "", "bad host"} {
+		cfg := valid()
+		cfg.BlockedDomains = append(cfg.BlockedDomains, entry)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("blocked_domains entry %q accepted", entry)
+		}
+
+		cfg = valid()
+		cfg.Profiles[0].BlockedDomains = append(cfg.Profiles[0].BlockedDomains, entry)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("profile blocked_domains entry %q accepted", entry)
+		}
+	}
+}
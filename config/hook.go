@@ -0,0 +1,137 @@
+package config
+
+import (
+	"appblock/events"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultHookTimeout bounds one attempt of a hook without timeout_seconds
+const DefaultHookTimeout = 10 * time.Second
+
+// Hook posts a webhook or runs a command when an event happens
+type Hook struct {
+	Name    string            `json:"name,omitempty"`    // Shown in the log
+	Events  []string          `json:"events"`            // Event names (see events.Names), or "*" for all
+	URL     string            `json:"url,omitempty"`     // POST the body here...
+	Headers map[string]string `json:"headers,omitempty"` // Extra request headers, e.g. Authorization
+	Body    string            `json:"body,omitempty"`    // Template of the JSON body (default: the event as JSON)
+	Command []string          `json:"command,omitempty"` // ...or run this program with the event in APPBLOCK_* variables
+
+	// BlockedLimit makes a process_blocked hook fire only once a day, when
+	// this many blocked attempts were reached today
+	BlockedLimit   int `json:"blocked_limit,omitempty"`
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"` // Per attempt (default 10)
+	Retries        int `json:"retries,omitempty"`         // Further attempts after a failure, with backoff
+}
+
+// Label returns the hook name, or what it calls if it has none
+func (h Hook) Label() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.URL != "":
+		if u, err := url.Parse(h.URL); err == nil {
+			return u.Host
+		}
+		return "webhook"
+	case len(h.Command) > 0:
+		return h.Command[0]
+	default:
+		return "hook"
+	}
+}
+
+// Timeout returns how long one attempt may take
+func (h Hook) Timeout() time.Duration {
+	if h.TimeoutSeconds <= 0 {
+		return DefaultHookTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// Matches reports whether the hook fires on the named event
+func (h Hook) Matches(event string) bool {
+	for _, e := range h.Events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseBody parses the body template, or returns nil without one. Fields of
+// the event are available by their JSON name, e.g. {{json .app}}; the json
+// function quotes a value for use inside JSON.
+func (h Hook) ParseBody() (*template.Template, error) {
+	if h.Body == "" {
+		return nil, nil
+	}
+	return template.New(h.Label()).Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(h.Body)
+}
+
+// clone returns a deep copy of the hook
+func (h Hook) clone() Hook {
+	h.Events = cloneSlice(h.Events)
+	h.Command = cloneSlice(h.Command)
+	if h.Headers != nil {
+		h.Headers = maps.Clone(h.Headers)
+	}
+	return h
+}
+
+// validateHooks checks that every hook has a target and known events
+func (c *Config) validateHooks() error {
+	for i, h := range c.Hooks {
+		name := fmt.Sprintf("hook %d (%s)", i+1, h.Label())
+
+		switch {
+		case h.URL != "" && len(h.Command) > 0:
+			return fmt.Errorf("%s: set either url or command, not both", name)
+		case h.URL != "":
+			u, err := url.Parse(h.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s: url must be an http or https URL", name)
+			}
+		case len(h.Command) > 0:
+			if strings.TrimSpace(h.Command[0]) == "" {
+				return fmt.Errorf("%s: command must start with a program", name)
+			}
+			if h.Body != "" || len(h.Headers) > 0 {
+				return fmt.Errorf("%s: body and headers only apply to url hooks", name)
+			}
+		default:
+			return fmt.Errorf("%s: set url or command", name)
+		}
+
+		if len(h.Events) == 0 {
+			return fmt.Errorf("%s: list the events it fires on (or \"*\")", name)
+		}
+		for _, e := range h.Events {
+			if e != "*" && !slices.Contains(events.Names(), e) {
+				return fmt.Errorf("%s: unknown event %q (use %s or *)", name, e, strings.Join(events.Names(), ", "))
+			}
+		}
+		if h.BlockedLimit < 0 || h.TimeoutSeconds < 0 || h.Retries < 0 {
+			return fmt.Errorf("%s: blocked_limit, timeout_seconds and retries must not be negative", name)
+		}
+		if h.Retries > 10 {
+			return fmt.Errorf("%s: at most 10 retries, got %d", name, h.Retries)
+		}
+		if _, err := h.ParseBody(); err != nil {
+			return fmt.Errorf("%s: invalid body template: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultProfileName is the display name for the top-level settings,
+// which act as the profile used when no other profile is active
+const DefaultProfileName = "Default"
+
+// Profile is a named set of blocking rules (e.g. "Work", "Study").
+// Empty fields inherit the top-level settings.
+type Profile struct {
+	Name                 string       `json:"name"`
+	ActiveDays           []string     `json:"active_days,omitempty"`
+	TimeWindows          []TimeWindow `json:"time_windows,omitempty"`
+	Blocklist            []string     `json:"blocklist,omitempty"`
+	BlockCategories      []string     `json:"block_categories,omitempty"`
+	BlockedDomains       []string     `json:"blocked_domains,omitempty"`
+	Personality          string       `json:"personality,omitempty"`
+	PopupCooldownSeconds int          `json:"popup_cooldown_seconds,omitempty"`
+
+	// AutoSwitch activates the profile automatically during its own
+	// time windows when no profile has been selected manually
+	AutoSwitch bool `json:"auto_switch,omitempty"`
+}
+
+// clone returns a deep copy of the profile
+func (p Profile) clone() Profile {
+	p.ActiveDays = cloneSlice(p.ActiveDays)
+	p.TimeWindows = cloneSlice(p.TimeWindows)
+	p.Blocklist = cloneSlice(p.Blocklist)
+	p.BlockCategories = cloneSlice(p.BlockCategories)
+	p.BlockedDomains = cloneSlice(p.BlockedDomains)
+	return p
+}
+
+// FindProfile returns the profile with the given name (case-insensitive), or nil
+func (c *Config) FindProfile(name string) *Profile {
+	for i := range c.Profiles {
+		if strings.EqualFold(c.Profiles[i].Name, name) {
+			return &c.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of all profiles, starting with the default profile
+func (c *Config) ProfileNames() []string {
+	names := []string{DefaultProfileName}
+	for _, p := range c.Profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// ProfileAt returns the profile in effect at the given time, or nil for the default profile.
+// A manually selected profile always wins; otherwise the first auto-switch
+// profile whose schedule covers now is used.
+func (c *Config) ProfileAt(now time.Time) *Profile {
+	if c.ActiveProfile != "" {
+		return c.FindProfile(c.ActiveProfile)
+	}
+
+	for i := range c.Profiles {
+		p := &c.Profiles[i]
+		if !p.AutoSwitch {
+			continue
+		}
+
+		days := p.ActiveDays
+		if days == nil {
+			days = c.ActiveDays
+		}
+		if inSchedule(days, p.TimeWindows, now) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// ProfileNameAt returns the display name of the profile in effect at the given time
+func (c *Config) ProfileNameAt(now time.Time) string {
+	if p := c.ProfileAt(now); p != nil {
+		return p.Name
+	}
+	return DefaultProfileName
+}
+
+// Effective returns a copy of the config with the settings of the profile
+// in effect at the given time merged over the top-level settings
+func (c *Config) Effective(now time.Time) *Config {
+	effective := c.Clone()
+
+	p := c.ProfileAt(now)
+	if p == nil {
+		return effective
+	}
+
+	if p.ActiveDays != nil {
+		effective.ActiveDays = cloneSlice(p.ActiveDays)
+	}
+	if p.TimeWindows != nil {
+		effective.TimeWindows = cloneSlice(p.TimeWindows)
+	}
+	if p.Blocklist != nil {
+		effective.Blocklist = cloneSlice(p.Blocklist)
+	}
+	if p.BlockCategories != nil {
+		effective.BlockCategories = cloneSlice(p.BlockCategories)
+	}
+	if p.BlockedDomains != nil {
+		effective.BlockedDomains = cloneSlice(p.BlockedDomains)
+	}
+	if p.Personality != "" {
+		effective.AI.Personality = p.Personality
+	}
+	if p.PopupCooldownSeconds > 0 {
+		effective.PopupCooldownSeconds = p.PopupCooldownSeconds
+	}
+
+	return effective
+}
+
+// BlockApp adds app to the blocklist in effect at now: that of the profile
+// in effect if it has its own, otherwise the top-level one. It returns the
+// name of the profile whose blocklist holds app, which may already have.
+func (c *Config) BlockApp(app string, now time.Time) string {
+	blocklist, profile := &c.Blocklist, DefaultProfileName
+	if p := c.ProfileAt(now); p != nil && p.Blocklist != nil {
+		blocklist, profile = &p.Blocklist, p.Name
+	}
+
+	for _, blocked := range *blocklist {
+		if strings.EqualFold(blocked, app) {
+			return profile
+		}
+	}
+	*blocklist = append(*blocklist, app)
+	return profile
+}
+
+// validateProfiles checks profile names and schedules
+func (c *Config) validateProfiles() error {
+	seen := make(map[string]bool)
+	for _, p := range c.Profiles {
+		name := strings.ToLower(strings.TrimSpace(p.Name))
+		if name == "" {
+			return fmt.Errorf("profile name must not be empty")
+		}
+		if name == strings.ToLower(DefaultProfileName) {
+			return fmt.Errorf("profile name %q is reserved", DefaultProfileName)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate profile name %q", p.Name)
+		}
+		seen[name] = true
+
+		if p.PopupCooldownSeconds < 0 {
+			return fmt.Errorf("profile %q: popup_cooldown_seconds must not be negative", p.Name)
+		}
+		if err := validateSchedule(p.ActiveDays, p.TimeWindows); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		if err := validateDomains(p.BlockedDomains); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		if p.AutoSwitch && len(p.TimeWindows) == 0 {
+			return fmt.Errorf("profile %q: auto_switch requires time_windows", p.Name)
+		}
+	}
+
+	if c.ActiveProfile != "" && c.FindProfile(c.ActiveProfile) == nil {
+		return fmt.Errorf("active_profile %q does not exist", c.ActiveProfile)
+	}
+
+	return nil
+}
+
+// SetActiveProfile selects a profile manually. Passing "" or DefaultProfileName
+// returns to the default profile with schedule-based switching.
+func SetActiveProfile(name string) error {
+	cfg := current()
+	if cfg == nil {
+		return fmt.Errorf("config not initialized")
+	}
+
+	if strings.EqualFold(name, DefaultProfileName) {
+		name = ""
+	}
+	if name != "" {
+		p := cfg.FindProfile(name)
+		if p == nil {
+			return fmt.Errorf("profile %q does not exist", name)
+		}
+		name = p.Name
+	}
+
+	return Update(func(c *Config) {
+		c.ActiveProfile = name
+	})
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalid is wrapped by the error Update returns for a change that
+// fails validation
+var ErrInvalid = errors.New("invalid config")
+
+// The current configuration is an immutable snapshot: it is never modified
+// after being stored. Changes go through Update, which stores a new snapshot
+// and hands every subscriber its own copy.
+var (
+	mu          sync.RWMutex
+	instance    *Config
+	subscribers []func(*Config)
+	publishMu   sync.Mutex
+)
+
+// Get returns a copy of the current configuration.
+// Modifying the returned value has no effect; use Update to change settings.
+func Get() *Config {
+	cfg := current()
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Clone()
+}
+
+// Update applies mutate to a copy of the current configuration, validates,
+// saves it and publishes the result to all subscribers. A change that fails
+// validation is neither saved nor published; its error wraps ErrInvalid.
+func Update(mutate func(*Config)) error {
+	mu.Lock()
+	if instance == nil {
+		mu.Unlock()
+		return fmt.Errorf("config not initialized")
+	}
+
+	cfg := instance.Clone()
+	mutate(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		mu.Unlock()
+		return fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if err := save(cfg); err != nil {
+		mu.Unlock()
+		return err
+	}
+	instance = cfg
+	mu.Unlock()
+
+	publish()
+	return nil
+}
+
+// Subscribe registers fn to receive a copy of the configuration every time it changes
+func Subscribe(fn func(*Config)) {
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// current returns the stored snapshot without copying. Callers must not modify it.
+func current() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return instance
+}
+
+// apply stores cfg as the current snapshot and notifies subscribers
+func apply(cfg *Config) {
+	mu.Lock()
+	instance = cfg
+	mu.Unlock()
+
+	publish()
+}
+
+// publish sends the latest snapshot to every subscriber.
+// It runs outside mu so subscribers may call Get, and it always reads the
+// latest snapshot so overlapping updates cannot deliver stale config last.
+func publish() {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	mu.RLock()
+	cfg := instance
+	subs := make([]func(*Config), len(subscribers))
+	copy(subs, subscribers)
+	mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(cfg.Clone())
+	}
+}
+
+// Clone returns a deep copy of the configuration
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.ActiveDays = cloneSlice(c.ActiveDays)
+	clone.TimeWindows = cloneSlice(c.TimeWindows)
+	clone.Blocklist = cloneSlice(c.Blocklist)
+	clone.BlockCategories = cloneSlice(c.BlockCategories)
+	clone.Categories = cloneSlice(c.Categories)
+	for i := range clone.Categories {
+		clone.Categories[i] = clone.Categories[i].clone()
+	}
+	clone.BlockedDomains = cloneSlice(c.BlockedDomains)
+	clone.Profiles = cloneSlice(c.Profiles)
+	for i := range clone.Profiles {
+		clone.Profiles[i] = clone.Profiles[i].clone()
+	}
+	clone.Calendar.Match = cloneSlice(c.Calendar.Match)
+	clone.Hooks = cloneSlice(c.Hooks)
+	for i := range clone.Hooks {
+		clone.Hooks[i] = clone.Hooks[i].clone()
+	}
+	return &clone
+}
+
+// cloneSlice copies a slice, keeping nil and empty distinct so snapshots
+// serialize exactly like the original
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
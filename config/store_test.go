@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useConfigFile points the package at config.json in a temporary directory
+// and clears the stored snapshot and subscribers
+func useConfigFile(t *testing.T) string {
+	t.Helper()
+	reset := func() {
+		mu.Lock()
+		instance, subscribers = nil, nil
+		mu.Unlock()
+	}
+	reset()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Cleanup(func() {
+		reset()
+		configPath = ""
+	})
+	return configPath
+}
+
+func TestLoadValidates(t *testing.T) {
+	path := useConfigFile(t)
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("default config was not written: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"enabled": true, "active_days": ["Someday"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(); err == nil {
+		t.Fatal("Load accepted an invalid active day")
+	}
+	if days := Get().ActiveDays; len(days) == 0 || days[0] == "Someday" {
+		t.Errorf("invalid config was applied: active days %v", days)
+	}
+}
+
+func TestUpdateValidates(t *testing.T) {
+	path := useConfigFile(t)
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := 0
+	Subscribe(func(*Config) { published++ })
+
+	err = Update(func(c *Config) { c.ActiveDays = []string{"Someday"} })
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Update of an invalid change: err = %v, want ErrInvalid", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("invalid config was saved")
+	}
+	if days := Get().ActiveDays; len(days) == 0 || days[0] == "Someday" {
+		t.Errorf("invalid config was applied: active days %v", days)
+	}
+	if published != 0 {
+		t.Error("invalid config was published")
+	}
+}
+
+func TestStoreConcurrentAccess(t *testing.T) {
+	useConfigFile(t)
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	var seenMu sync.Mutex
+	seen := 0
+	Subscribe(func(cfg *Config) {
+		// Each subscriber gets its own copy it may modify
+		cfg.Blocklist = append(cfg.Blocklist, "subscriber.exe")
+		seenMu.Lock()
+		seen++
+		seenMu.Unlock()
+	})
+
+	const workers = 8
+	const rounds = 25
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				if err := Update(func(c *Config) {
+					c.Enabled = !c.Enabled
+					c.Blocklist = append(c.Blocklist, "app.exe")
+				}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				cfg := Get()
+				_ = cfg.IsProductiveAt(time.Now())
+				cfg.Blocklist = append(cfg.Blocklist[:0], "mutated.exe")
+				cfg.TimeWindows = nil
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			Subscribe(func(cfg *Config) { _ = len(cfg.Blocklist) })
+		}()
+	}
+	wg.Wait()
+
+	cfg := Get()
+	if want := 3 + workers*rounds; len(cfg.Blocklist) != want {
+		t.Errorf("blocklist has %d entries, want %d: an update was lost or a copy leaked", len(cfg.Blocklist), want)
+	}
+	for _, app := range cfg.Blocklist {
+		if app == "mutated.exe" || app == "subscriber.exe" {
+			t.Fatalf("a copy handed out by Get or Subscribe changed the stored config: %v", cfg.Blocklist)
+		}
+	}
+	seenMu.Lock()
+	defer seenMu.Unlock()
+	if seen < workers*rounds {
+		t.Errorf("subscriber saw %d updates, want at least %d", seen, workers*rounds)
+	}
+}
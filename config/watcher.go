@@ -0,0 +1,131 @@
+package config
+
+import (
+	"appblock/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// debounceDelay groups the burst of events editors and atomic saves produce into one reload
+const debounceDelay = 500 * time.Millisecond
+
+// Watcher reloads the config file automatically when it changes on disk
+type Watcher struct {
+	watcher  *fsnotify.Watcher
+	stopChan chan bool
+}
+
+// NewWatcher creates a watcher for the config file.
+// Valid changes are published to subscribers registered with Subscribe.
+func NewWatcher() (*Watcher, error) {
+	if configPath == "" {
+		return nil, fmt.Errorf("config not initialized")
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	// Watch the directory rather than the file: atomic saves replace the file,
+	// which would silently drop a watch on the old inode
+	if err := fsWatcher.Add(filepath.Dir(configPath)); err != nil {
+		fsWatcher.Close()
+		return nil, fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	return &Watcher{
+		watcher:  fsWatcher,
+		stopChan: make(chan bool),
+	}, nil
+}
+
+// Start starts the watcher loop
+func (w *Watcher) Start() {
+	utils.LogInfo("Config watcher started for %s", configPath)
+
+	go func() {
+		var debounce <-chan time.Time
+
+		for {
+			select {
+			case event, ok := <-w.watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(configPath) {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				debounce = time.After(debounceDelay)
+			case <-debounce:
+				debounce = nil
+				w.reload()
+			case err, ok := <-w.watcher.Errors:
+				if !ok {
+					return
+				}
+				utils.LogWarning("Config watcher error: %v", err)
+			case <-w.stopChan:
+				w.watcher.Close()
+				utils.LogInfo("Config watcher stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the watcher
+func (w *Watcher) Stop() {
+	w.stopChan <- true
+}
+
+// reload reads and validates the config file, then applies it.
+// An invalid file is logged and ignored so the running config stays in effect.
+func (w *Watcher) reload() {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		// The file may be briefly missing while an editor replaces it
+		if !os.IsNotExist(err) {
+			utils.LogWarning("Config watcher failed to read config: %v", err)
+		}
+		return
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		utils.LogWarning("Ignoring config change - failed to parse: %v", err)
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		utils.LogWarning("Ignoring config change - invalid config: %v", err)
+		return
+	}
+
+	// Our own saves trigger the watcher too; skip when nothing actually changed
+	if sameConfig(cfg, current()) {
+		return
+	}
+
+	utils.LogInfo("Config file changed - applying new configuration")
+	apply(cfg)
+}
+
+// sameConfig reports whether two configs serialize identically
+func sameConfig(a, b *Config) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"appblock/config"
+	"appblock/paths"
+	"appblock/utils"
+	"appblock/web"
+	"sync"
+)
+
+// dashboardEndpoint runs the web dashboard while it is enabled
+type dashboardEndpoint struct {
+	server   *web.Server
+	addr     string // Configured address of server, which may differ from the one it resolved to
+	headless bool   // Always serve the dashboard, as there is no other interface
+	schedule web.Schedule
+	onURL    func(url string)
+	mu       sync.Mutex
+}
+
+// apply starts, stops or moves the server to match the settings
+func (d *dashboardEndpoint) apply(cfg config.DashboardConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	enabled := cfg.Enabled || d.headless
+	if d.server != nil {
+		if enabled && d.addr == cfg.Addr() {
+			return
+		}
+		d.closeLocked()
+	}
+	if !enabled {
+		return
+	}
+
+	token, err := web.LoadToken(paths.ConfigDir())
+	if err != nil {
+		utils.LogError("Failed to start dashboard: %v", err)
+		return
+	}
+	server, err := web.Listen(cfg.Addr(), token, d.schedule)
+	if err != nil {
+		utils.LogError("Failed to start dashboard on %s: %v", cfg.Addr(), err)
+		return
+	}
+	d.server = server
+	d.addr = cfg.Addr()
+	utils.LogInfo("Dashboard available at http://%s/ (run \"appblock dashboard\" for the link)", server.Addr())
+	if d.onURL != nil {
+		d.onURL(server.URL())
+	}
+}
+
+// stop stops the server if it is running
+func (d *dashboardEndpoint) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.server != nil {
+		d.closeLocked()
+	}
+}
+
+// closeLocked stops the server. Callers must hold mu.
+func (d *dashboardEndpoint) closeLocked() {
+	if err := d.server.Close(); err != nil {
+		utils.LogWarning("Failed to stop dashboard: %v", err)
+	}
+	d.server = nil
+	if d.onURL != nil {
+		d.onURL("")
+	}
+}
@@ -0,0 +1,186 @@
+// Package events is an in-process event bus for scheduler and blocker state
+// changes. Components publish typed events and any number of subscribers
+// (tray, hooks, dashboards) receive them without being wired to each other.
+package events
+
+import (
+	"appblock/utils"
+	"sync"
+)
+
+// queueSize is how many events a subscriber may fall behind before new
+// events are dropped for it, unless it subscribed with SubscribeReliable
+const queueSize = 64
+
+// Event is implemented by every event type
+type Event interface {
+	// Name returns the event name in snake_case, e.g. "process_blocked"
+	Name() string
+}
+
+// Bus delivers published events to its subscribers. Each subscriber runs in
+// its own goroutine and receives events in publishing order, so a slow
+// subscriber never blocks the publisher or the other subscribers.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[int]*subscriber
+	next int
+}
+
+// subscriber is the queue of events not yet delivered to fn
+type subscriber struct {
+	fn       func(Event)
+	reliable bool          // Queue without limit instead of dropping events
+	wake     chan struct{} // Signals that queue has events or closed is set
+	mu       sync.Mutex
+	queue    []Event
+	closed   bool
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscriber)}
+}
+
+// Subscribe calls fn for every event published from now on, until the
+// returned function is called. Events are dropped for fn while it is
+// queueSize events behind.
+func (b *Bus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	return b.subscribe(fn, false)
+}
+
+// SubscribeReliable is Subscribe for subscribers that keep state from the
+// events, e.g. a history: no event is dropped however far fn falls behind
+func (b *Bus) SubscribeReliable(fn func(Event)) (unsubscribe func()) {
+	return b.subscribe(fn, true)
+}
+
+// subscribe adds a subscriber and starts its delivery goroutine
+func (b *Bus) subscribe(fn func(Event), reliable bool) (unsubscribe func()) {
+	s := &subscriber{fn: fn, reliable: reliable, wake: make(chan struct{}, 1)}
+
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = s
+	b.mu.Unlock()
+
+	go s.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			s.close()
+		})
+	}
+}
+
+// Publish sends e to all subscribers without waiting for them
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, s := range b.subs {
+		s.push(e)
+	}
+}
+
+// push queues e for delivery, dropping it if the subscriber is too far behind
+func (s *subscriber) push(e Event) {
+	s.mu.Lock()
+	if !s.reliable && len(s.queue) >= queueSize {
+		s.mu.Unlock()
+		utils.LogWarning("Event subscriber is falling behind, dropped %s event", e.Name())
+		return
+	}
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	s.signal()
+}
+
+// close stops delivery once the events already queued are delivered
+func (s *subscriber) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.signal()
+}
+
+// signal wakes the delivery goroutine without blocking
+func (s *subscriber) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events in order until the subscriber is closed
+func (s *subscriber) run() {
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 {
+			if s.closed {
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Unlock()
+			<-s.wake
+			s.mu.Lock()
+		}
+		e := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		s.fn(e)
+	}
+}
+
+// OnBus subscribes fn to the events of type T on b
+func OnBus[T Event](b *Bus, fn func(T)) (unsubscribe func()) {
+	return b.Subscribe(func(e Event) {
+		if t, ok := e.(T); ok {
+			fn(t)
+		}
+	})
+}
+
+// OnBusReliable subscribes fn to the events of type T on b without ever
+// dropping one, see Bus.SubscribeReliable
+func OnBusReliable[T Event](b *Bus, fn func(T)) (unsubscribe func()) {
+	return b.SubscribeReliable(func(e Event) {
+		if t, ok := e.(T); ok {
+			fn(t)
+		}
+	})
+}
+
+// defaultBus is the application-wide bus
+var defaultBus = NewBus()
+
+// Publish sends e to the subscribers of the application-wide bus
+func Publish(e Event) {
+	defaultBus.Publish(e)
+}
+
+// Subscribe calls fn for every event on the application-wide bus
+func Subscribe(fn func(Event)) (unsubscribe func()) {
+	return defaultBus.Subscribe(fn)
+}
+
+// On subscribes fn to the events of type T on the application-wide bus,
+// e.g. events.On(func(e events.ProcessBlocked) { ... })
+func On[T Event](fn func(T)) (unsubscribe func()) {
+	return OnBus(defaultBus, fn)
+}
+
+// OnReliable subscribes fn to the events of type T on the application-wide
+// bus without ever dropping one, see Bus.SubscribeReliable
+func OnReliable[T Event](fn func(T)) (unsubscribe func()) {
+	return OnBusReliable(defaultBus, fn)
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+// receive waits for the next value on ch
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		panic("unreachable")
+	}
+}
+
+func TestSubscribersReceiveInOrder(t *testing.T) {
+	bus := NewBus()
+	first := make(chan Event, 10)
+	second := make(chan Event, 10)
+	defer bus.Subscribe(func(e Event) { first <- e })()
+	defer bus.Subscribe(func(e Event) { second <- e })()
+
+	bus.Publish(ProductiveStarted{Profile: "Study"})
+	bus.Publish(ProcessBlocked{App: "steam.exe", PID: 42})
+
+	for _, ch := range []chan Event{first, second} {
+		if e := receive(t, ch); e.Name() != "productive_started" {
+			t.Errorf("first event = %s", e.Name())
+		}
+		if e, ok := receive(t, ch).(ProcessBlocked); !ok || e.App != "steam.exe" || e.PID != 42 {
+			t.Errorf("second event = %#v", e)
+		}
+	}
+}
+
+func TestOnFiltersByType(t *testing.T) {
+	bus := NewBus()
+	blocked := make(chan ProcessBlocked, 10)
+	defer OnBus(bus, func(e ProcessBlocked) { blocked <- e })()
+
+	bus.Publish(ConfigReloaded{})
+	bus.Publish(KillFailed{App: "game.exe"})
+	bus.Publish(ProcessBlocked{App: "discord.exe"})
+
+	if e := receive(t, blocked); e.App != "discord.exe" {
+		t.Errorf("got %#v", e)
+	}
+	select {
+	case e := <-blocked:
+		t.Errorf("unexpected event %#v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	got := make(chan Event, 10)
+	unsubscribe := bus.Subscribe(func(e Event) { got <- e })
+
+	bus.Publish(ConfigReloaded{})
+	receive(t, got)
+
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+	bus.Publish(ConfigReloaded{})
+	select {
+	case e := <-got:
+		t.Errorf("event after unsubscribe: %#v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	defer close(release)
+	defer bus.Subscribe(func(Event) { <-release })()
+
+	fast := make(chan Event, 2*queueSize)
+	defer bus.Subscribe(func(e Event) { fast <- e })()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*queueSize; i++ {
+			bus.Publish(ProcessBlocked{PID: int32(i)})
+		}
+		close(done)
+	}()
+	receive(t, done)
+
+	// The other subscriber still gets at least a full queue
+	for i := 0; i < queueSize; i++ {
+		if e := receive(t, fast).(ProcessBlocked); e.PID != int32(i) {
+			t.Fatalf("event %d has pid %d", i, e.PID)
+		}
+	}
+}
+
+func TestReliableSubscriberGetsEveryEvent(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	got := make(chan ProcessBlocked, 10*queueSize)
+	defer OnBusReliable(bus, func(e ProcessBlocked) {
+		<-release
+		got <- e
+	})()
+
+	// Flood the bus far past the queue while the subscriber is stuck
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10*queueSize; i++ {
+			bus.Publish(ProcessBlocked{PID: int32(i)})
+		}
+		close(done)
+	}()
+	receive(t, done)
+	close(release)
+
+	for i := 0; i < 10*queueSize; i++ {
+		if e := receive(t, got); e.PID != int32(i) {
+			t.Fatalf("event %d has pid %d", i, e.PID)
+		}
+	}
+}
+
+func TestUnsubscribeDeliversQueuedEvents(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	got := make(chan Event, 3)
+	unsubscribe := bus.SubscribeReliable(func(e Event) {
+		<-release
+		got <- e
+	})
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(ProcessBlocked{PID: int32(i)})
+	}
+	unsubscribe()
+	bus.Publish(ConfigReloaded{})
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		if e := receive(t, got).(ProcessBlocked); e.PID != int32(i) {
+			t.Fatalf("event %d has pid %d", i, e.PID)
+		}
+	}
+	select {
+	case e := <-got:
+		t.Errorf("event after unsubscribe: %#v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestNames(t *testing.T) {
+	all := []Event{
+		ProductiveStarted{}, ProductiveEnded{}, ProductiveUpcoming{}, ProfileChanged{},
+		ProcessBlocked{}, KillFailed{}, ConfigReloaded{}, OverrideGranted{},
+	}
+	names := Names()
+	if len(names) != len(all) {
+		t.Fatalf("Names() has %d entries, want %d", len(names), len(all))
+	}
+	for i, e := range all {
+		if names[i] != e.Name() {
+			t.Errorf("Names()[%d] = %s, want %s", i, names[i], e.Name())
+		}
+	}
+}
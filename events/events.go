@@ -0,0 +1,76 @@
+package events
+
+import "time"
+
+// ProductiveStarted is published when productive time begins and blocking
+// becomes active
+type ProductiveStarted struct {
+	Time    time.Time `json:"time"`
+	Profile string    `json:"profile"` // Profile in effect
+}
+
+// ProductiveEnded is published when productive time ends or blocking is
+// disabled during it
+type ProductiveEnded struct {
+	Time    time.Time `json:"time"`
+	Profile string    `json:"profile"` // Profile in effect
+}
+
+// ProductiveUpcoming is published warn_minutes_before productive time starts
+type ProductiveUpcoming struct {
+	Time  time.Time `json:"time"`
+	Start time.Time `json:"start"`
+}
+
+// ProfileChanged is published when another profile takes effect, manually
+// or by schedule
+type ProfileChanged struct {
+	Time    time.Time `json:"time"`
+	Profile string    `json:"profile"`
+}
+
+// ProcessBlocked is published when a blocked app was closed
+type ProcessBlocked struct {
+	Time time.Time `json:"time"`
+	App  string    `json:"app"`
+	PID  int32     `json:"pid"`
+}
+
+// KillFailed is published when a blocked app could not be closed
+type KillFailed struct {
+	Time  time.Time `json:"time"`
+	App   string    `json:"app"`
+	PID   int32     `json:"pid"`
+	Error string    `json:"error"`
+}
+
+// ConfigReloaded is published after a new configuration was applied to all
+// components
+type ConfigReloaded struct {
+	Time time.Time `json:"time"`
+}
+
+// OverrideGranted is published when blocking is lifted during productive
+// time, e.g. by disabling it from the tray or CLI
+type OverrideGranted struct {
+	Time   time.Time `json:"time"`
+	Until  time.Time `json:"until"` // When productive time would have ended
+	Reason string    `json:"reason"`
+}
+
+// Names lists the names of all event types
+func Names() []string {
+	return []string{
+		"productive_started", "productive_ended", "productive_upcoming", "profile_changed",
+		"process_blocked", "kill_failed", "config_reloaded", "override_granted",
+	}
+}
+
+func (ProductiveStarted) Name() string  { return "productive_started" }
+func (ProductiveEnded) Name() string    { return "productive_ended" }
+func (ProductiveUpcoming) Name() string { return "productive_upcoming" }
+func (ProfileChanged) Name() string     { return "profile_changed" }
+func (ProcessBlocked) Name() string     { return "process_blocked" }
+func (KillFailed) Name() string         { return "kill_failed" }
+func (ConfigReloaded) Name() string     { return "config_reloaded" }
+func (OverrideGranted) Name() string    { return "override_granted" }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"appblock/classifier"
+	"appblock/config"
+	"sync"
+	"time"
+)
+
+// frontend is the user interface around the blocker: the system tray on
+// Windows, or nothing when running headless with only the web dashboard
+type frontend interface {
+	UpdateProductiveStatus(isProductive bool)
+	UpdateActiveProfile(name string)
+	UpdateAIStatus(status string)
+	UpdateConfig(cfg *config.Config)
+	SetClassifier(c *classifier.Classifier)
+	UpdateSuggestions()
+	SetDashboardURL(url string)
+	SetNextTransitionFunc(next func() (time.Time, bool))
+	SetQuitCallback(onQuit func())
+	SetOpenSettingsOnReady(open bool)
+	Start() // Blocks until Quit
+	Quit()
+}
+
+// headlessApp is the frontend without a user interface. Status is seen
+// through the web dashboard and the log.
+type headlessApp struct {
+	done chan struct{}
+	once sync.Once
+}
+
+func newHeadlessApp() *headlessApp {
+	return &headlessApp{done: make(chan struct{})}
+}
+
+func (h *headlessApp) UpdateProductiveStatus(bool)                    {}
+func (h *headlessApp) UpdateActiveProfile(string)                     {}
+func (h *headlessApp) UpdateAIStatus(string)                          {}
+func (h *headlessApp) UpdateConfig(*config.Config)                    {}
+func (h *headlessApp) SetClassifier(*classifier.Classifier)           {}
+func (h *headlessApp) UpdateSuggestions()                             {}
+func (h *headlessApp) SetDashboardURL(string)                         {}
+func (h *headlessApp) SetNextTransitionFunc(func() (time.Time, bool)) {}
+func (h *headlessApp) SetQuitCallback(func())                         {}
+func (h *headlessApp) SetOpenSettingsOnReady(bool)                    {}
+
+// Start waits until Quit is called
+func (h *headlessApp) Start() {
+	<-h.done
+}
+
+// Quit makes Start return
+func (h *headlessApp) Quit() {
+	h.once.Do(func() { close(h.done) })
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "appblock/config"
+
+// trayAvailable reports whether the system tray frontend can be used
+const trayAvailable = false
+
+// newFrontend returns the headless frontend; the tray and settings window
+// are Windows-only, so the web dashboard is the interface elsewhere
+func newFrontend(cfg *config.Config, headless bool) frontend {
+	return newHeadlessApp()
+}
@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"appblock/config"
+	"appblock/gui"
+	"appblock/tray"
+)
+
+// trayAvailable reports whether the system tray frontend can be used
+const trayAvailable = true
+
+// newFrontend returns the system tray app, or the headless frontend when
+// started with --headless
+func newFrontend(cfg *config.Config, headless bool) frontend {
+	if headless {
+		return newHeadlessApp()
+	}
+
+	gui.SetKeyTester(func() error {
+		return testAPIKey(config.Get().AI)
+	})
+	return tray.NewApp(cfg)
+}
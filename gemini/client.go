@@ -1,31 +1,35 @@
 package gemini
 
 import (
+	"appblock/ai"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	geminiAPIEndpoint = "https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s"
+	// DefaultBaseURL is the public Gemini API; override with SetBaseURL
+	DefaultBaseURL    = "https://generativelanguage.googleapis.com/v1beta"
+	geminiAPIEndpoint = "%s/models/%s:generateContent"
 	defaultTimeout    = 8 * time.Second
 )
 
 // Client handles Gemini API interactions
 type Client struct {
-	apiKey      string
+	baseURL     string
 	model       string
 	personality string
 	httpClient  *http.Client
 	lastMessage string
+	retry       *ai.RetryingCompleter
 	mu          sync.RWMutex
 }
 
@@ -54,17 +58,18 @@ type Candidate struct {
 // NewClient creates a new Gemini API client
 // If API key not found, returns client with default message mode
 func NewClient(model, personality string) (*Client, error) {
-	apiKey := getAPIKey()
+	apiKey := getAPIKey(context.Background())
 	
 	client := &Client{
-		apiKey:      apiKey,
+		baseURL:     DefaultBaseURL,
 		model:       model,
 		personality: personality,
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
-		lastMessage: getDefaultMessage(personality),
+		lastMessage: ai.DefaultMessage(personality, ""),
 	}
+	client.retry = ai.NewRetryingCompleter(ai.CompleterFunc(client.generate), ai.DefaultRetryPolicy)
 	
 	// If no API key, warn but continue with default messages
 	if apiKey == "" {
@@ -74,60 +79,56 @@ func NewClient(model, personality string) (*Client, error) {
 	return client, nil
 }
 
-// getAPIKey tries to get API key from multiple sources
-func getAPIKey() string {
-	// 1. Try environment variable first
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey != "" {
-		return apiKey
+// SetBaseURL points the client at a different API root, such as a proxy or
+// a fake server in tests. An empty URL restores the public API.
+func (c *Client) SetBaseURL(baseURL string) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
 	}
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
 
-	// 2. Try .env file in executable directory
-	exePath, err := os.Executable()
-	if err != nil {
-		return ""
-	}
-	exeDir := filepath.Dir(exePath)
-	envPath := filepath.Join(exeDir, ".env")
+// Breaker returns the circuit breaker guarding the API, implementing ai.BreakerReporter
+func (c *Client) Breaker() *ai.CircuitBreaker {
+	return c.retry.Breaker()
+}
 
-	data, err := os.ReadFile(envPath)
-	if err != nil {
-		return ""
+// getAPIKey looks up the API key on every request, so a key set or deleted
+// with "appblock key" applies without a restart. A ctx from ai.WithAPIKeys
+// looks it up only once for the request and its retries.
+func getAPIKey(ctx context.Context) string {
+	return ai.APIKey(ctx, "GEMINI_API_KEY")
+}
+
+// GetMotivationalMessage gets a motivational message from Gemini AI
+// Falls back to default message if API not available.
+// An empty personality uses the one the client was created with.
+func (c *Client) GetMotivationalMessage(blockedApp, personality string) string {
+	if personality == "" {
+		personality = c.personality
 	}
 
-	// Parse .env file (simple format: GEMINI_API_KEY=your-key-here)
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "GEMINI_API_KEY=") {
-			key := strings.TrimPrefix(line, "GEMINI_API_KEY=")
-			key = strings.Trim(key, "\"'") // Remove quotes if present
-			return strings.TrimSpace(key)
-		}
+	ctx, cancel := context.WithTimeout(ai.WithAPIKeys(context.Background()), defaultTimeout)
+	defer cancel()
+
+	// If no API key, return default message
+	if getAPIKey(ctx) == "" {
+		return ai.DefaultMessage(personality, blockedApp)
 	}
 
-	return ""
-}
+	// Try to get message from API (without holding the lock, so concurrent
+	// blocks do not queue up behind each other)
+	message, err := c.Complete(ctx, ai.BuildPrompt(personality, blockedApp, time.Now()))
 
-// GetMotivationalMessage gets a motivational message from Gemini AI
-// Falls back to default message if API not available
-func (c *Client) GetMotivationalMessage(blockedApp string) string {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// If no API key, return default message
-	if c.apiKey == "" {
-		return getDefaultMessage(c.personality)
-	}
-
-	// Try to get message from API
-	message, err := c.fetchMessage(blockedApp)
 	if err != nil {
 		// Fallback to last successful message or default
 		if c.lastMessage != "" {
 			return c.lastMessage
 		}
-		return getDefaultMessage(c.personality)
+		return ai.DefaultMessage(personality, blockedApp)
 	}
 
 	// Update last message cache
@@ -135,21 +136,39 @@ func (c *Client) GetMotivationalMessage(blockedApp string) string {
 	return message
 }
 
+// FetchMessage sends a message prompt to the API without any fallback,
+// implementing ai.MessageFetcher
+func (c *Client) FetchMessage(ctx context.Context, prompt string) (string, error) {
+	return c.Complete(ctx, prompt)
+}
+
 // fetchMessage fetches a new message from Gemini API
-func (c *Client) fetchMessage(blockedApp string) (string, error) {
-	currentTime := time.Now().Format("15:04")
-	
-	prompt := fmt.Sprintf(`Kamu adalah asisten produktivitas yang %s.
+func (c *Client) fetchMessage(blockedApp, personality string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
 
-Aplikasi "%s" baru saja ditutup pada jam %s karena sedang waktu produktif untuk belajar.
+	return c.Complete(ctx, ai.BuildPrompt(personality, blockedApp, time.Now()))
+}
 
-Berikan pesan motivasi singkat (maksimal 2-3 kalimat) yang:
-1. Mengingatkan pentingnya fokus belajar
-2. Memberikan saran konkret yang bisa dilakukan sekarang
-3. Dalam bahasa Indonesia
+// Complete sends an arbitrary prompt to Gemini, retrying temporary
+// failures, implementing ai.Completer
+func (c *Client) Complete(ctx context.Context, prompt string) (string, error) {
+	ctx = ai.WithAPIKeys(ctx)
+	if getAPIKey(ctx) == "" {
+		return "", fmt.Errorf("GEMINI_API_KEY not set")
+	}
+	return c.retry.Complete(ctx, prompt)
+}
+
+// generate calls the Gemini generateContent API once with a single prompt
+func (c *Client) generate(ctx context.Context, prompt string) (text string, err error) {
+	start := time.Now()
+	defer func() { ai.ObserveRequest(ai.ProviderGemini, start, err) }()
 
-Langsung berikan pesannya tanpa tambahan format atau penjelasan lain.`, 
-		c.personality, blockedApp, currentTime)
+	apiKey := getAPIKey(ctx)
+	if apiKey == "" {
+		return "", fmt.Errorf("GEMINI_API_KEY not set")
+	}
 
 	reqBody := GeminiRequest{
 		Contents: []Content{
@@ -166,27 +185,31 @@ Langsung berikan pesannya tanpa tambahan format atau penjelasan lain.`,
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf(geminiAPIEndpoint, c.model, c.apiKey)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
+	endpoint := fmt.Sprintf(geminiAPIEndpoint, c.baseURL, c.model)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	// A header keeps the key out of URLs, which end up in errors and proxy logs
+	req.Header.Set("x-goog-api-key", apiKey)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		// Drop the request URL from the error, it may contain credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return "", ai.NewAPIError(resp, body)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -212,24 +235,3 @@ func (c *Client) GetLastMessage() string {
 	defer c.mu.RUnlock()
 	return c.lastMessage
 }
-
-// getDefaultMessage returns a default motivational message based on personality
-func getDefaultMessage(personality string) string {
-	// Parse personality to determine message style
-	lowerPersonality := strings.ToLower(personality)
-	
-	if strings.Contains(lowerPersonality, "programmer") || strings.Contains(lowerPersonality, "developer") {
-		return "⏰ Waktunya fokus coding! Tutup distraksi dan selesaikan task kamu. Debugging bisa lebih smooth kalau fokus penuh!"
-	} else if strings.Contains(lowerPersonality, "student") || strings.Contains(lowerPersonality, "pelajar") {
-		return "📚 Fokus belajar dulu ya! Masa depan kamu ditentukan dari usaha hari ini. Semangat!"
-	} else if strings.Contains(lowerPersonality, "designer") || strings.Contains(lowerPersonality, "creative") {
-		return "🎨 Waktunya berkarya! Fokus ke project kamu. Kreativitas butuh konsentrasi penuh!"
-	} else if strings.Contains(lowerPersonality, "writer") || strings.Contains(lowerPersonality, "content") {
-		return "✍️ Tulis dulu konten kamu! Konsistensi adalah kunci. Fokus menulis sekarang!"
-	} else if strings.Contains(lowerPersonality, "entrepreneur") || strings.Contains(lowerPersonality, "business") {
-		return "💼 Fokus ke bisnis! Goals kamu tidak akan tercapai dengan distraksi. Execute sekarang!"
-	}
-	
-	// Default generic message
-	return "🚀 Fokus sekarang! Singkirkan distraksi dan selesaikan yang penting. You got this!"
-}
@@ -0,0 +1,164 @@
+package gemini
+
+import (
+	"appblock/ai"
+	"appblock/gemini/geminitest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client with an API key, pointed at the fake server
+func newTestClient(t *testing.T, server *geminitest.Server) *Client {
+	t.Helper()
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	client, err := NewClient("test-model", "programmer")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.SetBaseURL(server.BaseURL())
+	policy := ai.DefaultRetryPolicy
+	policy.BaseDelay = time.Millisecond
+	client.retry = ai.NewRetryingCompleter(ai.CompleterFunc(client.generate), policy)
+	return client
+}
+
+func TestGetMotivationalMessageSuccess(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.Reply("Fokus dulu, main nanti!")
+
+	client := newTestClient(t, server)
+
+	got := client.GetMotivationalMessage("steam.exe", "")
+	if got != "Fokus dulu, main nanti!" {
+		t.Fatalf("message = %q, want server reply", got)
+	}
+	if client.GetLastMessage() != got {
+		t.Errorf("last message = %q, want %q", client.GetLastMessage(), got)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.Model != "test-model" {
+		t.Errorf("model = %q, want test-model", req.Model)
+	}
+	if req.APIKey != "test-key" {
+		t.Errorf("api key header = %q, want test-key", req.APIKey)
+	}
+	if strings.Contains(req.RawQuery, "test-key") {
+		t.Errorf("api key leaked into the query string: %q", req.RawQuery)
+	}
+	if !strings.Contains(req.Prompt, "steam.exe") || !strings.Contains(req.Prompt, "programmer") {
+		t.Errorf("prompt does not mention app and personality: %q", req.Prompt)
+	}
+}
+
+func TestFetchMessageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"non-200", http.StatusInternalServerError, `{"error":{"message":"boom"}}`, "status 500"},
+		{"quota exceeded", http.StatusTooManyRequests, `{"error":{"message":"quota"}}`, "status 429"},
+		{"empty candidates", http.StatusOK, `{"candidates":[]}`, "no content"},
+		{"candidate without parts", http.StatusOK, `{"candidates":[{"content":{"parts":[]}}]}`, "no content"},
+		{"malformed json", http.StatusOK, `{"candidates": [`, "failed to parse response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := geminitest.NewServer()
+			defer server.Close()
+			server.ReplyRaw(tt.status, tt.body)
+
+			client := newTestClient(t, server)
+
+			_, err := client.fetchMessage("steam.exe", "programmer")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+
+			// The popup still gets a message: the initial default one
+			got := client.GetMotivationalMessage("steam.exe", "")
+			if want := ai.DefaultMessage("programmer", "steam.exe"); got != want {
+				t.Errorf("fallback message = %q, want default %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFetchMessageTimeout(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.Delay(time.Second)
+
+	client := newTestClient(t, server)
+	client.httpClient.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := client.fetchMessage("steam.exe", "programmer")
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("request took %v, want it cut off by the timeout", elapsed)
+	}
+}
+
+func TestFallbackToLastMessage(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+
+	client := newTestClient(t, server)
+
+	server.Reply("Pesan pertama")
+	if got := client.GetMotivationalMessage("steam.exe", ""); got != "Pesan pertama" {
+		t.Fatalf("first message = %q", got)
+	}
+
+	server.ReplyRaw(http.StatusServiceUnavailable, "unavailable")
+	if got := client.GetMotivationalMessage("discord.exe", ""); got != "Pesan pertama" {
+		t.Errorf("message after failure = %q, want last successful message", got)
+	}
+}
+
+func TestNoAPIKeyUsesDefaultMessage(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	t.Setenv("GEMINI_API_KEY", "")
+
+	client, err := NewClient("test-model", "student")
+	if err == nil {
+		t.Error("NewClient without key should report it")
+	}
+	client.SetBaseURL(server.BaseURL())
+
+	if got, want := client.GetMotivationalMessage("steam.exe", ""), ai.DefaultMessage("student", "steam.exe"); got != want {
+		t.Errorf("message = %q, want default %q", got, want)
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("server received %d requests without an API key", n)
+	}
+}
+
+func TestSetBaseURL(t *testing.T) {
+	client := &Client{}
+
+	client.SetBaseURL("http://localhost:1234/v1beta/")
+	if client.baseURL != "http://localhost:1234/v1beta" {
+		t.Errorf("baseURL = %q, want trailing slash trimmed", client.baseURL)
+	}
+
+	client.SetBaseURL("")
+	if client.baseURL != DefaultBaseURL {
+		t.Errorf("baseURL = %q, want default", client.baseURL)
+	}
+}
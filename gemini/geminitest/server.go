@@ -0,0 +1,162 @@
+// Package geminitest provides a fake Gemini API server for tests
+package geminitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a generateContent call received by the fake server
+type Request struct {
+	Model    string
+	APIKey   string // from the x-goog-api-key header
+	RawQuery string
+	Prompt   string
+}
+
+// Response is a canned response queued with Queue
+type Response struct {
+	Status int
+	Body   string
+	Header map[string]string
+}
+
+// Server is a fake Gemini API. By default it answers every request with a
+// fixed message; use the Reply* methods to change its behavior.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	body     string
+	queue    []Response
+	delay    time.Duration
+	requests []Request
+}
+
+// DefaultReply is the message returned until Reply is called
+const DefaultReply = "Pesan dari server palsu"
+
+// NewServer starts a fake Gemini server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{}
+	s.Reply(DefaultReply)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// BaseURL returns the URL to pass to gemini.Client.SetBaseURL
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1beta"
+}
+
+// Reply makes the server answer with a successful response containing text
+func (s *Server) Reply(text string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"candidates": []interface{}{
+			map[string]interface{}{
+				"content": map[string]interface{}{
+					"parts": []interface{}{map[string]string{"text": text}},
+				},
+			},
+		},
+	})
+	s.ReplyRaw(http.StatusOK, string(data))
+}
+
+// ReplyRaw makes the server answer with the given status and body verbatim,
+// e.g. an error payload, malformed JSON or a response without candidates
+func (s *Server) ReplyRaw(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.body = body
+}
+
+// Queue makes the next requests get the given responses, one each, before
+// the server goes back to its Reply/ReplyRaw behavior. Use it to script
+// retries, e.g. a 503 followed by a success.
+func (s *Server) Queue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, responses...)
+}
+
+// Delay makes the server wait before answering, to trigger client timeouts
+func (s *Server) Delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// handle serves POST /v1beta/models/{model}:generateContent
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/v1beta/models/"
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, prefix) || !strings.HasSuffix(r.URL.Path, ":generateContent") {
+		http.Error(w, fmt.Sprintf("unexpected request %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	model := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), ":generateContent")
+
+	var req struct {
+		Contents []struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"contents"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	prompt := ""
+	if len(req.Contents) > 0 && len(req.Contents[0].Parts) > 0 {
+		prompt = req.Contents[0].Parts[0].Text
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Model:    model,
+		APIKey:   r.Header.Get("x-goog-api-key"),
+		RawQuery: r.URL.RawQuery,
+		Prompt:   prompt,
+	})
+	resp := Response{Status: s.status, Body: s.body}
+	if len(s.queue) > 0 {
+		resp = s.queue[0]
+		s.queue = s.queue[1:]
+	}
+	delay := s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	for key, value := range resp.Header {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(resp.Status)
+	io.WriteString(w, resp.Body)
+}
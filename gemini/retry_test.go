@@ -0,0 +1,170 @@
+package gemini
+
+import (
+	"appblock/ai"
+	"appblock/gemini/geminitest"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryTemporaryFailures(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.Queue(
+		geminitest.Response{Status: http.StatusServiceUnavailable, Body: "unavailable"},
+		geminitest.Response{Status: http.StatusBadGateway, Body: "bad gateway"},
+	)
+
+	client := newTestClient(t, server)
+
+	got, err := client.fetchMessage("steam.exe", "programmer")
+	if err != nil {
+		t.Fatalf("fetchMessage: %v", err)
+	}
+	if got != geminitest.DefaultReply {
+		t.Errorf("message = %q, want %q", got, geminitest.DefaultReply)
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("server received %d requests, want 3", n)
+	}
+	if state, _ := client.Breaker().State(); state != ai.BreakerClosed {
+		t.Errorf("breaker state = %v, want closed", state)
+	}
+}
+
+func TestRetriesReuseAPIKey(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.Queue(geminitest.Response{Status: http.StatusServiceUnavailable, Body: "unavailable"})
+
+	client := newTestClient(t, server)
+
+	// The key is looked up once per request, not again for each retry
+	ctx := ai.WithAPIKeys(context.Background())
+	getAPIKey(ctx)
+	t.Setenv("GEMINI_API_KEY", "changed-key")
+
+	if _, err := client.Complete(ctx, "prompt"); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	for i, req := range server.Requests() {
+		if req.APIKey != "test-key" {
+			t.Errorf("request %d used key %q, want test-key", i, req.APIKey)
+		}
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.ReplyRaw(http.StatusBadRequest, `{"error":{"message":"bad request"}}`)
+
+	client := newTestClient(t, server)
+
+	if _, err := client.fetchMessage("steam.exe", "programmer"); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestRetryAfterIsHonored(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.Queue(geminitest.Response{
+		Status: http.StatusTooManyRequests,
+		Body:   "slow down",
+		Header: map[string]string{"Retry-After": "1"},
+	})
+
+	client := newTestClient(t, server)
+
+	start := time.Now()
+	if _, err := client.fetchMessage("steam.exe", "programmer"); err != nil {
+		t.Fatalf("fetchMessage: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+}
+
+func TestLongRetryAfterOpensBreaker(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.Queue(geminitest.Response{
+		Status: http.StatusTooManyRequests,
+		Body:   "quota exceeded",
+		Header: map[string]string{"Retry-After": "120"},
+	})
+
+	client := newTestClient(t, server)
+
+	var apiErr *ai.APIError
+	_, err := client.fetchMessage("steam.exe", "programmer")
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 2*time.Minute {
+		t.Fatalf("error = %v, want APIError with 2m Retry-After", err)
+	}
+
+	state, until := client.Breaker().State()
+	if state != ai.BreakerOpen {
+		t.Fatalf("breaker state = %v, want open", state)
+	}
+	if d := time.Until(until); d < time.Minute || d > 2*time.Minute {
+		t.Errorf("breaker open for %v, want about the Retry-After", d)
+	}
+
+	if _, err := client.fetchMessage("steam.exe", "programmer"); !errors.Is(err, ai.ErrCircuitOpen) {
+		t.Errorf("error while open = %v, want ErrCircuitOpen", err)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestRepeatedFailuresOpenBreaker(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.ReplyRaw(http.StatusInternalServerError, "boom")
+
+	client := newTestClient(t, server)
+
+	for i := 0; i < ai.DefaultRetryPolicy.BreakerThreshold; i++ {
+		if _, err := client.fetchMessage("steam.exe", "programmer"); errors.Is(err, ai.ErrCircuitOpen) {
+			t.Fatalf("breaker opened after %d failures, want %d", i, ai.DefaultRetryPolicy.BreakerThreshold)
+		}
+	}
+	if state, _ := client.Breaker().State(); state != ai.BreakerOpen {
+		t.Fatalf("breaker state = %v, want open", state)
+	}
+
+	sent := len(server.Requests())
+	if got, want := client.GetMotivationalMessage("steam.exe", ""), ai.DefaultMessage("programmer", "steam.exe"); got != want {
+		t.Errorf("message while open = %q, want default %q", got, want)
+	}
+	if n := len(server.Requests()); n != sent {
+		t.Errorf("server received %d requests while the breaker was open", n-sent)
+	}
+}
+
+func TestUnauthorizedOpensBreaker(t *testing.T) {
+	server := geminitest.NewServer()
+	defer server.Close()
+	server.ReplyRaw(http.StatusForbidden, `{"error":{"message":"API key not valid"}}`)
+
+	client := newTestClient(t, server)
+
+	client.fetchMessage("steam.exe", "programmer")
+	if state, _ := client.Breaker().State(); state != ai.BreakerOpen {
+		t.Errorf("breaker state = %v, want open after an invalid key", state)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
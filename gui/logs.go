@@ -0,0 +1,204 @@
+//go:build windows
+// +build windows
+
+package gui
+
+import (
+	"appblock/logview"
+	"appblock/utils"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// maxViewerEntries bounds the entries shown, keeping the newest
+const maxViewerEntries = 2000
+
+// viewerLevels are the level filter choices
+var viewerLevels = []struct {
+	Name  string
+	Level slog.Level
+}{
+	{Name: "Semua", Level: slog.LevelDebug},
+	{Name: "Info", Level: slog.LevelInfo},
+	{Name: "Warning", Level: slog.LevelWarn},
+	{Name: "Error", Level: slog.LevelError},
+}
+
+// viewerPeriods are the time filter choices; zero means everything
+var viewerPeriods = []struct {
+	Name   string
+	Period time.Duration
+}{
+	{Name: "1 jam terakhir", Period: time.Hour},
+	{Name: "24 jam terakhir", Period: 24 * time.Hour},
+	{Name: "7 hari terakhir", Period: 7 * 24 * time.Hour},
+	{Name: "Semua", Period: 0},
+}
+
+// allApps is the app filter choice that shows every entry
+const allApps = "Semua aplikasi"
+
+// ShowLogViewer shows the log viewer window
+func ShowLogViewer() error {
+	var mainWindow *walk.MainWindow
+	var levelCombo, appCombo, periodCombo *walk.ComboBox
+	var logText *walk.TextEdit
+	var statusLabel *walk.Label
+
+	apps := []string{allApps}
+	refreshing := false
+
+	refresh := func() {
+		// Combo boxes report changes while the window is still being created
+		if refreshing || logText == nil || statusLabel == nil {
+			return
+		}
+		refreshing = true
+		defer func() { refreshing = false }()
+
+		filter := logview.Filter{Level: slog.LevelDebug}
+		if period := viewerPeriods[max(periodCombo.CurrentIndex(), 0)].Period; period > 0 {
+			filter.Since = time.Now().Add(-period)
+		}
+
+		entries, err := logview.Read(utils.LogFiles(utils.GetLogPath()), filter)
+		if err != nil {
+			statusLabel.SetText("Gagal membaca log: " + err.Error())
+		}
+
+		// Offer the apps seen in the period, keeping the current choice
+		selected := appCombo.Text()
+		apps = append([]string{allApps}, logview.Apps(entries)...)
+		appCombo.SetModel(apps)
+		appCombo.SetCurrentIndex(0)
+		for i, app := range apps {
+			if app == selected {
+				appCombo.SetCurrentIndex(i)
+			}
+		}
+
+		filter.Level = viewerLevels[max(levelCombo.CurrentIndex(), 0)].Level
+		if idx := appCombo.CurrentIndex(); idx > 0 {
+			filter.App = apps[idx]
+		}
+
+		var lines []string
+		for _, e := range entries {
+			if filter.Matches(e) {
+				lines = append(lines, e.String())
+			}
+		}
+		total := len(lines)
+		if len(lines) > maxViewerEntries {
+			lines = lines[len(lines)-maxViewerEntries:]
+		}
+
+		logText.SetText(strings.Join(lines, "\r\n"))
+		logText.SendMessage(0x00B7, 0, 0) // EM_SCROLLCARET: show the newest entries
+		if err == nil {
+			statusLabel.SetText(fmt.Sprintf("%d entri (menampilkan %d) - %s", total, len(lines), utils.GetLogPath()))
+		}
+	}
+
+	err := MainWindow{
+		AssignTo: &mainWindow,
+		Title:    "APPBlock - Log",
+		MinSize:  Size{Width: 700, Height: 400},
+		Size:     Size{Width: 1000, Height: 650},
+		Layout:   VBox{},
+		Children: []Widget{
+			Composite{
+				Layout: HBox{},
+				Children: []Widget{
+					Label{Text: "Level:"},
+					ComboBox{
+						AssignTo:              &levelCombo,
+						Model:                 []string{viewerLevels[0].Name, viewerLevels[1].Name, viewerLevels[2].Name, viewerLevels[3].Name},
+						CurrentIndex:          1,
+						OnCurrentIndexChanged: func() { refresh() },
+					},
+					Label{Text: "Aplikasi:"},
+					ComboBox{
+						AssignTo:              &appCombo,
+						Model:                 apps,
+						CurrentIndex:          0,
+						OnCurrentIndexChanged: func() { refresh() },
+					},
+					Label{Text: "Waktu:"},
+					ComboBox{
+						AssignTo:              &periodCombo,
+						Model:                 []string{viewerPeriods[0].Name, viewerPeriods[1].Name, viewerPeriods[2].Name, viewerPeriods[3].Name},
+						CurrentIndex:          1,
+						OnCurrentIndexChanged: func() { refresh() },
+					},
+					PushButton{
+						Text:      "🔄 Refresh",
+						OnClicked: func() { refresh() },
+					},
+					HSpacer{},
+					PushButton{
+						Text:      "📦 Export Diagnostik",
+						OnClicked: func() { exportDiagnostics(mainWindow) },
+					},
+				},
+			},
+			TextEdit{
+				AssignTo: &logText,
+				ReadOnly: true,
+				VScroll:  true,
+				HScroll:  true,
+				Font:     Font{Family: "Consolas", PointSize: 9},
+			},
+			Label{
+				AssignTo:  &statusLabel,
+				Font:      Font{PointSize: 8},
+				TextColor: walk.RGB(100, 100, 100),
+			},
+		},
+	}.Create()
+
+	if err != nil {
+		return err
+	}
+
+	refresh()
+
+	mainWindow.Show()
+	mainWindow.BringToTop()
+	mainWindow.Activate()
+	mainWindow.Run()
+
+	return nil
+}
+
+// exportDiagnostics asks where to save a diagnostic bundle and writes it
+func exportDiagnostics(owner walk.Form) {
+	dlg := walk.FileDialog{
+		Title:    "Export Diagnostic Bundle",
+		Filter:   "Zip Files (*.zip)|*.zip",
+		FilePath: logview.DefaultBundleName(),
+	}
+
+	if ok, _ := dlg.ShowSave(owner); !ok {
+		return
+	}
+
+	path := dlg.FilePath
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		path += ".zip"
+	}
+
+	if err := logview.ExportDiagnostics(path); err != nil {
+		walk.MsgBox(owner, "Error", "Gagal membuat bundle diagnostik: "+err.Error(), walk.MsgBoxIconError)
+		return
+	}
+
+	utils.LogInfo("Diagnostic bundle exported to %s", path)
+	walk.MsgBox(owner, "Export Berhasil ✅", "Bundle diagnostik disimpan di:\n"+path+"\n\nBerisi log, config (tanpa API key/token) dan info versi.", walk.MsgBoxIconInformation)
+}
@@ -4,7 +4,9 @@
 package gui
 
 import (
+	"appblock/ai"
 	"appblock/config"
+	"appblock/secret"
 	"appblock/utils"
 	"fmt"
 	"strings"
@@ -54,6 +56,23 @@ var personalityPresets = []PersonalityPreset{
 	},
 }
 
+// messageLanguages are the languages with bundled prompt and message templates
+var messageLanguages = []struct {
+	Code string
+	Name string
+}{
+	{Code: ai.LanguageIndonesian, Name: "Bahasa Indonesia"},
+	{Code: ai.LanguageEnglish, Name: "English"},
+}
+
+// keyTester sends a test request with the configured AI provider
+var keyTester func() error
+
+// SetKeyTester sets the function behind the API key Test button
+func SetKeyTester(fn func() error) {
+	keyTester = fn
+}
+
 // ShowSettings shows the settings window
 func ShowSettings(onSave func()) error {
 	cfg := config.Get()
@@ -65,11 +84,19 @@ func ShowSettings(onSave func()) error {
 	var timeWindowModel *TimeWindowModel
 	var personalityCombo *walk.ComboBox
 	var personalityEdit *walk.TextEdit
+	var goalEdit *walk.LineEdit
+	var languageCombo *walk.ComboBox
+	var keyEdit *walk.LineEdit
+	var testKeyButton *walk.PushButton
+	keyName := cfg.AI.APIKeyName()
 	var enabledCheck *walk.CheckBox
 	var autostartCheck *walk.CheckBox
 	var scanIntervalEdit *walk.NumberEdit
 	var popupCooldownEdit *walk.NumberEdit
+	var warnMinutesEdit *walk.NumberEdit
 	var aiEnabledCheck *walk.CheckBox
+	var classifyCheck *walk.CheckBox
+	var liveMessagesCheck *walk.CheckBox
 	
 	// Blocklist model
 	blocklistModel = NewBlocklistModel(cfg.Blocklist)
@@ -133,6 +160,14 @@ func ShowSettings(onSave func()) error {
 								MaxValue: 300,
 								ToolTipText: "",
 							},
+							Label{Text: "Peringatan Sebelum Blocking (menit):", ToolTipText: ""},
+							NumberEdit{
+								AssignTo: &warnMinutesEdit,
+								Value:    float64(cfg.WarnMinutesBefore),
+								MinValue: 0,
+								MaxValue: 120,
+								ToolTipText: "Daftar aplikasi yang akan ditutup muncul sekian menit sebelum jam produktif (0 = mati)",
+							},
 						},
 					},
 					
@@ -196,11 +231,50 @@ func ShowSettings(onSave func()) error {
 					GroupBox{
 						Title:  "🤖 AI Personality",
 						Layout: VBox{},
-						Children: []Widget{						Label{
-							Text: "ℹ️ Untuk AI: Buat file .env dengan GEMINI_API_KEY=your_key\nTanpa API key = pesan default.",
-							Font: Font{PointSize: 8},
-							TextColor: walk.RGB(100, 100, 100),
-						},							CheckBox{
+						Children: []Widget{							Label{
+								Text:    "API Key (" + keyName + "):",
+								Visible: keyName != "",
+							},
+							Composite{
+								Layout:  HBox{MarginsZero: true},
+								Visible: keyName != "",
+								Children: []Widget{
+									LineEdit{
+										AssignTo:     &keyEdit,
+										PasswordMode: true,
+										CueBanner:    keyStatus(keyName),
+									},
+									PushButton{
+										Text: "Simpan",
+										OnClicked: func() {
+											saveKey(mainWindow, keyEdit, keyName)
+										},
+									},
+									PushButton{
+										AssignTo: &testKeyButton,
+										Text:     "Test",
+										OnClicked: func() {
+											if keyEdit.Text() != "" && !saveKey(mainWindow, keyEdit, keyName) {
+												return
+											}
+											testKey(mainWindow, testKeyButton)
+										},
+									},
+									PushButton{
+										Text: "Hapus",
+										OnClicked: func() {
+											deleteKey(mainWindow, keyEdit, keyName)
+										},
+									},
+								},
+							},
+							Label{
+								Text:      "ℹ️ Key disimpan di " + secret.Backend() + ".\nTanpa API key = pesan default.",
+								Font:      Font{PointSize: 8},
+								TextColor: walk.RGB(100, 100, 100),
+								Visible:   keyName != "",
+							},
+							CheckBox{
 								AssignTo: &aiEnabledCheck,
 								Text:     "Enable AI Motivational Messages",
 								Checked:  cfg.AI.Enabled,
@@ -229,6 +303,30 @@ func ShowSettings(onSave func()) error {
 								MinSize:  Size{Height: 80},
 								ReadOnly: true,
 							},
+							Label{Text: "Bahasa Pesan:"},
+							ComboBox{
+								AssignTo:     &languageCombo,
+								Model:        getLanguageNames(),
+								CurrentIndex: getLanguageIndex(cfg.AI.Language),
+							},
+							Label{Text: "Target Sesi Ini (opsional):"},
+							LineEdit{
+								AssignTo:  &goalEdit,
+								Text:      cfg.AI.Goal,
+								CueBanner: "Contoh: Selesaikan bab 3 skripsi",
+							},
+							CheckBox{
+								AssignTo:    &liveMessagesCheck,
+								Text:        "Tulis pesan saat aplikasi diblokir",
+								Checked:     cfg.AI.LiveMessages,
+								ToolTipText: "Pesan menyebut jam dan jumlah percobaan hari ini, tapi popup menunggu balasan AI",
+							},
+							CheckBox{
+								AssignTo:    &classifyCheck,
+								Text:        "Sarankan blokir aplikasi baru (AI)",
+								Checked:     cfg.AI.ClassifyUnknown,
+								ToolTipText: "Nama, lokasi dan judul jendela aplikasi yang tidak dikenal dikirim ke AI",
+							},
 						},
 					},
 					
@@ -295,25 +393,30 @@ func ShowSettings(onSave func()) error {
 					PushButton{
 						Text: "💾 Save",
 						OnClicked: func() {
-							// Save config
-							cfg := config.Get()
-							cfg.Enabled = enabledCheck.Checked()
-							cfg.Autostart = autostartCheck.Checked()
-							cfg.ScanIntervalSeconds = int(scanIntervalEdit.Value())
-							cfg.PopupCooldownSeconds = int(popupCooldownEdit.Value())
-							cfg.Blocklist = blocklistModel.GetItems()
-							cfg.TimeWindows = timeWindowModel.GetItems()
-							cfg.AI.Enabled = aiEnabledCheck.Checked()
-							cfg.AI.Personality = personalityEdit.Text()
-							
-							if err := config.Save(); err != nil {
+							// Save config and mark first run as completed
+							err := config.Update(func(cfg *config.Config) {
+								cfg.Enabled = enabledCheck.Checked()
+								cfg.Autostart = autostartCheck.Checked()
+								cfg.ScanIntervalSeconds = int(scanIntervalEdit.Value())
+								cfg.PopupCooldownSeconds = int(popupCooldownEdit.Value())
+								cfg.WarnMinutesBefore = int(warnMinutesEdit.Value())
+								cfg.Blocklist = blocklistModel.GetItems()
+								cfg.TimeWindows = timeWindowModel.GetItems()
+								cfg.AI.Enabled = aiEnabledCheck.Checked()
+								cfg.AI.Personality = personalityEdit.Text()
+								cfg.AI.Goal = strings.TrimSpace(goalEdit.Text())
+								cfg.AI.ClassifyUnknown = classifyCheck.Checked()
+								cfg.AI.LiveMessages = liveMessagesCheck.Checked()
+								if idx := languageCombo.CurrentIndex(); idx >= 0 && idx < len(messageLanguages) {
+									cfg.AI.Language = messageLanguages[idx].Code
+								}
+								cfg.FirstRunCompleted = true
+							})
+							if err != nil {
 								walk.MsgBox(mainWindow, "Error", "Failed to save config: "+err.Error(), walk.MsgBoxIconError)
 								return
 							}
 							
-							// Mark first run as completed
-							cfg.MarkFirstRunCompleted()
-							
 							utils.LogInfo("Settings saved via GUI")
 							
 							// Close window first
@@ -434,6 +537,94 @@ func (m *BlocklistModel) GetItems() []string {
 }
 
 // Helper functions
+// keyStatus returns the placeholder for the API key field. The stored key
+// itself is never shown.
+func keyStatus(name string) string {
+	if _, err := secret.Get(name); err == nil {
+		return "•••••••• (tersimpan - ketik untuk mengganti)"
+	}
+	return "Belum ada key"
+}
+
+// saveKey stores the entered API key and clears the field
+func saveKey(owner walk.Form, edit *walk.LineEdit, name string) bool {
+	value := strings.TrimSpace(edit.Text())
+	if value == "" {
+		walk.MsgBox(owner, "API Key", "Masukkan API key terlebih dahulu.", walk.MsgBoxIconInformation)
+		return false
+	}
+
+	if err := secret.Set(name, value); err != nil {
+		walk.MsgBox(owner, "Error", "Gagal menyimpan API key: "+err.Error(), walk.MsgBoxIconError)
+		return false
+	}
+
+	edit.SetText("")
+	edit.SetCueBanner(keyStatus(name))
+	utils.LogInfo("API key %s stored in %s", name, secret.Backend())
+	return true
+}
+
+// testKey sends a test request in the background, so the window stays
+// responsive while the provider answers, and reports the result
+func testKey(owner walk.Form, button *walk.PushButton) {
+	if keyTester == nil {
+		return
+	}
+
+	button.SetEnabled(false)
+	button.SetText("Testing...")
+	go func() {
+		err := keyTester()
+		owner.Synchronize(func() {
+			button.SetText("Test")
+			button.SetEnabled(true)
+			if err != nil {
+				walk.MsgBox(owner, "Test Gagal", "API key tidak bisa dipakai:\n"+err.Error(), walk.MsgBoxIconError)
+				return
+			}
+			walk.MsgBox(owner, "Test Berhasil ✅", "API key berfungsi.", walk.MsgBoxIconInformation)
+		})
+	}()
+}
+
+// deleteKey removes the stored API key after confirmation
+func deleteKey(owner walk.Form, edit *walk.LineEdit, name string) {
+	if walk.MsgBox(owner, "Hapus API Key", "Hapus "+name+" dari "+secret.Backend()+"?", walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	if err := secret.Delete(name); err != nil {
+		walk.MsgBox(owner, "Error", "Gagal menghapus API key: "+err.Error(), walk.MsgBoxIconError)
+		return
+	}
+
+	edit.SetCueBanner(keyStatus(name))
+	utils.LogInfo("API key %s deleted from %s", name, secret.Backend())
+}
+
+func getLanguageNames() []string {
+	names := make([]string, len(messageLanguages))
+	for i, lang := range messageLanguages {
+		names[i] = lang.Name
+	}
+	return names
+}
+
+// getLanguageIndex returns the combo index of a language code, or -1 for a
+// custom language so saving keeps it unchanged
+func getLanguageIndex(code string) int {
+	if code == "" {
+		code = ai.DefaultLanguage
+	}
+	for i, lang := range messageLanguages {
+		if strings.EqualFold(lang.Code, code) {
+			return i
+		}
+	}
+	return -1
+}
+
 func getPersonalityNames() []string {
 	names := make([]string, len(personalityPresets))
 	for i, preset := range personalityPresets {
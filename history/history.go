@@ -0,0 +1,173 @@
+// Package history records blocked apps and the messages shown for them
+package history
+
+import (
+	"appblock/events"
+	"appblock/utils"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retention is how long events are kept on disk
+const retention = 30 * 24 * time.Hour
+
+// Event kinds
+const (
+	KindBlocked = "blocked" // a blocked app was closed
+	KindMessage = "message" // a motivational message was shown for an app
+)
+
+// Event is a recorded block or message
+type Event struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	App     string    `json:"app"`
+	Message string    `json:"message,omitempty"`
+}
+
+var (
+	historyPath string
+	recorded    []Event
+	mu          sync.RWMutex
+)
+
+// Init loads the history stored in dir, dropping events past the retention period
+func Init(dir string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	historyPath = filepath.Join(dir, "history.jsonl")
+	recorded = nil
+
+	data, err := os.ReadFile(historyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-retention)
+	pruned := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Skip lines torn by a crash mid-write
+			pruned = true
+			continue
+		}
+		if e.Time.Before(cutoff) {
+			pruned = true
+			continue
+		}
+		recorded = append(recorded, e)
+	}
+
+	if pruned {
+		return rewrite()
+	}
+	return nil
+}
+
+// Subscribe records the apps the blocker closes, as it publishes them on
+// the events bus, until unsubscribe is called. The subscription never drops
+// an event, so a slow disk delays blocks in the history but loses none.
+func Subscribe() (unsubscribe func()) {
+	return events.OnReliable(func(e events.ProcessBlocked) {
+		record(Event{Time: e.Time, Kind: KindBlocked, App: e.App})
+	})
+}
+
+// RecordMessage records the message shown for app
+func RecordMessage(app, message string) {
+	record(Event{Time: time.Now(), Kind: KindMessage, App: app, Message: message})
+}
+
+// CountBlocked returns how many times app was blocked since the given time
+func CountBlocked(app string, since time.Time) int {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	count := 0
+	for _, e := range recorded {
+		if e.Kind == KindBlocked && strings.EqualFold(e.App, app) && !e.Time.Before(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// LastMessage returns the last message shown for app, or "" if none
+func LastMessage(app string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for i := len(recorded) - 1; i >= 0; i-- {
+		if e := recorded[i]; e.Kind == KindMessage && strings.EqualFold(e.App, app) {
+			return e.Message
+		}
+	}
+	return ""
+}
+
+// Events returns the events recorded since the given time, oldest first
+func Events(since time.Time) []Event {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var result []Event
+	for _, e := range recorded {
+		if !e.Time.Before(since) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// record appends an event in memory and to the history file
+func record(e Event) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	recorded = append(recorded, e)
+	if historyPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(historyPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		utils.LogWarning("Failed to record history: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		utils.LogWarning("Failed to record history: %v", err)
+	}
+}
+
+// rewrite replaces the history file with the events in memory. Callers must hold mu.
+func rewrite() error {
+	var buf bytes.Buffer
+	for _, e := range recorded {
+		line, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return utils.WriteFileAtomic(historyPath, buf.Bytes(), 0644)
+}
@@ -0,0 +1,67 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// execute runs the hook's command with the event in its environment:
+// APPBLOCK_EVENT, APPBLOCK_APP, APPBLOCK_TIME and so on for every field, and
+// APPBLOCK_PAYLOAD with the whole event as JSON
+func execute(ctx context.Context, h *hook, data map[string]any) error {
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	env, err := environment(data)
+	if err != nil {
+		return &permanentError{err}
+	}
+	cmd.Env = append(os.Environ(), env...)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out: %w", ctx.Err())
+		}
+		if out := strings.TrimSpace(output.String()); out != "" {
+			return fmt.Errorf("%w: %s", err, truncate(out, 200))
+		}
+		return err
+	}
+	return nil
+}
+
+// environment returns the APPBLOCK_* variables for the event
+func environment(data map[string]any) ([]string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	env := []string{"APPBLOCK_PAYLOAD=" + string(encoded)}
+	for key, value := range data {
+		name := "APPBLOCK_" + strings.ToUpper(key)
+		if s, ok := value.(string); ok {
+			env = append(env, name+"="+s)
+		} else {
+			env = append(env, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,262 @@
+// Package hooks runs the configured webhooks and commands when scheduler and
+// blocker events are published, retrying failed attempts with backoff.
+package hooks
+
+import (
+	"appblock/config"
+	"appblock/events"
+	"appblock/history"
+	"appblock/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// maxRunning bounds the hooks running at the same time
+const maxRunning = 4
+
+// retryDelay is the wait before the first retry; it doubles after each one
+var retryDelay = 2 * time.Second
+
+// Runner fires hooks for the events on the application-wide bus
+type Runner struct {
+	hooks       []*hook
+	running     chan struct{} // Semaphore of maxRunning
+	unsubscribe func()
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+}
+
+// hook is a configured hook with its parsed body template
+type hook struct {
+	config.Hook
+	body     *template.Template
+	limitDay string // Day the blocked limit was last reported, guarded by Runner.mu
+}
+
+// NewRunner creates a runner without hooks
+func NewRunner() *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		running: make(chan struct{}, maxRunning),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Apply replaces the hooks. Webhook URLs and header values are kept out of
+// the log, as they often contain tokens.
+func (r *Runner) Apply(hooks []config.Hook) {
+	parsed := make([]*hook, 0, len(hooks))
+	for _, h := range hooks {
+		body, err := h.ParseBody()
+		if err != nil {
+			utils.LogWarning("Ignoring hook %s: invalid body template: %v", h.Label(), err)
+			continue
+		}
+		utils.RegisterSecret(h.URL)
+		for _, value := range h.Headers {
+			utils.RegisterSecret(value)
+		}
+		parsed = append(parsed, &hook{Hook: h, body: body})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Keep the daily limit state of hooks that did not change
+	for _, h := range parsed {
+		for _, old := range r.hooks {
+			if hookEqual(old.Hook, h.Hook) {
+				h.limitDay = old.limitDay
+			}
+		}
+	}
+	r.hooks = parsed
+}
+
+// Start fires hooks for every event published from now on
+func (r *Runner) Start() {
+	r.unsubscribe = events.Subscribe(r.Fire)
+}
+
+// Stop stops firing hooks and waits briefly for running ones, which are
+// cancelled after that
+func (r *Runner) Stop() {
+	if r.unsubscribe != nil {
+		r.unsubscribe()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		r.cancel()
+		<-done
+	}
+	r.cancel()
+}
+
+// Fire runs the hooks matching e in the background
+func (r *Runner) Fire(e events.Event) {
+	matching := r.matching(e)
+	if len(matching) == 0 {
+		return
+	}
+
+	data, err := payload(e)
+	if err != nil {
+		utils.LogError("Failed to encode %s event for hooks: %v", e.Name(), err)
+		return
+	}
+
+	for _, h := range matching {
+		r.wg.Add(1)
+		go func(h *hook) {
+			defer r.wg.Done()
+
+			select {
+			case r.running <- struct{}{}:
+				defer func() { <-r.running }()
+			case <-r.ctx.Done():
+				return
+			}
+			r.run(h, e.Name(), data)
+		}(h)
+	}
+}
+
+// matching returns the hooks that fire on e
+func (r *Runner) matching(e events.Event) []*hook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matching []*hook
+	for _, h := range r.hooks {
+		if !h.Matches(e.Name()) {
+			continue
+		}
+		if _, blocked := e.(events.ProcessBlocked); blocked && h.BlockedLimit > 0 {
+			if !limitReached(h, e) {
+				continue
+			}
+		}
+		matching = append(matching, h)
+	}
+	return matching
+}
+
+// limitReached reports whether today's blocked attempts, counting e, just
+// reached the hook's limit, at most once a day. Callers must hold Runner.mu.
+func limitReached(h *hook, e events.Event) bool {
+	now := time.Now()
+	day := now.Format("2006-01-02")
+	if h.limitDay == day || blockedToday(e) < h.BlockedLimit {
+		return false
+	}
+	h.limitDay = day
+	return true
+}
+
+// run calls the hook, retrying failures with exponential backoff
+func (r *Runner) run(h *hook, event string, data map[string]any) {
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(r.ctx, h.Timeout())
+		var err error
+		if h.URL != "" {
+			err = post(ctx, h, data)
+		} else {
+			err = execute(ctx, h, data)
+		}
+		cancel()
+
+		if err == nil {
+			utils.LogDebug("Hook %s ran for %s", h.Label(), event)
+			return
+		}
+
+		var permanent *permanentError
+		if attempt >= h.Retries || errors.As(err, &permanent) || r.ctx.Err() != nil {
+			utils.LogWarning("Hook %s failed for %s: %v", h.Label(), event, err)
+			return
+		}
+		utils.LogDebug("Hook %s failed for %s, retrying in %s: %v", h.Label(), event, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-r.ctx.Done():
+			return
+		}
+		delay *= 2
+	}
+}
+
+// permanentError is a failure that a retry would not fix, e.g. HTTP 400
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// payload returns the event fields by their JSON names, plus the event
+// name and today's number of blocked attempts
+func payload(e events.Event) (map[string]any, error) {
+	encoded, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	// Keep numbers as written, so a PID is not turned into a float
+	data := make(map[string]any)
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	data["event"] = e.Name()
+	data["blocked_today"] = blockedToday(e)
+	return data, nil
+}
+
+// blockedToday counts the blocked attempts since midnight. For a
+// ProcessBlocked event the count runs up to and including that block:
+// history receives the event at the same time as the hooks, so it may or
+// may not have recorded it yet.
+func blockedToday(e events.Event) int {
+	now := time.Now()
+	blocked, isBlock := e.(events.ProcessBlocked)
+	if isBlock && !blocked.Time.IsZero() {
+		now = blocked.Time
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	count := 0
+	for _, h := range history.Events(midnight) {
+		if h.Kind == history.KindBlocked && (!isBlock || h.Time.Before(now)) {
+			count++
+		}
+	}
+	if isBlock {
+		count++
+	}
+	return count
+}
+
+// hookEqual reports whether two hook configurations are the same
+func hookEqual(a, b config.Hook) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
@@ -0,0 +1,225 @@
+package hooks
+
+import (
+	"appblock/config"
+	"appblock/events"
+	"appblock/history"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	retryDelay = 10 * time.Millisecond
+}
+
+// fire runs the hooks for e and waits for them to finish
+func fire(t *testing.T, hooks []config.Hook, e events.Event) {
+	t.Helper()
+	if err := history.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	fireWith(NewRunner(), hooks, e)
+}
+
+func fireWith(r *Runner, hooks []config.Hook, e events.Event) {
+	r.Apply(hooks)
+	r.Fire(e)
+	r.wg.Wait()
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	var got map[string]any
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid JSON body %s: %v", body, err)
+		}
+	}))
+	defer srv.Close()
+
+	fire(t, []config.Hook{{
+		Events:  []string{"process_blocked"},
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer abc"},
+		Body:    `{"text": {{json (printf "Closed %s" .app)}}, "pid": {{.pid}}}`,
+	}}, events.ProcessBlocked{App: `steam "beta".exe`, PID: 4242})
+
+	if got["text"] != `Closed steam "beta".exe` || got["pid"] != float64(4242) {
+		t.Errorf("body = %v", got)
+	}
+	if auth != "Bearer abc" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
+
+func TestWebhookDefaultBodyAndFilter(t *testing.T) {
+	var calls atomic.Int32
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	hooks := []config.Hook{{Events: []string{"productive_started"}, URL: srv.URL}}
+	fire(t, hooks, events.ProcessBlocked{App: "steam.exe"})
+	if calls.Load() != 0 {
+		t.Fatalf("hook fired for an event it does not list")
+	}
+
+	fire(t, hooks, events.ProductiveStarted{Profile: "Study"})
+	if calls.Load() != 1 || got["event"] != "productive_started" || got["profile"] != "Study" {
+		t.Errorf("calls = %d, body = %v", calls.Load(), got)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	fire(t, []config.Hook{{Events: []string{"*"}, URL: srv.URL, Retries: 5}}, events.ConfigReloaded{})
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3 (two failures, then success)", calls.Load())
+	}
+
+	// Retries stop after the configured number
+	calls.Store(-10)
+	fire(t, []config.Hook{{Events: []string{"*"}, URL: srv.URL, Retries: 2}}, events.ConfigReloaded{})
+	if calls.Load() != -7 {
+		t.Errorf("calls = %d, want 3 attempts", calls.Load()+10)
+	}
+}
+
+func TestWebhookNoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	fire(t, []config.Hook{{Events: []string{"*"}, URL: srv.URL, Retries: 3}}, events.ConfigReloaded{})
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	fire(t, []config.Hook{{Events: []string{"*"}, URL: srv.URL, TimeoutSeconds: 1, Retries: 1}}, events.ConfigReloaded{})
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("took %s despite the 1s timeout", elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2 (timed out attempts are retried)", calls.Load())
+	}
+}
+
+func TestBlockedLimit(t *testing.T) {
+	if err := history.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	unsubscribe := history.Subscribe()
+	defer unsubscribe()
+
+	r := NewRunner()
+	hooks := []config.Hook{{Events: []string{"process_blocked"}, URL: srv.URL, BlockedLimit: 3}}
+	for i := 0; i < 5; i++ {
+		// History records the block concurrently with the hook firing
+		e := events.ProcessBlocked{Time: time.Now(), App: "steam.exe"}
+		events.Publish(e)
+		fireWith(r, hooks, e)
+		// Fires once, on the third block of the day
+		want := int32(0)
+		if i >= 2 {
+			want = 1
+		}
+		if calls.Load() != want {
+			t.Fatalf("after %d blocks: calls = %d, want %d", i+1, calls.Load(), want)
+		}
+
+		for deadline := time.Now().Add(time.Second); len(history.Events(e.Time)) == 0; {
+			if time.Now().After(deadline) {
+				t.Fatal("block was not recorded in history")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+
+	fire(t, []config.Hook{{
+		Events:  []string{"productive_upcoming"},
+		Command: []string{"sh", "-c", `echo "$APPBLOCK_EVENT $APPBLOCK_START" > "$0"`, out},
+	}}, events.ProductiveUpcoming{Start: time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)})
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "productive_upcoming 2024-03-11T09:00:00Z" {
+		t.Errorf("command saw %q", got)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	data, err := payload(events.KillFailed{App: "game.exe", PID: 1234567, Error: "access denied"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := environment(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joined := strings.Join(env, "\n")
+	for _, want := range []string{"APPBLOCK_EVENT=kill_failed", "APPBLOCK_APP=game.exe", "APPBLOCK_PID=1234567", "APPBLOCK_ERROR=access denied", "APPBLOCK_PAYLOAD={"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("environment lacks %s:\n%s", want, joined)
+		}
+	}
+}
+
+func TestInvalidTemplateOutput(t *testing.T) {
+	h := &hook{Hook: config.Hook{Body: `{"text": {{.app}}}`}}
+	h.body, _ = h.ParseBody()
+	if _, err := renderBody(h, map[string]any{"app": "steam.exe"}); err == nil {
+		t.Error("unquoted string accepted as JSON")
+	}
+}
@@ -0,0 +1,67 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// client sends webhooks; each request is bounded by the hook's timeout
+var client = &http.Client{}
+
+// post sends the event to the hook's URL
+func post(ctx context.Context, h *hook, data map[string]any) error {
+	body, err := renderBody(h, data)
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "APPBlock")
+	for name, value := range h.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("%s responded %s", req.URL.Host, resp.Status)
+	// Client errors other than timeouts and rate limits won't go away by retrying
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// renderBody returns the request body: the hook's template filled with the
+// event, or the event as JSON
+func renderBody(h *hook, data map[string]any) ([]byte, error) {
+	if h.body == nil {
+		return json.Marshal(data)
+	}
+
+	var buf bytes.Buffer
+	if err := h.body.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("body template did not produce valid JSON (quote values with the json function)")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,237 @@
+package logview
+
+import (
+	"appblock/config"
+	"appblock/paths"
+	"appblock/secret"
+	"appblock/utils"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// BundleInfo describes what goes into a diagnostic bundle
+type BundleInfo struct {
+	Version    string   // APPBlock version
+	ConfigPath string   // config.json, included with secrets redacted
+	LogFiles   []string // Log files, oldest first
+	Extra      []string // Additional lines for version.txt, e.g. the secret store backend
+}
+
+// sensitiveKeys are config keys whose values are never included in a bundle
+var sensitiveKeys = []string{"key", "token", "secret", "password", "authorization"}
+
+// ExportBundle writes a zip with the logs, the redacted config and version
+// information to path
+func ExportBundle(path string, info BundleInfo) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(file)
+
+	if err := addVersion(zw, info); err != nil {
+		return err
+	}
+	if err := addConfig(zw, info.ConfigPath); err != nil {
+		return err
+	}
+	for _, name := range info.LogFiles {
+		if err := addLog(zw, name); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// addVersion writes version.txt
+func addVersion(zw *zip.Writer, info BundleInfo) error {
+	w, err := zw.Create("version.txt")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "APPBlock %s\n", info.Version)
+	fmt.Fprintf(w, "Go %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "Exported %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(w, "Config %s\n", info.ConfigPath)
+	for _, line := range info.Extra {
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+// addConfig writes config.json with secrets removed. A missing config is skipped.
+func addConfig(zw *zip.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || path == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	redacted, err := RedactConfig(data)
+	if err != nil {
+		// Still useful for diagnosing a broken config
+		redacted = []byte(utils.Redact(string(data)))
+	}
+
+	w, err := zw.Create("config.json")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(redacted)
+	return err
+}
+
+// addLog writes a log file uncompressed under logs/, redacting each line
+// again in case it was written before redaction existed
+func addLog(zw *zip.Writer, name string) error {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	base := filepath.Base(name)
+	if strings.HasSuffix(base, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+		base = strings.TrimSuffix(base, ".gz")
+	}
+
+	w, err := zw.Create("logs/" + base)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if _, err := io.WriteString(w, utils.Redact(scanner.Text())+"\n"); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// RedactConfig removes secrets from a config file: values of keys that look
+// like credentials, credentials in URLs, and known key formats
+func RedactConfig(data []byte) ([]byte, error) {
+	var cfg any
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	redactValue("", &cfg)
+
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(utils.Redact(string(out))), nil
+}
+
+// redactValue redacts v in place; key is the name v is stored under
+func redactValue(key string, v *any) {
+	switch value := (*v).(type) {
+	case map[string]any:
+		for k, item := range value {
+			redactValue(k, &item)
+			value[k] = item
+		}
+	case []any:
+		for i := range value {
+			redactValue(key, &value[i])
+		}
+	case string:
+		if value != "" && isSensitiveKey(key) {
+			*v = "[REDACTED]"
+			return
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			return
+		}
+		if u.User != nil {
+			u.User = url.User("[REDACTED]")
+			*v = u.String()
+		}
+		// Webhook and private calendar URLs usually carry their token in the path
+		if (key == "url" || key == "source") && (strings.Trim(u.Path, "/") != "" || u.RawQuery != "") {
+			u.Path, u.RawQuery = "/[REDACTED]", ""
+			*v = u.String()
+		}
+	}
+}
+
+// isSensitiveKey reports whether a config key holds a credential. Keys
+// naming where a credential is found (e.g. api_key_env) are kept.
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "_env") {
+		return false
+	}
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportDiagnostics writes a diagnostic bundle of the running installation:
+// every app.log file, config.json and the version and secret store in use
+func ExportDiagnostics(path string) error {
+	logPath := utils.GetLogPath()
+	if logPath == "" {
+		var err error
+		if logPath, err = utils.DefaultLogPath(); err != nil {
+			return err
+		}
+	}
+
+	return ExportBundle(path, BundleInfo{
+		Version:    utils.Version,
+		ConfigPath: config.GetPath(),
+		LogFiles:   utils.LogFiles(logPath),
+		Extra: []string{
+			"Log " + logPath,
+			"Secrets " + secret.Backend(),
+			fmt.Sprintf("Data %s (portable %t)", paths.DataDir(), paths.Portable()),
+		},
+	})
+}
+
+// DefaultBundleName returns a file name for a bundle exported now
+func DefaultBundleName() string {
+	return "appblock-diagnostics-" + time.Now().Format("20060102-150405") + ".zip"
+}
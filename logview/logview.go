@@ -0,0 +1,397 @@
+// Package logview reads, filters and follows APPBlock's log files, and
+// exports them in a diagnostic bundle
+package logview
+
+import (
+	"appblock/utils"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// followInterval is how often Follow checks the log for new lines
+const followInterval = 500 * time.Millisecond
+
+// maxLineSize bounds a single log line
+const maxLineSize = 1 << 20
+
+// Attr is a key-value attribute of a log entry
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// Entry is a parsed log line
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   []Attr
+}
+
+// Attr returns the value of the attribute key, or ""
+func (e Entry) Attr(key string) string {
+	for _, a := range e.Attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// App returns the blocked app the entry is about, or ""
+func (e Entry) App() string {
+	return e.Attr("app")
+}
+
+// String formats the entry for display
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, " %-5s %s", e.Level.String(), e.Message)
+	for _, a := range e.Attrs {
+		value := a.Value
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", a.Key, value)
+	}
+	return b.String()
+}
+
+// Filter selects log entries. Zero fields match everything.
+type Filter struct {
+	Since time.Time  // Entries at or after this time
+	Level slog.Level // Minimum level; the zero value is info, use slog.LevelDebug for all
+	App   string     // Blocked app, case-insensitive
+}
+
+// Matches reports whether the entry passes the filter
+func (f Filter) Matches(e Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if e.Level < f.Level {
+		return false
+	}
+	if f.App != "" && !strings.EqualFold(e.App(), f.App) {
+		return false
+	}
+	return true
+}
+
+// Read returns the entries of the log files matching the filter, oldest
+// first. files are read in order; gzip-compressed files (.gz) are supported.
+func Read(files []string, filter Filter) ([]Entry, error) {
+	var entries []Entry
+	for _, name := range files {
+		err := readFile(name, func(e Entry) {
+			if filter.Matches(e) {
+				entries = append(entries, e)
+			}
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// Apps returns the blocked apps mentioned in entries, sorted
+func Apps(entries []Entry) []string {
+	seen := make(map[string]bool)
+	var apps []string
+	for _, e := range entries {
+		app := e.App()
+		if app == "" || seen[strings.ToLower(app)] {
+			continue
+		}
+		seen[strings.ToLower(app)] = true
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return strings.ToLower(apps[i]) < strings.ToLower(apps[j])
+	})
+	return apps
+}
+
+// Follow calls fn for every matching entry appended to the log at path until
+// ctx is done. It starts at the end of the file and picks up rotations.
+func Follow(ctx context.Context, path string, filter Filter, fn func(Entry)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	var partial []byte
+	buf := make([]byte, 32*1024)
+	emit := func(line []byte) {
+		if e, ok := ParseLine(string(line)); ok && filter.Matches(e) {
+			fn(e)
+		}
+	}
+	// readToEOF passes on every complete line appended to file since the last read
+	readToEOF := func() {
+		for {
+			n, err := file.Read(buf)
+			offset += int64(n)
+			partial = append(partial, buf[:n]...)
+			for {
+				i := bytes.IndexByte(partial, '\n')
+				if i < 0 {
+					break
+				}
+				emit(partial[:i])
+				partial = partial[i+1:]
+			}
+			if err != nil || n == 0 {
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Reopen when the log was rotated (replaced or truncated)
+		if info, err := os.Stat(path); err == nil {
+			current, _ := file.Stat()
+			replaced := current == nil || !os.SameFile(info, current)
+			if replaced || info.Size() < offset {
+				if reopened, err := os.Open(path); err == nil {
+					// Lines written just before a rotation are still in the
+					// old file, and the last one will not be completed
+					if replaced {
+						readToEOF()
+						if len(partial) > 0 {
+							emit(partial)
+						}
+					}
+					file.Close()
+					file = reopened
+					offset = 0
+					partial = nil
+				}
+			}
+		}
+
+		readToEOF()
+	}
+}
+
+// readFile parses every line of a log file
+func readFile(name string, fn func(Entry)) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if e, ok := ParseLine(scanner.Text()); ok {
+			fn(e)
+		}
+	}
+	return scanner.Err()
+}
+
+// legacyLine matches lines written before structured logging, e.g.
+// [2024-03-11 14:05:00] [WARN] message
+var legacyLine = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\] \[([A-Z]+)\] (.*)$`)
+
+// ParseLine parses a log line in the JSON, text or legacy format
+func ParseLine(line string) (Entry, bool) {
+	line = strings.TrimRight(line, "\r")
+	switch {
+	case strings.HasPrefix(line, "{"):
+		return parseJSON(line)
+	case strings.HasPrefix(line, "time="):
+		return parseText(line)
+	case strings.HasPrefix(line, "["):
+		return parseLegacy(line)
+	default:
+		return Entry{}, false
+	}
+}
+
+// parseJSON parses a line written by slog's JSON handler
+func parseJSON(line string) (Entry, bool) {
+	var fields map[string]any
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return Entry{}, false
+	}
+
+	var e Entry
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := fields[key]
+		switch key {
+		case slog.TimeKey:
+			e.Time, _ = time.Parse(time.RFC3339Nano, fmt.Sprint(value))
+		case slog.LevelKey:
+			e.Level = parseLevel(fmt.Sprint(value))
+		case slog.MessageKey:
+			e.Message = fmt.Sprint(value)
+		default:
+			if s, ok := value.(string); ok {
+				e.Attrs = append(e.Attrs, Attr{Key: key, Value: s})
+			} else {
+				data, _ := json.Marshal(value)
+				e.Attrs = append(e.Attrs, Attr{Key: key, Value: string(data)})
+			}
+		}
+	}
+	return e, !e.Time.IsZero()
+}
+
+// parseText parses a line written by slog's text handler (logfmt)
+func parseText(line string) (Entry, bool) {
+	var e Entry
+	for _, a := range parseLogfmt(line) {
+		switch a.Key {
+		case slog.TimeKey:
+			e.Time, _ = time.Parse(time.RFC3339Nano, a.Value)
+		case slog.LevelKey:
+			e.Level = parseLevel(a.Value)
+		case slog.MessageKey:
+			e.Message = a.Value
+		default:
+			e.Attrs = append(e.Attrs, a)
+		}
+	}
+	return e, !e.Time.IsZero()
+}
+
+// parseLegacy parses a line written before structured logging
+func parseLegacy(line string) (Entry, bool) {
+	m := legacyLine.FindStringSubmatch(line)
+	if m == nil {
+		return Entry{}, false
+	}
+
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	e := Entry{Time: t, Level: parseLevel(m[2]), Message: m[3]}
+	if m[2] == "BLOCK" {
+		app := strings.TrimPrefix(m[3], "Terminated process: ")
+		e.Attrs = []Attr{{Key: "event", Value: "blocked"}, {Key: "app", Value: app}}
+	}
+	return e, true
+}
+
+// parseLogfmt splits key=value pairs; values may be quoted Go strings
+func parseLogfmt(line string) []Attr {
+	var attrs []Attr
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " ")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			break
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := quotedEnd(line)
+			unquoted, err := strconv.Unquote(line[:end])
+			if err != nil {
+				unquoted = line[1 : end-1]
+			}
+			value = unquoted
+			line = line[end:]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		attrs = append(attrs, Attr{Key: key, Value: value})
+	}
+	return attrs
+}
+
+// quotedEnd returns the index just past the closing quote of the quoted
+// string at the start of s, or len(s) when it is not closed
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// parseLevel parses a level name as written by slog or the legacy logger
+func parseLevel(name string) slog.Level {
+	switch strings.ToUpper(name) {
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN", "WARNING":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	default:
+		// INFO, BLOCK and anything unknown
+		return slog.LevelInfo
+	}
+}
+
+// ParseLevel parses a level name for filtering; "" or "all" includes debug
+func ParseLevel(name string) (slog.Level, error) {
+	if name == "" || strings.EqualFold(name, "all") {
+		return slog.LevelDebug, nil
+	}
+	return utils.ParseLogLevel(name)
+}
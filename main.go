@@ -1,24 +1,52 @@
 package main
 
 import (
+	"appblock/ai"
 	"appblock/autostart"
 	"appblock/blocker"
+	"appblock/calendar"
+	"appblock/catalog"
+	"appblock/classifier"
 	"appblock/config"
-	"appblock/gemini"
+	"appblock/events"
+	"appblock/history"
+	"appblock/hooks"
+	"appblock/paths"
 	"appblock/popup"
+	"appblock/report"
 	"appblock/scheduler"
-	"appblock/tray"
+	"appblock/secret"
 	"appblock/utils"
+	"appblock/website"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 )
 
 func main() {
+	// Run command-line subcommands without starting the tray app.
+	// --headless runs without the tray, with the web dashboard as interface.
+	headless := !trayAvailable
+	if len(os.Args) > 1 {
+		if os.Args[1] != "--headless" {
+			os.Exit(runCLI(os.Args[1:]))
+		}
+		headless = true
+	}
+
+	// Resolve the per-user directories, moving files from older versions
+	pathsErr := paths.Init()
+
 	// Check for single instance (prevent multiple instances)
 	if err := checkSingleInstance(); err != nil {
+		if headless {
+			fmt.Fprintln(os.Stderr, "APPBlock is already running. Run \"appblock dashboard\" for the dashboard link.")
+			os.Exit(1)
+		}
 		// Show notification that app is already running
 		popup.ShowInfo("APPBlock Sudah Berjalan! ✅", 
 			"APPBlock sudah aktif di system tray (pojok kanan bawah).\n\n"+
@@ -37,6 +65,15 @@ func main() {
 	defer utils.CloseLogger()
 
 	utils.LogInfo("APPBlock starting...")
+	if pathsErr != nil {
+		utils.LogWarning("Problem setting up data directories: %v", pathsErr)
+	}
+	for _, moved := range paths.Migrated() {
+		utils.LogInfo("Moved %s from the executable directory", moved)
+	}
+	if paths.Portable() {
+		utils.LogInfo("Portable mode: files are kept in %s", paths.ConfigDir())
+	}
 
 	// Load configuration
 	if err := config.Init(); err != nil {
@@ -46,30 +83,70 @@ func main() {
 
 	cfg := config.Get()
 	utils.LogInfo("Configuration loaded successfully")
+	if err := utils.ConfigureLogger(cfg.Logging.Options()); err != nil {
+		utils.LogWarning("Invalid logging settings: %v", err)
+	}
+
+	// Load app catalog (bundled, or updated copy next to config)
+	if err := catalog.Init(paths.ConfigDir()); err != nil {
+		utils.LogWarning("Failed to load app catalog: %v", err)
+	}
+
+	// Keep API keys in the OS keyring (or an encrypted file without one)
+	secret.Init(paths.ConfigDir())
+
+	// Load block history and the editable prompt and message templates
+	if err := history.Init(paths.DataDir()); err != nil {
+		utils.LogWarning("Failed to load block history: %v", err)
+	}
+	stopHistory := history.Subscribe()
+	defer stopHistory()
+	if err := ai.InitTemplates(paths.ConfigDir()); err != nil {
+		utils.LogWarning("Failed to load templates: %v", err)
+	}
+	ai.SetLanguage(cfg.AI.Language)
+	ai.SetPromptContext(promptContext)
+	report.Init(paths.DataDir())
 
 	// Sync autostart with config
 	if err := autostart.Sync(cfg.Autostart); err != nil {
 		utils.LogWarning("Failed to sync autostart: %v", err)
 	}
 
-	// Initialize Gemini client
-	var geminiClient *gemini.Client
-	if cfg.AI.Enabled {
-		client, err := gemini.NewClient(cfg.AI.Model, cfg.AI.Personality)
-		if err != nil {
-			utils.LogWarning("Failed to initialize Gemini client: %v", err)
-			utils.LogWarning("AI features will be disabled")
-		} else {
-			geminiClient = client
-			utils.LogInfo("Gemini AI client initialized")
-		}
-	}
+	// Initialize AI message provider
+	provider := initMessageProvider(cfg)
+	var providerMu sync.Mutex
+	defer func() {
+		providerMu.Lock()
+		stopMessageProvider(provider)
+		providerMu.Unlock()
+	}()
 
 	// Create scheduler
 	sched := scheduler.NewScheduler(cfg)
 	
 	// Create blocker
-	block := blocker.NewBlocker(cfg, sched, geminiClient)
+	block := blocker.NewBlocker(cfg, sched, provider)
+
+	// Suggest blocking unknown distractions seen during productive time (opt-in)
+	classify := classifier.New(classifierCompleter(cfg.AI), filepath.Join(paths.DataDir(), "classifications.json"))
+	classify.Start()
+	defer classify.Stop()
+	block.SetClassifier(classify)
+
+	// Run the configured webhooks and commands on scheduler and blocker events
+	hookRunner := hooks.NewRunner()
+	hookRunner.Apply(cfg.Hooks)
+	hookRunner.Start()
+	defer hookRunner.Stop()
+
+	// Count focus blocks from the configured calendar as productive time
+	cal := calendar.New()
+	cal.Apply(cfg.Calendar)
+	sched.SetCalendar(cal)
+	cal.OnChange(sched.ForceCheck)
+	cal.Start()
+	defer cal.Stop()
 
 	// Start scheduler
 	sched.Start()
@@ -79,48 +156,87 @@ func main() {
 	block.Start()
 	defer block.Stop()
 
-	// Create system tray app
-	trayApp := tray.NewApp(cfg)
+	// Serve Prometheus metrics for central monitoring (opt-in)
+	var metricsServer metricsEndpoint
+	metricsServer.apply(cfg.Metrics)
+	defer metricsServer.stop()
 
-	// Set up scheduler callback to update tray status
-	sched.SetStatusCallback(func(isProductive bool) {
-		trayApp.UpdateProductiveStatus(isProductive)
-	})
+	// Create and start website blocker (restores the hosts file on stop)
+	webBlock := website.NewBlocker(cfg, sched, paths.DataDir())
+	webBlock.Start()
+	defer webBlock.Stop()
+
+	// Create system tray app (or the headless frontend)
+	trayApp := newFrontend(cfg, headless)
 
-	// Set up tray callbacks
-	trayApp.SetCallbacks(
-		func() {
-			// On toggle enabled - reload config
-			if err := config.Load(); err != nil {
-				utils.LogError("Failed to reload config: %v", err)
-			} else {
-				cfg = config.Get()
-				sched.UpdateConfig(cfg)
-				block.UpdateConfig(cfg)
-				sched.ForceCheck()
+	// Serve the web dashboard (opt-in, always on when headless)
+	dashboard := dashboardEndpoint{headless: headless, schedule: sched, onURL: trayApp.SetDashboardURL}
+	dashboard.apply(cfg.Dashboard)
+	defer dashboard.stop()
+
+	// The tray follows later changes through the events package
+	trayApp.SetNextTransitionFunc(sched.NextTransition)
+	trayApp.UpdateActiveProfile(sched.ActiveProfile())
+	trayApp.UpdateProductiveStatus(sched.IsProductive())
+	watchAIStatus(provider, trayApp.UpdateAIStatus)
+	trayApp.SetClassifier(classify)
+	classify.OnSuggestionsChanged(trayApp.UpdateSuggestions)
+
+	// Push every config change (tray, settings window, file edits) to all components
+	currentAI := cfg.AI
+	currentLogging := cfg.Logging
+	config.Subscribe(func(newCfg *config.Config) {
+		if newCfg.Logging != currentLogging {
+			currentLogging = newCfg.Logging
+			if err := utils.ConfigureLogger(newCfg.Logging.Options()); err != nil {
+				utils.LogWarning("Invalid logging settings: %v", err)
 			}
-		},
-		func() {
-			// On reload config - update all components
-			utils.LogInfo("Reloading all components with new config...")
-			cfg = config.Get()
-			
-			// Update scheduler with new config
-			sched.UpdateConfig(cfg)
-			
-			// Update blocker with new config
-			block.UpdateConfig(cfg)
-			
-			// Force scheduler to recheck productive time
-			sched.ForceCheck()
-			
-			utils.LogInfo("All components updated with new configuration")
-		},
-		func() {
-			// On quit
-			utils.LogInfo("APPBlock shutting down...")
-		},
-	)
+		}
+
+		metricsServer.apply(newCfg.Metrics)
+		dashboard.apply(newCfg.Dashboard)
+		hookRunner.Apply(newCfg.Hooks)
+		cal.Apply(newCfg.Calendar)
+
+		// Recreate the AI provider only when its settings changed
+		if newCfg.AI != currentAI {
+			currentAI = newCfg.AI
+			ai.SetLanguage(newCfg.AI.Language)
+			providerMu.Lock()
+			stopMessageProvider(provider)
+			provider = initMessageProvider(newCfg)
+			block.SetProvider(provider)
+			watchAIStatus(provider, trayApp.UpdateAIStatus)
+			providerMu.Unlock()
+			classify.SetCompleter(classifierCompleter(newCfg.AI))
+		}
+
+		sched.UpdateConfig(newCfg)
+		block.UpdateConfig(newCfg)
+		webBlock.UpdateConfig(newCfg)
+		trayApp.UpdateConfig(newCfg)
+		sched.ForceCheck()
+		utils.LogInfo("All components updated with new configuration")
+		events.Publish(events.ConfigReloaded{Time: time.Now()})
+	})
+
+	trayApp.SetQuitCallback(func() {
+		utils.LogInfo("APPBlock shutting down...")
+	})
+
+	// Watch config file and apply external edits automatically
+	watcher, err := config.NewWatcher()
+	if err != nil {
+		utils.LogWarning("Failed to start config watcher: %v", err)
+	} else {
+		watcher.Start()
+		defer watcher.Stop()
+	}
+
+	// Save a coaching summary of the previous week each Monday
+	stopReports := make(chan struct{})
+	go runWeeklyReports(stopReports)
+	defer close(stopReports)
 
 	// Handle system signals for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -132,6 +248,13 @@ func main() {
 		trayApp.Quit()
 	}()
 
+	if headless {
+		utils.LogInfo("APPBlock started successfully - Running headless")
+		trayApp.Start()
+		utils.LogInfo("APPBlock stopped")
+		return
+	}
+
 	utils.LogInfo("APPBlock started successfully - Running in system tray")
 
 	// Always auto-open settings on startup
@@ -163,48 +286,38 @@ func main() {
 	utils.LogInfo("APPBlock stopped")
 }
 
-// Single instance management using lock file
+// lockFile holds the single-instance lock while APPBlock runs
 var lockFile *os.File
 
+// checkSingleInstance locks the lock file, failing if another instance holds
+// the lock. The lock is held by the OS, not by the file existing, so one left
+// behind by a crashed instance is free again and nothing needs to be deleted.
+// The file holds the PID of the running instance for troubleshooting.
 func checkSingleInstance() error {
-	exePath, err := os.Executable()
+	lockPath := filepath.Join(paths.RuntimeDir(), "appblock.lock")
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return fmt.Errorf("failed to open lock file: %w", err)
 	}
-	
-	exeDir := filepath.Dir(exePath)
-	lockPath := filepath.Join(exeDir, "appblock.lock")
-	
-	// Try to create lock file
-	lockFile, err = os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
-	if err != nil {
-		if os.IsExist(err) {
-			// Check if process still running, if not delete stale lock
-			os.Remove(lockPath)
-			// Try again
-			lockFile, err = os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
-			if err != nil {
-				return fmt.Errorf("another instance is already running")
-			}
-			return nil
-		}
-		return fmt.Errorf("failed to create lock file: %w", err)
+	if err := lockInstance(f); err != nil {
+		f.Close()
+		return fmt.Errorf("another instance is already running")
+	}
+
+	lockFile = f
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%d", os.Getpid())
 	}
-	
-	// Write PID to lock file
-	fmt.Fprintf(lockFile, "%d", os.Getpid())
 	return nil
 }
 
+// releaseSingleInstance releases the lock. The file is kept: removing it
+// could let an instance that opened it just before lock it, while another
+// instance locks a new file at the same path.
 func releaseSingleInstance() {
 	if lockFile != nil {
+		lockFile.Truncate(0)
 		lockFile.Close()
-		
-		exePath, err := os.Executable()
-		if err == nil {
-			exeDir := filepath.Dir(exePath)
-			lockPath := filepath.Join(exeDir, "appblock.lock")
-			os.Remove(lockPath)
-		}
 	}
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"appblock/config"
+	"appblock/metrics"
+	"appblock/utils"
+	"net"
+	"sync"
+)
+
+// metricsEndpoint runs the Prometheus metrics server while it is enabled
+type metricsEndpoint struct {
+	server *metrics.Server
+	addr   string // Configured address of server, which may differ from the one it resolved to
+	mu     sync.Mutex
+}
+
+// apply starts, stops or moves the server to match the settings
+func (m *metricsEndpoint) apply(cfg config.MetricsConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.server != nil {
+		if cfg.Enabled && m.addr == cfg.Addr() {
+			return
+		}
+		m.closeLocked()
+	}
+	if !cfg.Enabled {
+		return
+	}
+
+	server, err := metrics.Listen(cfg.Addr())
+	if err != nil {
+		utils.LogError("Failed to start metrics endpoint on %s: %v", cfg.Addr(), err)
+		return
+	}
+	m.server = server
+	m.addr = cfg.Addr()
+	utils.LogInfo("Metrics available at http://%s/metrics", server.Addr())
+	if host, _, err := net.SplitHostPort(server.Addr()); err == nil && net.ParseIP(host).IsLoopback() {
+		utils.LogInfo("Metrics endpoint only accepts local connections; set metrics.address to scrape it from another machine")
+	}
+}
+
+// stop stops the server if it is running
+func (m *metricsEndpoint) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.server != nil {
+		m.closeLocked()
+	}
+}
+
+// closeLocked stops the server. Callers must hold mu.
+func (m *metricsEndpoint) closeLocked() {
+	if err := m.server.Close(); err != nil {
+		utils.LogWarning("Failed to stop metrics endpoint: %v", err)
+	}
+	m.server = nil
+}
@@ -0,0 +1,43 @@
+// Package metrics exposes APPBlock's instrumentation over HTTP in the
+// Prometheus text format, so a central Prometheus (or any OpenMetrics
+// scraper) can monitor APPBlock. Metrics are client_golang collectors
+// registered in Default, next to the Go runtime and process metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// DefBuckets are histogram buckets in seconds suited to request latencies
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry the instrumented packages register in and the
+// metrics endpoint serves. It includes the go_* runtime and process_*
+// (CPU, memory, open handles) metrics.
+var Default = NewRegistry()
+
+// NewRegistry creates a registry with the Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// MustRegister registers c in the Default registry and returns it, for
+// package-level metric variables. It panics on a duplicate name.
+func MustRegister[C prometheus.Collector](c C) C {
+	Default.MustRegister(c)
+	return c
+}
+
+// BoolValue returns 1 for true and 0 for false, for gauges of a state
+func BoolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
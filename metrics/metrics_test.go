@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// scrape fetches url and parses the body as the Prometheus text format
+func scrape(t *testing.T, url string) map[string]*dto.MetricFamily {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		t.Fatalf("output is not valid exposition format: %v", err)
+	}
+	return families
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	killed := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_killed_total",
+		Help: "Processes killed",
+	}, []string{"app"})
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_latency_seconds",
+		Help:    "Latency",
+		Buckets: []float64{0.1, 0.5, 1},
+	})
+	r.MustRegister(killed, latency)
+
+	killed.WithLabelValues("steam.exe").Inc()
+	killed.WithLabelValues(`odd"name`).Add(2)
+	latency.Observe(0.05)
+	latency.Observe(0.3)
+	latency.Observe(4)
+
+	srv := httptest.NewServer(Handler(r))
+	defer srv.Close()
+	families := scrape(t, srv.URL+"/metrics")
+
+	counter := families["test_killed_total"]
+	if counter == nil || counter.GetType() != dto.MetricType_COUNTER || len(counter.Metric) != 2 {
+		t.Fatalf("test_killed_total = %v", counter)
+	}
+	for _, m := range counter.Metric {
+		app := m.Label[0].GetValue()
+		if want := map[string]float64{"steam.exe": 1, `odd"name`: 2}[app]; m.Counter.GetValue() != want {
+			t.Errorf("test_killed_total{app=%q} = %v, want %v", app, m.Counter.GetValue(), want)
+		}
+	}
+
+	histogram := families["test_latency_seconds"]
+	if histogram == nil || histogram.GetType() != dto.MetricType_HISTOGRAM {
+		t.Fatalf("test_latency_seconds = %v", histogram)
+	}
+	h := histogram.Metric[0].Histogram
+	if h.GetSampleCount() != 3 || h.GetSampleSum() != 4.35 {
+		t.Errorf("count, sum = %d, %v", h.GetSampleCount(), h.GetSampleSum())
+	}
+	for i, want := range []uint64{1, 2, 2} {
+		if got := h.Bucket[i].GetCumulativeCount(); got != want {
+			t.Errorf("bucket le=%v = %d, want %d", h.Bucket[i].GetUpperBound(), got, want)
+		}
+	}
+
+	// Runtime and process metrics come with every registry
+	for _, name := range []string{"go_goroutines", "go_memstats_alloc_bytes", "process_start_time_seconds"} {
+		if families[name] == nil {
+			t.Errorf("missing %s", name)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	scans := MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "test_listen_scans_total",
+		Help: "Scans performed",
+	}))
+	scans.Inc()
+
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	families := scrape(t, "http://"+s.Addr()+"/metrics")
+	if m := families["test_listen_scans_total"]; m == nil || m.Metric[0].Counter.GetValue() != 1 {
+		t.Errorf("test_listen_scans_total = %v", m)
+	}
+	if families["go_goroutines"] == nil {
+		t.Error("missing go_goroutines")
+	}
+
+	if _, err := Listen(s.Addr()); err == nil {
+		t.Error("expected an error listening on a port in use")
+	}
+}
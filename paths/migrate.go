@@ -0,0 +1,136 @@
+package paths
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// legacyFiles are the files earlier versions kept next to the executable,
+// as glob patterns, with the directory each now belongs in
+var legacyFiles = []struct {
+	Pattern string
+	Dir     func(Dirs) string
+}{
+	{"config.json", configDir},
+	{"catalog.json", configDir},
+	{"secrets.enc", configDir},
+	{"secrets.key", configDir},
+	{".env", configDir},
+	{"prompt.tmpl", configDir},
+	{"templates", configDir},
+	{"history.jsonl", dataDir},
+	{"messages.json", dataDir},
+	{"classifications.json", dataDir},
+	{"hosts.appblock.bak", dataDir},
+	{"reports", dataDir},
+	{"app.log", stateDir},
+	{"app-*.log*", stateDir},
+}
+
+func configDir(d Dirs) string { return d.Config }
+func dataDir(d Dirs) string   { return d.Data }
+func stateDir(d Dirs) string  { return d.State }
+
+// Migrate moves files left in d.ExeDir by earlier versions to the per-user
+// directories, returning the paths they were moved to. Files already present
+// in the new location are left alone. When the executable's directory is
+// read-only the files are copied instead.
+func Migrate(d Dirs) ([]string, error) {
+	var moved []string
+	var errs []error
+
+	for _, legacy := range legacyFiles {
+		target := legacy.Dir(d)
+		if sameDir(d.ExeDir, target) {
+			continue
+		}
+
+		matches, _ := filepath.Glob(filepath.Join(d.ExeDir, legacy.Pattern))
+		for _, src := range matches {
+			dst := filepath.Join(target, filepath.Base(src))
+			if _, err := os.Lstat(dst); !errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			if err := move(src, dst); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			moved = append(moved, dst)
+		}
+	}
+
+	return moved, errors.Join(errs...)
+}
+
+// move renames src to dst, falling back to copying when renaming fails
+// (another drive, or a read-only source). A source that cannot be removed
+// after copying is left in place.
+func move(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if err := copyPath(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	os.RemoveAll(src)
+	return nil
+}
+
+// copyPath copies a file or directory tree, keeping permissions
+func copyPath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFile copies a single file
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// sameDir reports whether a and b are the same directory
+func sameDir(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
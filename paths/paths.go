@@ -0,0 +1,221 @@
+// Package paths resolves where APPBlock keeps its files: per-user
+// directories (XDG base directories on Linux, %APPDATA% on Windows), or the
+// executable's directory in portable mode. Files left next to the executable
+// by earlier versions are moved to the per-user directories on first use.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Environment variables and files that select where files are kept
+const (
+	// EnvHome keeps every file in one directory, e.g. for testing
+	EnvHome = "APPBLOCK_HOME"
+	// EnvPortable set to 1 or true keeps every file next to the executable
+	EnvPortable = "APPBLOCK_PORTABLE"
+	// PortableMarker is a file next to the executable that enables portable mode
+	PortableMarker = "portable"
+)
+
+// Dirs are the directories APPBlock keeps its files in
+type Dirs struct {
+	Config   string // config.json, catalog.json, templates and the encrypted secrets
+	Data     string // Block history, reports, the message pool and classifications
+	State    string // app.log and rotated logs
+	Runtime  string // Single-instance lock file
+	ExeDir   string // Directory of the executable; where earlier versions kept everything
+	Portable bool   // Everything is kept in ExeDir
+}
+
+var (
+	dirs     Dirs
+	migrated []string
+	initErr  error
+	once     sync.Once
+)
+
+// Init resolves the directories, creates them and moves files left next to
+// the executable by earlier versions. It runs once; the directory functions
+// call it on first use.
+func Init() error {
+	once.Do(func() {
+		exeDir := "."
+		if exe, err := os.Executable(); err == nil {
+			exeDir = filepath.Dir(exe)
+		}
+
+		dirs, initErr = Resolve(runtime.GOOS, os.Getenv, exeDir)
+		if initErr != nil {
+			// Keep working from the executable's directory as before
+			dirs = Dirs{Config: exeDir, Data: exeDir, State: exeDir, Runtime: exeDir, ExeDir: exeDir, Portable: true}
+			return
+		}
+
+		for _, dir := range []string{dirs.Config, dirs.Data, dirs.State, dirs.Runtime} {
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				initErr = fmt.Errorf("failed to create %s: %w", dir, err)
+				return
+			}
+		}
+
+		if !dirs.Portable {
+			migrated, initErr = Migrate(dirs)
+		}
+	})
+	return initErr
+}
+
+// Get returns the resolved directories
+func Get() Dirs {
+	Init()
+	return dirs
+}
+
+// ConfigDir returns the directory for config.json and other user-edited files
+func ConfigDir() string {
+	return Get().Config
+}
+
+// DataDir returns the directory for files APPBlock generates
+func DataDir() string {
+	return Get().Data
+}
+
+// StateDir returns the directory for logs
+func StateDir() string {
+	return Get().State
+}
+
+// RuntimeDir returns the directory for the lock file
+func RuntimeDir() string {
+	return Get().Runtime
+}
+
+// Portable reports whether files are kept next to the executable
+func Portable() bool {
+	return Get().Portable
+}
+
+// Migrated returns the files Init moved from the executable's directory
+func Migrated() []string {
+	Init()
+	return migrated
+}
+
+// Resolve works out the directories for an OS from its environment. exeDir
+// is the executable's directory, checked for the portable marker.
+func Resolve(goos string, getenv func(string) string, exeDir string) (Dirs, error) {
+	if home := getenv(EnvHome); home != "" {
+		return Dirs{Config: home, Data: home, State: home, Runtime: home, ExeDir: exeDir}, nil
+	}
+
+	if isPortable(getenv, exeDir) {
+		return Dirs{Config: exeDir, Data: exeDir, State: exeDir, Runtime: exeDir, ExeDir: exeDir, Portable: true}, nil
+	}
+
+	switch goos {
+	case "windows":
+		return resolveWindows(getenv, exeDir)
+	case "darwin":
+		return resolveDarwin(getenv, exeDir)
+	default:
+		return resolveXDG(getenv, exeDir)
+	}
+}
+
+// resolveWindows keeps settings and data in %APPDATA%\APPBlock, which roams
+// with the user profile, and logs and the lock in %LOCALAPPDATA%\APPBlock
+func resolveWindows(getenv func(string) string, exeDir string) (Dirs, error) {
+	profile := getenv("USERPROFILE")
+	roaming := getenv("APPDATA")
+	if roaming == "" && profile != "" {
+		roaming = filepath.Join(profile, "AppData", "Roaming")
+	}
+	local := getenv("LOCALAPPDATA")
+	if local == "" && profile != "" {
+		local = filepath.Join(profile, "AppData", "Local")
+	}
+	if roaming == "" || local == "" {
+		return Dirs{}, errors.New("neither %APPDATA% nor %USERPROFILE% is set")
+	}
+
+	return Dirs{
+		Config:  filepath.Join(roaming, "APPBlock"),
+		Data:    filepath.Join(roaming, "APPBlock"),
+		State:   filepath.Join(local, "APPBlock"),
+		Runtime: filepath.Join(local, "APPBlock"),
+		ExeDir:  exeDir,
+	}, nil
+}
+
+// resolveDarwin keeps everything in ~/Library/Application Support/APPBlock
+// and logs in ~/Library/Logs/APPBlock
+func resolveDarwin(getenv func(string) string, exeDir string) (Dirs, error) {
+	home := getenv("HOME")
+	if home == "" {
+		return Dirs{}, errors.New("$HOME is not set")
+	}
+
+	support := filepath.Join(home, "Library", "Application Support", "APPBlock")
+	return Dirs{
+		Config:  support,
+		Data:    support,
+		State:   filepath.Join(home, "Library", "Logs", "APPBlock"),
+		Runtime: support,
+		ExeDir:  exeDir,
+	}, nil
+}
+
+// resolveXDG follows the XDG Base Directory specification. Relative XDG
+// variables are invalid and ignored.
+func resolveXDG(getenv func(string) string, exeDir string) (Dirs, error) {
+	home := getenv("HOME")
+	xdg := func(name, fallback string) (string, error) {
+		if dir := getenv(name); filepath.IsAbs(dir) {
+			return filepath.Join(dir, "appblock"), nil
+		}
+		if home == "" {
+			return "", fmt.Errorf("neither $%s nor $HOME is set", name)
+		}
+		return filepath.Join(home, fallback, "appblock"), nil
+	}
+
+	var d Dirs
+	var err error
+	if d.Config, err = xdg("XDG_CONFIG_HOME", ".config"); err != nil {
+		return Dirs{}, err
+	}
+	if d.Data, err = xdg("XDG_DATA_HOME", filepath.Join(".local", "share")); err != nil {
+		return Dirs{}, err
+	}
+	if d.State, err = xdg("XDG_STATE_HOME", filepath.Join(".local", "state")); err != nil {
+		return Dirs{}, err
+	}
+
+	// $XDG_RUNTIME_DIR has no default; the state directory is the next best
+	d.Runtime = d.State
+	if dir := getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		d.Runtime = filepath.Join(dir, "appblock")
+	}
+
+	d.ExeDir = exeDir
+	return d, nil
+}
+
+// isPortable reports whether portable mode is enabled by the environment or
+// a marker file next to the executable
+func isPortable(getenv func(string) string, exeDir string) bool {
+	switch strings.ToLower(getenv(EnvPortable)) {
+	case "1", "true", "yes":
+		return true
+	}
+	_, err := os.Stat(filepath.Join(exeDir, PortableMarker))
+	return err == nil
+}
@@ -0,0 +1,184 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestResolveXDG(t *testing.T) {
+	exeDir := t.TempDir()
+
+	d, err := Resolve("linux", env(map[string]string{"HOME": "/home/u"}), exeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Dirs{
+		Config:  "/home/u/.config/appblock",
+		Data:    "/home/u/.local/share/appblock",
+		State:   "/home/u/.local/state/appblock",
+		Runtime: "/home/u/.local/state/appblock",
+		ExeDir:  exeDir,
+	}
+	if d != want {
+		t.Errorf("defaults = %+v, want %+v", d, want)
+	}
+
+	d, err = Resolve("linux", env(map[string]string{
+		"HOME":            "/home/u",
+		"XDG_CONFIG_HOME": "/cfg",
+		"XDG_DATA_HOME":   "relative/ignored",
+		"XDG_RUNTIME_DIR": "/run/user/1000",
+	}), exeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Config != "/cfg/appblock" || d.Data != "/home/u/.local/share/appblock" || d.Runtime != "/run/user/1000/appblock" {
+		t.Errorf("with XDG variables = %+v", d)
+	}
+
+	if _, err := Resolve("linux", env(nil), exeDir); err == nil {
+		t.Error("expected an error without $HOME")
+	}
+}
+
+func TestResolveWindows(t *testing.T) {
+	exeDir := t.TempDir()
+
+	d, err := Resolve("windows", env(map[string]string{
+		"APPDATA":      filepath.Join("C:", "Users", "u", "AppData", "Roaming"),
+		"LOCALAPPDATA": filepath.Join("C:", "Users", "u", "AppData", "Local"),
+	}), exeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Config != filepath.Join("C:", "Users", "u", "AppData", "Roaming", "APPBlock") || d.Data != d.Config {
+		t.Errorf("config/data = %q, %q", d.Config, d.Data)
+	}
+	if d.State != filepath.Join("C:", "Users", "u", "AppData", "Local", "APPBlock") {
+		t.Errorf("state = %q", d.State)
+	}
+
+	d, err = Resolve("windows", env(map[string]string{"USERPROFILE": filepath.Join("C:", "Users", "u")}), exeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Config != filepath.Join("C:", "Users", "u", "AppData", "Roaming", "APPBlock") {
+		t.Errorf("config from USERPROFILE = %q", d.Config)
+	}
+}
+
+func TestResolvePortable(t *testing.T) {
+	exeDir := t.TempDir()
+	vars := map[string]string{"HOME": "/home/u"}
+
+	d, _ := Resolve("linux", env(vars), exeDir)
+	if d.Portable {
+		t.Fatal("portable without marker")
+	}
+
+	if err := os.WriteFile(filepath.Join(exeDir, PortableMarker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, _ = Resolve("linux", env(vars), exeDir)
+	if !d.Portable || d.Config != exeDir || d.State != exeDir {
+		t.Errorf("with marker = %+v", d)
+	}
+
+	vars[EnvHome] = "/srv/appblock"
+	d, _ = Resolve("linux", env(vars), exeDir)
+	if d.Portable || d.Config != "/srv/appblock" || d.Runtime != "/srv/appblock" {
+		t.Errorf("with %s = %+v", EnvHome, d)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	root := t.TempDir()
+	d := Dirs{
+		ExeDir: filepath.Join(root, "bin"),
+		Config: filepath.Join(root, "config"),
+		Data:   filepath.Join(root, "data"),
+		State:  filepath.Join(root, "state"),
+	}
+	for _, dir := range []string{d.ExeDir, d.Config, d.Data, d.State, filepath.Join(d.ExeDir, "templates", "en")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(d.ExeDir, "config.json"), "old config")
+	write(filepath.Join(d.ExeDir, "history.jsonl"), "old history")
+	write(filepath.Join(d.ExeDir, "app.log"), "log")
+	write(filepath.Join(d.ExeDir, "app-2024-03-11T14-05-00.log.gz"), "rotated")
+	write(filepath.Join(d.ExeDir, "templates", "en", "prompt.tmpl"), "prompt")
+	write(filepath.Join(d.ExeDir, "appblock.exe"), "binary")
+	// Already migrated, must not be overwritten
+	write(filepath.Join(d.Data, "history.jsonl"), "new history")
+
+	moved, err := Migrate(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 4 {
+		t.Errorf("moved %v, want 4 files", moved)
+	}
+
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+	if got := read(filepath.Join(d.Config, "config.json")); got != "old config" {
+		t.Errorf("config.json = %q", got)
+	}
+	if got := read(filepath.Join(d.Config, "templates", "en", "prompt.tmpl")); got != "prompt" {
+		t.Errorf("templates not moved: %q", got)
+	}
+	if got := read(filepath.Join(d.State, "app-2024-03-11T14-05-00.log.gz")); got != "rotated" {
+		t.Errorf("rotated log = %q", got)
+	}
+	if got := read(filepath.Join(d.Data, "history.jsonl")); got != "new history" {
+		t.Errorf("existing history overwritten: %q", got)
+	}
+	if got := read(filepath.Join(d.ExeDir, "history.jsonl")); got != "old history" {
+		t.Errorf("skipped file should stay in place: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(d.ExeDir, "config.json")); !os.IsNotExist(err) {
+		t.Error("config.json left behind")
+	}
+	if _, err := os.Stat(filepath.Join(d.ExeDir, "appblock.exe")); err != nil {
+		t.Error("unrelated file moved")
+	}
+}
+
+func TestCopyPath(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "reports")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "weekly-2024-03-11.md"), []byte("summary"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(root, "copy")
+	if err := copyPath(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "weekly-2024-03-11.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
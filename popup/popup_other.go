@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package popup
+
+import (
+	"appblock/utils"
+	"fmt"
+	"strings"
+)
+
+// Show writes the message to the log. Outside Windows APPBlock runs
+// headless, and its messages are seen in the log and the web dashboard.
+func Show(title, message string) error {
+	utils.LogInfo("[popup] %s: %s", title, strings.ReplaceAll(message, "\n", " "))
+	return nil
+}
+
+// ShowBlocked shows a notification for a blocked app with motivational message
+func ShowBlocked(appName, aiMessage string) error {
+	return Show("APPBlock - Waktu Produktif! 📚", fmt.Sprintf("Aplikasi ditutup: %s - %s", appName, aiMessage))
+}
+
+// ShowInfo shows an informational message
+func ShowInfo(title, message string) error {
+	return Show(title, message)
+}
+
+// ShowTestMessage shows a test popup
+func ShowTestMessage() error {
+	return Show("APPBlock - Test Popup", "Popup berfungsi dengan baik!")
+}
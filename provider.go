@@ -0,0 +1,211 @@
+package main
+
+import (
+	"appblock/ai"
+	"appblock/config"
+	"appblock/gemini"
+	"appblock/history"
+	"appblock/paths"
+	"appblock/utils"
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// initMessageProvider creates the configured provider, logging any problems.
+// Network-backed providers are wrapped in a prefetching message pool that is
+// already started. Returns nil when AI messages are disabled or unavailable.
+func initMessageProvider(cfg *config.Config) ai.MessageProvider {
+	if !cfg.AI.Enabled {
+		return nil
+	}
+
+	provider, err := newMessageProvider(cfg.AI)
+	if err != nil {
+		utils.LogWarning("AI provider %s: %v", providerName(cfg.AI.Provider), err)
+	}
+	if provider == nil {
+		utils.LogWarning("AI features will be disabled")
+		return nil
+	}
+
+	utils.LogInfo("AI message provider initialized (%s)", providerName(cfg.AI.Provider))
+
+	fetcher, ok := provider.(ai.MessageFetcher)
+	if !ok {
+		return provider
+	}
+
+	// Serve popups from a prefetched pool so they never wait on the network,
+	// unless the user opted in to messages written at block time
+	poolPath := filepath.Join(paths.DataDir(), "messages.json")
+	pool := ai.NewMessagePool(fetcher, cfg.AI.Personality, poolPath)
+	pool.SetLive(cfg.AI.LiveMessages)
+	pool.Start()
+
+	effective := cfg.Effective(time.Now())
+	pool.Warm(effective.Blocklist, effective.AI.Personality)
+
+	return pool
+}
+
+// promptContext adds today's block count for the app, the time left in the
+// productive window, the session goal and the last message shown to AI prompts
+func promptContext(data *ai.TemplateData, now time.Time) {
+	cfg := config.Get()
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	data.Attempts = history.CountBlocked(data.App, midnight)
+	if end, ok := cfg.WindowEndAt(now); ok {
+		data.MinutesLeft = max(int(end.Sub(now).Minutes()), 1)
+	}
+	data.Goal = cfg.AI.Goal
+	data.LastMessage = history.LastMessage(data.App)
+}
+
+// stopMessageProvider stops the background work of a provider created by initMessageProvider
+func stopMessageProvider(provider ai.MessageProvider) {
+	if breaker := providerBreaker(provider); breaker != nil {
+		breaker.OnStateChange(nil)
+	}
+	if pool, ok := provider.(*ai.MessagePool); ok {
+		pool.Stop()
+	}
+}
+
+// watchAIStatus reports the provider's circuit breaker state through update,
+// now and whenever it changes. Providers without a breaker report an empty status.
+func watchAIStatus(provider ai.MessageProvider, update func(string)) {
+	breaker := providerBreaker(provider)
+	if breaker == nil {
+		update("")
+		return
+	}
+
+	breaker.OnStateChange(func() {
+		update(aiStatusText(breaker))
+	})
+	update(aiStatusText(breaker))
+}
+
+// providerBreaker returns the circuit breaker guarding provider's API, or nil
+func providerBreaker(provider ai.MessageProvider) *ai.CircuitBreaker {
+	if reporter, ok := provider.(ai.BreakerReporter); ok {
+		return reporter.Breaker()
+	}
+	return nil
+}
+
+// aiStatusText describes the breaker state for the tray menu
+func aiStatusText(breaker *ai.CircuitBreaker) string {
+	state, until := breaker.State()
+	switch state {
+	case ai.BreakerOpen:
+		return fmt.Sprintf("AI: paused until %s (offline messages)", until.Format("15:04"))
+	case ai.BreakerHalfOpen:
+		return "AI: retrying"
+	default:
+		return "AI: OK"
+	}
+}
+
+// testAPIKey sends a short request to the configured provider to check that
+// its API key (if it needs one) is set and accepted
+func testAPIKey(cfg config.AIConfig) error {
+	keyName := cfg.APIKeyName()
+	if keyName != "" && ai.LookupAPIKey(keyName) == "" {
+		return fmt.Errorf("%s not set", keyName)
+	}
+
+	completer := newCompleter(cfg)
+	if completer == nil {
+		return fmt.Errorf("AI provider %s does not use an API", providerName(cfg.Provider))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*ai.DefaultTimeout)
+	defer cancel()
+
+	_, err := completer.Complete(ctx, "Reply with the single word OK.")
+	return err
+}
+
+// newCompleter creates a client for free-form requests to the configured
+// model, or nil for providers without one. Every client retries temporary
+// failures and pauses behind a circuit breaker; the Gemini client does so
+// itself.
+func newCompleter(cfg config.AIConfig) ai.Completer {
+	var completer ai.Completer
+	switch cfg.Provider {
+	case "", ai.ProviderGemini:
+		client, _ := gemini.NewClient(cfg.Model, cfg.Personality)
+		client.SetBaseURL(cfg.Endpoint)
+		return client
+	case ai.ProviderOpenAI:
+		completer = ai.NewOpenAIClient(cfg.Endpoint, cfg.APIKeyName(), cfg.Model)
+	case ai.ProviderOllama:
+		completer = ai.NewOllamaClient(cfg.Endpoint, cfg.Model)
+	case ai.ProviderLlamaCpp:
+		completer = ai.NewLlamaCppClient(cfg.Endpoint)
+	default:
+		return nil
+	}
+	return ai.NewRetryingCompleter(completer, ai.DefaultRetryPolicy)
+}
+
+// classifierCompleter returns the model used to classify unknown processes,
+// or nil when classification is disabled
+func classifierCompleter(cfg config.AIConfig) ai.Completer {
+	if !cfg.Enabled || !cfg.ClassifyUnknown {
+		return nil
+	}
+	return newCompleter(cfg)
+}
+
+// reportCompleter returns the model that writes weekly summaries, or nil to
+// use the offline summary when AI is disabled or its API key is missing
+func reportCompleter(cfg config.AIConfig) ai.Completer {
+	if !cfg.Enabled {
+		return nil
+	}
+	if name := cfg.APIKeyName(); name != "" && ai.LookupAPIKey(name) == "" {
+		return nil
+	}
+	return newCompleter(cfg)
+}
+
+// providerName returns the provider name, resolving the default
+func providerName(provider string) string {
+	if provider == "" {
+		return ai.ProviderGemini
+	}
+	return provider
+}
+
+// newMessageProvider creates the message provider selected in the AI config.
+// A provider is returned even with an error when it can still serve default messages.
+func newMessageProvider(cfg config.AIConfig) (ai.MessageProvider, error) {
+	switch cfg.Provider {
+	case "", ai.ProviderGemini:
+		client, err := gemini.NewClient(cfg.Model, cfg.Personality)
+		client.SetBaseURL(cfg.Endpoint)
+		return client, err
+
+	case ai.ProviderOpenAI:
+		keyName := cfg.APIKeyName()
+		client := ai.NewMessageClient(newCompleter(cfg), cfg.Personality)
+		if ai.LookupAPIKey(keyName) == "" && (cfg.Endpoint == "" || cfg.Endpoint == ai.DefaultOpenAIEndpoint) {
+			return client, fmt.Errorf("%s not set - requests to OpenAI will fail and default messages will be used", keyName)
+		}
+		return client, nil
+
+	case ai.ProviderOllama, ai.ProviderLlamaCpp:
+		return ai.NewMessageClient(newCompleter(cfg), cfg.Personality), nil
+
+	case ai.ProviderTemplate:
+		return ai.NewTemplateProvider(cfg.Personality), nil
+
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+	}
+}
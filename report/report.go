@@ -0,0 +1,209 @@
+// Package report writes weekly coaching summaries of blocked apps, built
+// from the block history, to the reports directory
+package report
+
+import (
+	"appblock/ai"
+	"appblock/config"
+	"appblock/history"
+	"appblock/utils"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits on the patterns included in a summary
+const (
+	maxApps  = 5
+	maxPeaks = 3
+)
+
+// Report is a generated weekly summary
+type Report struct {
+	Path    string // File the summary was saved to
+	Summary string
+	Blocked int  // Block attempts during the week
+	Offline bool // True when the summary came from the offline template
+}
+
+var (
+	reportsDir string
+	mu         sync.Mutex
+)
+
+// Init sets the directory reports are saved in (dir/reports)
+func Init(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	reportsDir = filepath.Join(dir, "reports")
+}
+
+// Dir returns the reports directory
+func Dir() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return reportsDir
+}
+
+// WeekStart returns midnight on the Monday of t's week
+func WeekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// LastWeek returns the start of the last complete week before now
+func LastWeek(now time.Time) time.Time {
+	return WeekStart(now).AddDate(0, 0, -7)
+}
+
+// Path returns the file of the weekly report for the week starting at start
+func Path(start time.Time) string {
+	return filepath.Join(Dir(), "weekly-"+start.Format("2006-01-02")+".md")
+}
+
+// Exists reports whether the weekly report for the week starting at start was saved
+func Exists(start time.Time) bool {
+	_, err := os.Stat(Path(start))
+	return err == nil
+}
+
+// Collect computes the patterns of the week starting at start from events,
+// which should also cover the week before for the comparison
+func Collect(events []history.Event, cfg *config.Config, start time.Time) ai.WeeklyData {
+	end := start.AddDate(0, 0, 7)
+	previous := start.AddDate(0, 0, -7)
+
+	data := ai.WeeklyData{
+		Personality: cfg.AI.Personality,
+		Goal:        cfg.AI.Goal,
+		From:        start.Format("2006-01-02"),
+		To:          end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Schedule:    describeSchedule(cfg),
+	}
+
+	type slot struct {
+		day  time.Weekday
+		hour int
+	}
+	apps := make(map[string]int)
+	names := make(map[string]string) // lower-case name to the first spelling seen
+	slots := make(map[slot]int)
+	var days [7]int
+
+	for _, e := range events {
+		if e.Kind != history.KindBlocked {
+			continue
+		}
+		if !e.Time.Before(previous) && e.Time.Before(start) {
+			data.PreviousTotal++
+			continue
+		}
+		if e.Time.Before(start) || !e.Time.Before(end) {
+			continue
+		}
+
+		data.Total++
+		key := strings.ToLower(e.App)
+		if _, ok := names[key]; !ok {
+			names[key] = e.App
+		}
+		apps[key]++
+		t := e.Time.In(start.Location())
+		slots[slot{t.Weekday(), t.Hour()}]++
+		days[t.Weekday()]++
+	}
+
+	for key, count := range apps {
+		data.Apps = append(data.Apps, ai.AppCount{App: names[key], Count: count})
+	}
+	sort.Slice(data.Apps, func(i, j int) bool {
+		if data.Apps[i].Count != data.Apps[j].Count {
+			return data.Apps[i].Count > data.Apps[j].Count
+		}
+		return data.Apps[i].App < data.Apps[j].App
+	})
+	if len(data.Apps) > maxApps {
+		data.Apps = data.Apps[:maxApps]
+	}
+
+	var peaks []slot
+	for s := range slots {
+		peaks = append(peaks, s)
+	}
+	// Ties go to the earlier slot in the week, starting Monday
+	order := func(s slot) int { return ((int(s.day)+6)%7)*24 + s.hour }
+	sort.Slice(peaks, func(i, j int) bool {
+		if slots[peaks[i]] != slots[peaks[j]] {
+			return slots[peaks[i]] > slots[peaks[j]]
+		}
+		return order(peaks[i]) < order(peaks[j])
+	})
+	if len(peaks) > maxPeaks {
+		peaks = peaks[:maxPeaks]
+	}
+	for _, s := range peaks {
+		data.Peaks = append(data.Peaks, ai.SlotCount{
+			Day:   ai.WeekdayName(s.day),
+			Hour:  fmt.Sprintf("%02d:00", s.hour),
+			Count: slots[s],
+		})
+	}
+
+	busiest := -1
+	for i := 0; i < 7; i++ {
+		day := (i + 1) % 7 // Monday first
+		if days[day] > 0 && (busiest < 0 || days[day] > days[busiest]) {
+			busiest = day
+		}
+	}
+	if busiest >= 0 {
+		data.BusiestDay = ai.WeekdayName(time.Weekday(busiest))
+	}
+
+	return data
+}
+
+// Generate writes the weekly summary for the week starting at start. The
+// summary is written by completer when given; without one, or when the
+// request fails, the offline summary template is used instead.
+func Generate(ctx context.Context, completer ai.Completer, cfg *config.Config, start time.Time) (Report, error) {
+	data := Collect(history.Events(start.AddDate(0, 0, -7)), cfg, start)
+
+	report := Report{Path: Path(start), Blocked: data.Total}
+	if completer != nil && data.Total > 0 {
+		summary, err := completer.Complete(ctx, ai.BuildWeeklyPrompt(data))
+		if err != nil {
+			utils.LogWarning("Failed to generate weekly summary with AI, using the offline summary: %v", err)
+		}
+		report.Summary = strings.TrimSpace(summary)
+	}
+	if report.Summary == "" {
+		report.Summary = ai.WeeklySummary(data)
+		report.Offline = true
+	}
+
+	content := fmt.Sprintf("# APPBlock %s - %s\n\n%s\n", data.From, data.To, report.Summary)
+	if err := os.MkdirAll(filepath.Dir(report.Path), 0755); err != nil {
+		return report, fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(report.Path, []byte(content), 0644); err != nil {
+		return report, fmt.Errorf("failed to save weekly report: %w", err)
+	}
+
+	utils.LogInfo("Weekly report saved to %s", report.Path)
+	return report, nil
+}
+
+// describeSchedule summarizes the main schedule, e.g. "Mon, Tue 09:00-12:00, 13:00-17:00"
+func describeSchedule(cfg *config.Config) string {
+	windows := make([]string, 0, len(cfg.TimeWindows))
+	for _, w := range cfg.TimeWindows {
+		windows = append(windows, w.Start+"-"+w.End)
+	}
+	return strings.Join(cfg.ActiveDays, ", ") + " " + strings.Join(windows, ", ")
+}
@@ -0,0 +1,137 @@
+package report
+
+import (
+	"appblock/ai"
+	"appblock/config"
+	"appblock/history"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// monday is the start of the week used by the tests
+var monday = time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+
+func blocked(app string, t time.Time) history.Event {
+	return history.Event{Time: t, Kind: history.KindBlocked, App: app}
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		ActiveDays:  []string{"Mon", "Tue"},
+		TimeWindows: []config.TimeWindow{{Start: "09:00", End: "17:00"}},
+		AI:          config.AIConfig{Personality: "programmer"},
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	sunday := time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC)
+	if got := WeekStart(sunday); !got.Equal(monday) {
+		t.Errorf("WeekStart(Sunday) = %v, want %v", got, monday)
+	}
+	if got := WeekStart(monday.Add(10 * time.Hour)); !got.Equal(monday) {
+		t.Errorf("WeekStart(Monday) = %v, want %v", got, monday)
+	}
+	if got := LastWeek(monday.AddDate(0, 0, 8)); !got.Equal(monday) {
+		t.Errorf("LastWeek = %v, want %v", got, monday)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ai.SetLanguage(ai.LanguageEnglish)
+	defer ai.SetLanguage("")
+
+	tuesday := monday.AddDate(0, 0, 1)
+	events := []history.Event{
+		blocked("steam.exe", monday.Add(-48*time.Hour)), // previous week
+		blocked("steam.exe", tuesday.Add(14*time.Hour)),
+		blocked("Steam.exe", tuesday.Add(14*time.Hour+10*time.Minute)),
+		blocked("discord.exe", tuesday.Add(14*time.Hour+20*time.Minute)),
+		blocked("discord.exe", monday.Add(10*time.Hour)),
+		blocked("steam.exe", monday.Add(16*time.Hour)),
+		{Time: monday.Add(10 * time.Hour), Kind: history.KindMessage, App: "discord.exe", Message: "Focus"},
+		blocked("steam.exe", monday.AddDate(0, 0, 7)), // next week
+	}
+
+	data := Collect(events, testConfig(), monday)
+
+	if data.Total != 5 || data.PreviousTotal != 1 {
+		t.Errorf("Total = %d, PreviousTotal = %d, want 5 and 1", data.Total, data.PreviousTotal)
+	}
+	if data.From != "2024-03-11" || data.To != "2024-03-17" {
+		t.Errorf("From/To = %s/%s", data.From, data.To)
+	}
+	if len(data.Apps) != 2 || data.Apps[0] != (ai.AppCount{App: "steam.exe", Count: 3}) {
+		t.Errorf("Apps = %+v, want steam.exe first with 3", data.Apps)
+	}
+	if len(data.Peaks) == 0 || data.Peaks[0] != (ai.SlotCount{Day: "Tuesday", Hour: "14:00", Count: 3}) {
+		t.Errorf("Peaks = %+v, want Tuesday 14:00 first with 3", data.Peaks)
+	}
+	if data.BusiestDay != "Tuesday" {
+		t.Errorf("BusiestDay = %q, want Tuesday", data.BusiestDay)
+	}
+	if data.Schedule != "Mon, Tue 09:00-17:00" {
+		t.Errorf("Schedule = %q", data.Schedule)
+	}
+
+	summary := ai.WeeklySummary(data)
+	for _, want := range []string{"5 times", "steam.exe: 3x", "14:00 on Tuesdays"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("offline summary missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+// fakeCompleter returns a fixed reply or error
+type fakeCompleter struct {
+	reply  string
+	err    error
+	prompt string
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	f.prompt = prompt
+	return f.reply, f.err
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	line, _ := json.Marshal(blocked("steam.exe", time.Now().Add(-time.Hour)))
+	if err := os.WriteFile(filepath.Join(dir, "history.jsonl"), append(line, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	Init(dir)
+	start := WeekStart(time.Now())
+
+	completer := &fakeCompleter{reply: "  Most attempts at 14:00.  "}
+	report, err := Generate(context.Background(), completer, testConfig(), start)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if report.Offline || report.Summary != "Most attempts at 14:00." {
+		t.Errorf("report = %+v, want the AI summary", report)
+	}
+	if !strings.Contains(completer.prompt, "steam.exe") {
+		t.Errorf("prompt does not mention the blocked app:\n%s", completer.prompt)
+	}
+	if !Exists(start) || filepath.Dir(report.Path) != filepath.Join(dir, "reports") {
+		t.Errorf("report not saved in the reports directory: %s", report.Path)
+	}
+
+	// Without a working model the offline summary is saved instead
+	report, err = Generate(context.Background(), &fakeCompleter{err: context.DeadlineExceeded}, testConfig(), start)
+	if err != nil {
+		t.Fatalf("Generate offline: %v", err)
+	}
+	saved, _ := os.ReadFile(report.Path)
+	if !report.Offline || !strings.Contains(string(saved), report.Summary) || report.Summary == "" {
+		t.Errorf("offline report = %+v, saved:\n%s", report, saved)
+	}
+}
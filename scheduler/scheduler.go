@@ -2,19 +2,48 @@ package scheduler
 
 import (
 	"appblock/config"
+	"appblock/events"
+	"appblock/metrics"
 	"appblock/utils"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// transitionHorizon is how far ahead the next transition is looked for
+const transitionHorizon = 8 * 24 * time.Hour
+
+// Calendar provides focus blocks that count as productive time in addition
+// to the time windows
+type Calendar interface {
+	// Contains reports whether t falls in a focus block
+	Contains(t time.Time) bool
+	// Boundaries returns the starts and ends of focus blocks in (from, to]
+	Boundaries(from, to time.Time) []time.Time
+}
+
+// productiveGauge exposes the productive state to the metrics endpoint
+var productiveGauge = metrics.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "appblock_productive",
+	Help: "1 during productive time (blocking active), otherwise 0",
+}))
+
 // Scheduler manages productive time checking
 type Scheduler struct {
-	config         *config.Config
-	isProductive   bool
-	mu             sync.RWMutex
-	ticker         *time.Ticker
-	stopChan       chan bool
-	statusCallback func(bool)
+	config        *config.Config
+	calendar      Calendar
+	isProductive  bool
+	activeProfile string
+	mu            sync.RWMutex
+	ticker        *time.Ticker
+	transition    *time.Timer // Fires at the next start or end of productive time
+	transitionAt  time.Time
+	warning       *time.Timer // Fires warn_minutes_before the next start
+	warnedFor     time.Time   // Start of productive time last warned about
+	stopped       bool
+	stopChan      chan bool
 }
 
 // NewScheduler creates a new scheduler instance
@@ -33,13 +62,14 @@ func (s *Scheduler) Start() {
 	
 	// Log initial status with current time
 	currentTime := time.Now().Format("15:04")
-	if s.isProductive {
+	if s.IsProductive() {
 		utils.LogInfo("Starting in productive time (current: %s) - blocking active", currentTime)
 	} else {
 		utils.LogInfo("Starting outside productive time (current: %s) - blocking idle", currentTime)
 	}
 	
-	// Create ticker for periodic checks (every 30 seconds)
+	// Transitions are caught exactly by a timer; the ticker picks up profile
+	// switches and clock changes such as resuming from sleep
 	s.ticker = time.NewTicker(30 * time.Second)
 	
 	utils.LogInfo("Scheduler started with 30s check interval")
@@ -60,6 +90,11 @@ func (s *Scheduler) Start() {
 
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.stopTimersLocked()
+	s.mu.Unlock()
+
 	if s.ticker != nil {
 		s.stopChan <- true
 	}
@@ -70,12 +105,22 @@ func (s *Scheduler) checkProductiveTime() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
+	now := time.Now()
 	wasProductive := s.isProductive
-	s.isProductive = s.config.IsProductiveTime()
+	s.isProductive = s.productiveAtLocked(now)
+	productiveGauge.Set(metrics.BoolValue(s.isProductive))
 	
-	// Debug: Always log current check result
-	currentTime := time.Now().Format("15:04")
-	utils.LogInfo("Productive time check at %s: enabled=%v, isProductive=%v", currentTime, s.config.Enabled, s.isProductive)
+	currentTime := now.Format("15:04")
+	utils.LogDebug("Productive time check at %s: enabled=%v, isProductive=%v", currentTime, s.config.Enabled, s.isProductive)
+	
+	// Track profile switches (manual or schedule-driven)
+	previousProfile := s.activeProfile
+	s.activeProfile = s.config.ProfileNameAt(now)
+	if previousProfile != s.activeProfile {
+		utils.LogInfo("Active profile is now %q", s.activeProfile)
+		
+		events.Publish(events.ProfileChanged{Time: now, Profile: s.activeProfile})
+	}
 	
 	// Log state changes with timestamp
 	if wasProductive != s.isProductive {
@@ -85,11 +130,154 @@ func (s *Scheduler) checkProductiveTime() {
 			utils.LogInfo("Left productive time at %s - blocking now idle", currentTime)
 		}
 		
-		// Notify callback if set
-		if s.statusCallback != nil {
-			s.statusCallback(s.isProductive)
+		if s.isProductive {
+			events.Publish(events.ProductiveStarted{Time: now, Profile: s.activeProfile})
+		} else {
+			events.Publish(events.ProductiveEnded{Time: now, Profile: s.activeProfile})
+		}
+	}
+
+	s.scheduleTransitionLocked(now)
+}
+
+// scheduleTransitionLocked sets the timer to check again at the next
+// transition, and the warning timer when productive time starts next.
+// Callers must hold mu.
+func (s *Scheduler) scheduleTransitionLocked(now time.Time) {
+	if s.stopped {
+		return
+	}
+
+	next, ok := s.nextTransitionLocked(now)
+	if ok && s.transition != nil && next.Equal(s.transitionAt) {
+		return // Already scheduled
+	}
+	s.stopTimersLocked()
+	if !ok {
+		return
+	}
+
+	// A fired timer is cleared, so the next check always schedules a new one
+	var timer *time.Timer
+	timer = time.AfterFunc(next.Sub(now), func() {
+		s.mu.Lock()
+		if s.transition == timer {
+			s.transition = nil
+		}
+		s.mu.Unlock()
+		s.checkProductiveTime()
+	})
+	s.transition = timer
+	s.transitionAt = next
+	utils.LogDebug("Next transition at %s", next.Format("Mon 15:04"))
+
+	if s.isProductive || s.config.WarnMinutesBefore <= 0 || s.warnedFor.Equal(next) {
+		return
+	}
+	// Started or reconfigured within the warning period: warn right away
+	warnIn := next.Add(-time.Duration(s.config.WarnMinutesBefore) * time.Minute).Sub(now)
+	if warnIn < 0 {
+		warnIn = 0
+	}
+	s.warning = time.AfterFunc(warnIn, func() { s.warnUpcoming(next) })
+}
+
+// stopTimersLocked stops the transition and warning timers. Callers must hold mu.
+func (s *Scheduler) stopTimersLocked() {
+	if s.transition != nil {
+		s.transition.Stop()
+		s.transition = nil
+	}
+	if s.warning != nil {
+		s.warning.Stop()
+		s.warning = nil
+	}
+}
+
+// warnUpcoming publishes that productive time starts at start, once per start
+func (s *Scheduler) warnUpcoming(start time.Time) {
+	s.mu.Lock()
+	if s.stopped || s.warnedFor.Equal(start) || s.isProductive {
+		s.mu.Unlock()
+		return
+	}
+	s.warnedFor = start
+	s.mu.Unlock()
+
+	utils.LogInfo("Productive time starts at %s", start.Format("15:04"))
+	events.Publish(events.ProductiveUpcoming{Time: time.Now(), Start: start})
+}
+
+// NextTransition returns when productive time next starts (while idle) or
+// ends (while blocking). ok is false when that never happens, e.g. blocking
+// is disabled or no time window is set.
+func (s *Scheduler) NextTransition() (next time.Time, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextTransitionLocked(time.Now())
+}
+
+// IsProductiveAt reports whether t falls in productive time, from the time
+// windows or a calendar focus block
+func (s *Scheduler) IsProductiveAt(t time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.productiveAtLocked(t)
+}
+
+// NextTransitionAt returns when productive time next starts or ends after now
+func (s *Scheduler) NextTransitionAt(now time.Time) (next time.Time, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextTransitionLocked(now)
+}
+
+// productiveAtLocked combines the time windows and the calendar. Callers
+// must hold mu.
+func (s *Scheduler) productiveAtLocked(t time.Time) bool {
+	if s.config.IsProductiveAt(t) {
+		return true
+	}
+	return s.config.Enabled && s.calendar != nil && s.calendar.Contains(t)
+}
+
+// nextTransitionLocked returns the first time after now at which the
+// combined state changes: a time window change or a focus block boundary
+// that is not covered by the other. Callers must hold mu.
+func (s *Scheduler) nextTransitionLocked(now time.Time) (next time.Time, ok bool) {
+	if s.calendar == nil {
+		return s.config.NextTransitionAt(now)
+	}
+	if !s.config.Enabled {
+		return time.Time{}, false
+	}
+
+	horizon := now.Add(transitionHorizon)
+	candidates := s.calendar.Boundaries(now, horizon)
+	for t := now; ; {
+		t, ok = s.config.NextTransitionAt(t)
+		if !ok || t.After(horizon) {
+			break
 		}
+		candidates = append(candidates, t)
 	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	productive := s.productiveAtLocked(now)
+	for _, t := range candidates {
+		if s.productiveAtLocked(t) != productive {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SetCalendar adds the focus blocks of cal to productive time. Call
+// ForceCheck when the blocks change.
+func (s *Scheduler) SetCalendar(cal Calendar) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calendar = cal
 }
 
 // IsProductive returns whether we're currently in productive time
@@ -99,18 +287,29 @@ func (s *Scheduler) IsProductive() bool {
 	return s.isProductive
 }
 
-// SetStatusCallback sets a callback for status changes
-func (s *Scheduler) SetStatusCallback(callback func(bool)) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.statusCallback = callback
+// ActiveProfile returns the name of the profile currently in effect
+func (s *Scheduler) ActiveProfile() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeProfile
 }
 
 // UpdateConfig updates the scheduler configuration
 func (s *Scheduler) UpdateConfig(cfg *config.Config) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	// Turning blocking off during productive time lifts it early
+	now := time.Now()
+	if s.isProductive && s.config.Enabled && !cfg.Enabled {
+		until, _ := s.config.WindowEndAt(now)
+		events.Publish(events.OverrideGranted{Time: now, Until: until, Reason: "blocking disabled"})
+	}
+
 	s.config = cfg
+	// Reschedule, as the warning period may have changed
+	s.stopTimersLocked()
+	s.scheduleTransitionLocked(now)
 	utils.LogInfo("Scheduler config updated")
 }
 
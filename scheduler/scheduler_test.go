@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"appblock/config"
+	"testing"
+	"time"
+)
+
+// fakeCalendar holds focus blocks as start/end pairs
+type fakeCalendar [][2]time.Time
+
+func (f fakeCalendar) Contains(t time.Time) bool {
+	for _, b := range f {
+		if !t.Before(b[0]) && t.Before(b[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fakeCalendar) Boundaries(from, to time.Time) []time.Time {
+	var times []time.Time
+	for _, b := range f {
+		for _, t := range b {
+			if t.After(from) && !t.After(to) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// at returns a time on Monday 11 March 2024
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 3, 11, hour, minute, 0, 0, time.Local)
+}
+
+func newTestScheduler(enabled bool) *Scheduler {
+	return NewScheduler(&config.Config{
+		Enabled:     enabled,
+		ActiveDays:  []string{"Mon", "Tue", "Wed", "Thu", "Fri"},
+		TimeWindows: []config.TimeWindow{{Start: "09:00", End: "12:00"}},
+	})
+}
+
+func TestCalendarMerge(t *testing.T) {
+	s := newTestScheduler(true)
+	if next, ok := s.NextTransitionAt(at(10, 0)); !ok || !next.Equal(at(12, 1)) {
+		t.Fatalf("without calendar: next = %v, %v", next, ok)
+	}
+
+	s.SetCalendar(fakeCalendar{
+		{at(11, 30), at(13, 0)}, // Runs past the end of the window
+		{at(15, 0), at(16, 0)},
+	})
+
+	for _, tt := range []struct {
+		now        time.Time
+		productive bool
+		next       time.Time
+	}{
+		{at(10, 0), true, at(13, 0)},
+		{at(12, 30), true, at(13, 0)},
+		{at(13, 30), false, at(15, 0)},
+		{at(15, 30), true, at(16, 0)},
+		{at(16, 0), false, time.Date(2024, 3, 12, 9, 0, 0, 0, time.Local)},
+	} {
+		if got := s.IsProductiveAt(tt.now); got != tt.productive {
+			t.Errorf("%s: productive = %v, want %v", tt.now.Format("15:04"), got, tt.productive)
+		}
+		if next, ok := s.NextTransitionAt(tt.now); !ok || !next.Equal(tt.next) {
+			t.Errorf("%s: next = %v, %v, want %v", tt.now.Format("15:04"), next, ok, tt.next)
+		}
+	}
+}
+
+func TestCalendarDisabled(t *testing.T) {
+	s := newTestScheduler(false)
+	s.SetCalendar(fakeCalendar{{at(15, 0), at(16, 0)}})
+
+	if s.IsProductiveAt(at(15, 30)) {
+		t.Error("focus block is productive while blocking is disabled")
+	}
+	if next, ok := s.NextTransitionAt(at(14, 0)); ok {
+		t.Errorf("next = %v while blocking is disabled", next)
+	}
+}
+
+// on returns a time in March 2024; 11 March is a Monday
+func on(day, hour, minute int) time.Time {
+	return time.Date(2024, 3, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestNextTransition(t *testing.T) {
+	weekdays := []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+	office := config.Config{Enabled: true, ActiveDays: weekdays, TimeWindows: []config.TimeWindow{{Start: "09:00", End: "12:00"}}}
+	lateShift := config.Config{Enabled: true, ActiveDays: weekdays, TimeWindows: []config.TimeWindow{{Start: "20:00", End: "23:59"}}}
+	overnight := config.Config{Enabled: true, ActiveDays: weekdays, TimeWindows: []config.TimeWindow{
+		{Start: "22:00", End: "23:59"}, {Start: "00:00", End: "02:00"},
+	}}
+	evening := office
+	evening.Profiles = []config.Profile{{Name: "Evening", ActiveDays: []string{"Mon", "Sat"},
+		TimeWindows: []config.TimeWindow{{Start: "19:00", End: "21:00"}}, AutoSwitch: true}}
+	disabled := office
+	disabled.Enabled = false
+	calendarOnly := config.Config{Enabled: true}
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		calendar fakeCalendar // nil for no calendar
+		now      time.Time
+		want     time.Time // Zero when the state never changes
+	}{
+		{"end minute is inclusive", office, nil, on(11, 12, 0), on(11, 12, 1)},
+		{"window ending 23:59", lateShift, nil, on(11, 21, 0), on(12, 0, 0)},
+		{"overnight", overnight, nil, on(11, 23, 0), on(12, 2, 1)},
+		{"weekend", office, nil, on(15, 12, 30), on(18, 9, 0)},
+		{"disabled", disabled, nil, on(11, 8, 0), time.Time{}},
+		{"disabled with calendar", disabled, fakeCalendar{{on(11, 13, 0), on(11, 14, 0)}}, on(11, 8, 0), time.Time{}},
+		{"block inside the window", office, fakeCalendar{{on(11, 10, 0), on(11, 11, 0)}}, on(11, 9, 30), on(11, 12, 1)},
+		{"block after the inclusive end", office, fakeCalendar{{on(11, 12, 1), on(11, 13, 0)}}, on(11, 10, 0), on(11, 13, 0)},
+		{"block across midnight", calendarOnly, fakeCalendar{{on(11, 23, 0), on(12, 1, 0)}}, on(11, 23, 30), on(12, 1, 0)},
+		{"block joins an overnight window", overnight, fakeCalendar{{on(12, 2, 1), on(12, 3, 0)}}, on(11, 23, 0), on(12, 3, 0)},
+		{"block on the weekend", office, fakeCalendar{{on(16, 10, 0), on(16, 11, 0)}}, on(15, 13, 0), on(16, 10, 0)},
+		{"block beyond the horizon", calendarOnly, fakeCalendar{{on(25, 10, 0), on(25, 11, 0)}}, on(11, 10, 0), time.Time{}},
+		{"auto-switch profile starts", evening, nil, on(16, 10, 0), on(16, 19, 0)},
+		{"block after an auto-switch profile", evening, fakeCalendar{{on(11, 21, 1), on(11, 22, 0)}}, on(11, 20, 0), on(11, 22, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			s := NewScheduler(&cfg)
+			if tt.calendar != nil {
+				s.SetCalendar(tt.calendar)
+			}
+
+			next, ok := s.NextTransitionAt(tt.now)
+			if ok != !tt.want.IsZero() || ok && !next.Equal(tt.want) {
+				t.Errorf("NextTransitionAt(%s) = %v, %v, want %v", tt.now.Format("Mon 15:04"), next, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTransitionDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := NewScheduler(&config.Config{
+		Enabled:     true,
+		ActiveDays:  []string{"Sun"},
+		TimeWindows: []config.TimeWindow{{Start: "01:00", End: "04:00"}},
+	})
+	// The clocks skip from 02:00 to 03:00 on 31 March 2024
+	end := time.Date(2024, 3, 31, 4, 1, 0, 0, berlin)
+	s.SetCalendar(fakeCalendar{{end, end.Add(time.Hour)}})
+
+	now := time.Date(2024, 3, 31, 1, 30, 0, 0, berlin)
+	if next, ok := s.NextTransitionAt(now); !ok || !next.Equal(end.Add(time.Hour)) {
+		t.Errorf("next = %v, %v, want %v", next, ok, end.Add(time.Hour))
+	}
+	if next, ok := s.NextTransitionAt(end.Add(time.Hour)); !ok || !next.Equal(time.Date(2024, 4, 7, 1, 0, 0, 0, berlin)) {
+		t.Errorf("after the block: next = %v, %v", next, ok)
+	}
+}
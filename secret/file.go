@@ -0,0 +1,276 @@
+package secret
+
+import (
+	"appblock/utils"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps secrets in an AES-GCM encrypted file, with the key in
+// secrets.key next to it. On Windows the key is itself encrypted with DPAPI,
+// so only the same Windows account can read the secrets. Elsewhere the key
+// file is plain and only readable by the user: the encryption keeps keys
+// out of plaintext config files and backups, but it is obfuscation against
+// anyone who can read the user's files.
+type FileStore struct {
+	path    string
+	keyPath string
+	mu      sync.Mutex
+}
+
+// NewFileStore creates a file store in dir (secrets.enc and secrets.key)
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		path:    filepath.Join(dir, "secrets.enc"),
+		keyPath: filepath.Join(dir, "secrets.key"),
+	}
+}
+
+// Get returns the secret stored under name, or ErrNotFound
+func (f *FileStore) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores value under name
+func (f *FileStore) Set(name, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return f.save(secrets)
+}
+
+// Delete removes the secret stored under name
+func (f *FileStore) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return f.save(secrets)
+}
+
+// Backend describes the store
+func (f *FileStore) Backend() string {
+	return "encrypted file " + f.path
+}
+
+// load decrypts the secrets file. A missing file holds no secrets.
+func (f *FileStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	gcm, err := f.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decrypt(gcm, data)
+	if err != nil {
+		// A rotation stopped after saving the secrets under the new key
+		pending := f.keyPath + ".new"
+		key, keyErr := readKey(pending)
+		if keyErr != nil {
+			return nil, err
+		}
+		if gcm, keyErr = newCipher(key); keyErr != nil {
+			return nil, err
+		}
+		if plaintext, keyErr = decrypt(gcm, data); keyErr != nil {
+			return nil, err
+		}
+		if err := os.Rename(pending, f.keyPath); err != nil {
+			return nil, fmt.Errorf("failed to replace secrets key: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, errors.New("secrets file is corrupt")
+	}
+	return secrets, nil
+}
+
+// decrypt opens data sealed by save
+func decrypt(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("secrets file is corrupt")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt secrets file - was secrets.key replaced?")
+	}
+	return plaintext, nil
+}
+
+// save encrypts and writes the secrets file
+func (f *FileStore) save(secrets map[string]string) error {
+	gcm, err := f.cipher(true)
+	if err != nil {
+		return err
+	}
+	return f.write(secrets, gcm)
+}
+
+// saveWith writes the secrets file encrypted with key
+func (f *FileStore) saveWith(secrets map[string]string, key []byte) error {
+	gcm, err := newCipher(key)
+	if err != nil {
+		return err
+	}
+	return f.write(secrets, gcm)
+}
+
+// write encrypts the secrets with gcm and writes them
+func (f *FileStore) write(secrets map[string]string, gcm cipher.AEAD) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := gcm.Seal(nonce, nonce, plaintext, nil)
+	if err := utils.WriteFileAtomic(f.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+// RotateKey re-encrypts the secrets under a new key. The new key is
+// written to secrets.key.new first and only replaces secrets.key after the
+// secrets were saved with it; load finishes an interrupted rotation.
+func (f *FileStore) RotateKey() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	pending := f.keyPath + ".new"
+	if err := writeKey(pending, key); err != nil {
+		return err
+	}
+	if err := f.saveWith(secrets, key); err != nil {
+		os.Remove(pending)
+		return err
+	}
+	if err := os.Rename(pending, f.keyPath); err != nil {
+		return fmt.Errorf("failed to replace secrets key: %w", err)
+	}
+	return nil
+}
+
+// cipher returns the AES-GCM cipher for the store, creating the key file if
+// create is true and it does not exist yet
+func (f *FileStore) cipher(create bool) (cipher.AEAD, error) {
+	key, err := readKey(f.keyPath)
+	if os.IsNotExist(err) && create {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := writeKey(f.keyPath, key); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return newCipher(key)
+}
+
+// newCipher returns the AES-GCM cipher for a key
+func newCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("secrets key is corrupt")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readKey reads and unprotects a key file. A plain key written before keys
+// were protected is protected in place.
+func readKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+
+	if len(data) == 32 {
+		if keyProtected {
+			if err := writeKey(path, data); err != nil {
+				utils.LogWarning("Failed to protect secrets key: %v", err)
+			}
+		}
+		return data, nil
+	}
+
+	key, err := unprotectKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unprotect secrets key: %w", err)
+	}
+	return key, nil
+}
+
+// writeKey protects a key and writes it to path
+func writeKey(path string, key []byte) error {
+	data, err := protectKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to protect secrets key: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets key: %w", err)
+	}
+	return nil
+}
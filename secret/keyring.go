@@ -0,0 +1,62 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/zalando/go-keyring"
+)
+
+// service groups APPBlock's entries in the OS keyring
+const service = "APPBlock"
+
+// keyringStore keeps secrets in the OS keyring
+type keyringStore struct{}
+
+func (keyringStore) Get(name string) (string, error) {
+	value, err := keyring.Get(service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from keyring: %w", name, err)
+	}
+	return value, nil
+}
+
+func (keyringStore) Set(name, value string) error {
+	if err := keyring.Set(service, name, value); err != nil {
+		return fmt.Errorf("failed to store %s in keyring: %w", name, err)
+	}
+	return nil
+}
+
+func (keyringStore) Delete(name string) error {
+	err := keyring.Delete(service, name)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete %s from keyring: %w", name, err)
+	}
+	return nil
+}
+
+func (keyringStore) Backend() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "Windows Credential Manager"
+	case "darwin":
+		return "macOS Keychain"
+	default:
+		return "Secret Service keyring"
+	}
+}
+
+// probeKeyring checks that the keyring answers, e.g. that a Secret Service
+// daemon is running on Linux
+func probeKeyring() error {
+	_, err := keyring.Get(service, "appblock-probe")
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package secret
+
+// keyProtected reports whether key files are encrypted for the user account.
+// Outside Windows there is no per-user OS encryption to use; the key file
+// relies on its 0600 permissions.
+const keyProtected = false
+
+func protectKey(key []byte) ([]byte, error) {
+	return key, nil
+}
+
+func unprotectKey(data []byte) ([]byte, error) {
+	return data, nil
+}
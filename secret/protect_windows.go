@@ -0,0 +1,45 @@
+//go:build windows
+// +build windows
+
+package secret
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// keyProtected reports whether key files are encrypted for the user account
+const keyProtected = true
+
+// protectKey encrypts the file store key with DPAPI for the current
+// Windows account
+func protectKey(key []byte) ([]byte, error) {
+	return dpapi(key, true)
+}
+
+// unprotectKey decrypts a key encrypted by protectKey
+func unprotectKey(data []byte) ([]byte, error) {
+	return dpapi(data, false)
+}
+
+func dpapi(data []byte, protect bool) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, windows.ERROR_INVALID_DATA
+	}
+	in := windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+	var out windows.DataBlob
+
+	var err error
+	if protect {
+		err = windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	} else {
+		err = windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
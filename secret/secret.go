@@ -0,0 +1,167 @@
+// Package secret stores API keys in the OS keyring (Credential Manager on
+// Windows, Secret Service on Linux), falling back to an encrypted file when
+// no keyring is available. Secret values must never be logged.
+package secret
+
+import (
+	"appblock/utils"
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned when no secret is stored under a name
+var ErrNotFound = errors.New("secret not found")
+
+// Store keeps named secrets
+type Store interface {
+	// Get returns the secret stored under name, or ErrNotFound
+	Get(name string) (string, error)
+	// Set stores value under name, replacing any previous value
+	Set(name, value string) error
+	// Delete removes the secret stored under name. Deleting a missing secret is not an error.
+	Delete(name string) error
+	// Backend describes where secrets are kept, for display
+	Backend() string
+}
+
+var (
+	store     Store
+	fileStore *FileStore // The encrypted file, preferred or fallback
+	mu        sync.RWMutex
+)
+
+// Init selects the secret store: the OS keyring when it is usable, otherwise
+// an encrypted file in dir. Secrets missing from the selected store are
+// looked up in the other one, and moved over when found there, so keys
+// stored while the keyring was unavailable are not lost once it returns.
+func Init(dir string) {
+	file := NewFileStore(dir)
+	var s Store = &fallbackStore{preferred: keyringStore{}, others: []Store{file}}
+	if err := probeKeyring(); err != nil {
+		utils.LogWarning("OS keyring unavailable (%v) - storing secrets in an encrypted file", err)
+		s = file
+	}
+
+	mu.Lock()
+	store = s
+	fileStore = file
+	mu.Unlock()
+}
+
+// Default returns the store selected by Init, or nil before Init
+func Default() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return store
+}
+
+// Get returns the secret stored under name in the default store, or
+// ErrNotFound if there is none or Init was not called
+func Get(name string) (string, error) {
+	s := Default()
+	if s == nil {
+		return "", ErrNotFound
+	}
+	return s.Get(name)
+}
+
+// Set stores a secret in the default store
+func Set(name, value string) error {
+	s := Default()
+	if s == nil {
+		return errors.New("secret store not initialized")
+	}
+	return s.Set(name, value)
+}
+
+// Delete removes a secret from the default store
+func Delete(name string) error {
+	s := Default()
+	if s == nil {
+		return errors.New("secret store not initialized")
+	}
+	return s.Delete(name)
+}
+
+// RotateKey re-encrypts the encrypted file store under a new key
+func RotateKey() error {
+	mu.RLock()
+	f := fileStore
+	mu.RUnlock()
+	if f == nil {
+		return errors.New("secret store not initialized")
+	}
+	return f.RotateKey()
+}
+
+// Backend describes the default store, or "none" before Init
+func Backend() string {
+	s := Default()
+	if s == nil {
+		return "none"
+	}
+	return s.Backend()
+}
+
+// fallbackStore writes to its preferred store and reads from the others as
+// well, moving secrets found there into the preferred store
+type fallbackStore struct {
+	preferred Store
+	others    []Store
+}
+
+func (f *fallbackStore) Get(name string) (string, error) {
+	value, err := f.preferred.Get(name)
+	if err == nil {
+		return value, nil
+	}
+
+	for _, other := range f.others {
+		found, otherErr := other.Get(name)
+		if otherErr != nil {
+			continue
+		}
+		// Only move the secret when the preferred store answered; a
+		// temporary keyring failure must not lose it
+		if errors.Is(err, ErrNotFound) {
+			if setErr := f.preferred.Set(name, found); setErr != nil {
+				utils.LogWarning("Failed to move secret %s to %s: %v", name, f.preferred.Backend(), setErr)
+			} else if delErr := other.Delete(name); delErr != nil {
+				utils.LogWarning("Moved secret %s to %s but could not remove it from %s: %v", name, f.preferred.Backend(), other.Backend(), delErr)
+			} else {
+				utils.LogInfo("Moved secret %s from %s to %s", name, other.Backend(), f.preferred.Backend())
+			}
+		}
+		return found, nil
+	}
+	return "", err
+}
+
+// Set stores the secret in the preferred store and removes older copies
+func (f *fallbackStore) Set(name, value string) error {
+	if err := f.preferred.Set(name, value); err != nil {
+		return err
+	}
+	for _, other := range f.others {
+		if err := other.Delete(name); err != nil {
+			utils.LogWarning("Failed to remove old copy of secret %s from %s: %v", name, other.Backend(), err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the secret from every store, so it does not come back
+// from a fallback
+func (f *fallbackStore) Delete(name string) error {
+	err := f.preferred.Delete(name)
+	for _, other := range f.others {
+		if otherErr := other.Delete(name); otherErr != nil && err == nil {
+			err = otherErr
+		}
+	}
+	return err
+}
+
+func (f *fallbackStore) Backend() string {
+	return f.preferred.Backend()
+}
@@ -0,0 +1,219 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFileStore(dir)
+
+	if _, err := f.Get("GEMINI_API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get from an empty store: err = %v, want ErrNotFound", err)
+	}
+	if err := f.Set("GEMINI_API_KEY", "AIza-test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("OPENAI_API_KEY", "sk-test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads what the first one wrote
+	reopened := NewFileStore(dir)
+	if value, err := reopened.Get("GEMINI_API_KEY"); err != nil || value != "AIza-test" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "secrets.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "AIza-test") || strings.Contains(string(data), "GEMINI_API_KEY") {
+		t.Error("secrets file holds plaintext")
+	}
+
+	if err := reopened.Delete("GEMINI_API_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Delete("GEMINI_API_KEY"); err != nil {
+		t.Errorf("deleting a missing secret: %v", err)
+	}
+	if _, err := f.Get("GEMINI_API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v", err)
+	}
+	if value, err := f.Get("OPENAI_API_KEY"); err != nil || value != "sk-test" {
+		t.Errorf("other secret after Delete = %q, %v", value, err)
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	tests := map[string]func(dir string) error{
+		"truncated file": func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "secrets.enc"), []byte("short"), 0600)
+		},
+		"garbage file": func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "secrets.enc"), make([]byte, 64), 0600)
+		},
+		"replaced key": func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "secrets.key"), make([]byte, 32), 0600)
+		},
+		"short key": func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "secrets.key"), []byte("key"), 0600)
+		},
+	}
+
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := NewFileStore(dir)
+			if err := f.Set("GEMINI_API_KEY", "AIza-test"); err != nil {
+				t.Fatal(err)
+			}
+			if err := corrupt(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Get("GEMINI_API_KEY"); err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("Get: err = %v, want a read error", err)
+			}
+			// A store that cannot be read is not overwritten
+			if err := f.Set("OPENAI_API_KEY", "sk-test"); err == nil {
+				t.Error("Set succeeded on an unreadable store")
+			}
+		})
+	}
+}
+
+func TestFileStoreRotateKey(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFileStore(dir)
+	if err := f.Set("GEMINI_API_KEY", "AIza-test"); err != nil {
+		t.Fatal(err)
+	}
+	oldKey, _ := os.ReadFile(filepath.Join(dir, "secrets.key"))
+
+	if err := f.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	newKey, _ := os.ReadFile(filepath.Join(dir, "secrets.key"))
+	if string(newKey) == string(oldKey) {
+		t.Error("key did not change")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secrets.key.new")); !os.IsNotExist(err) {
+		t.Errorf("pending key left behind: %v", err)
+	}
+	if value, err := NewFileStore(dir).Get("GEMINI_API_KEY"); err != nil || value != "AIza-test" {
+		t.Errorf("Get after rotation = %q, %v", value, err)
+	}
+
+	// The old key no longer opens the file
+	os.WriteFile(filepath.Join(dir, "secrets.key"), oldKey, 0600)
+	if _, err := f.Get("GEMINI_API_KEY"); err == nil {
+		t.Error("old key still decrypts the secrets")
+	}
+}
+
+func TestFileStoreInterruptedRotation(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFileStore(dir)
+	if err := f.Set("GEMINI_API_KEY", "AIza-test"); err != nil {
+		t.Fatal(err)
+	}
+	oldKey, _ := os.ReadFile(filepath.Join(dir, "secrets.key"))
+
+	// Rotate, then put the old key back as if the final rename never happened
+	if err := f.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	newKey, _ := os.ReadFile(filepath.Join(dir, "secrets.key"))
+	os.WriteFile(filepath.Join(dir, "secrets.key.new"), newKey, 0600)
+	os.WriteFile(filepath.Join(dir, "secrets.key"), oldKey, 0600)
+
+	if value, err := f.Get("GEMINI_API_KEY"); err != nil || value != "AIza-test" {
+		t.Fatalf("Get = %q, %v", value, err)
+	}
+	if key, _ := os.ReadFile(filepath.Join(dir, "secrets.key")); string(key) != string(newKey) {
+		t.Error("interrupted rotation was not finished")
+	}
+}
+
+// memoryStore is a Store in memory; fail makes every call return it
+type memoryStore struct {
+	secrets map[string]string
+	fail    error
+}
+
+func (m *memoryStore) Get(name string) (string, error) {
+	if m.fail != nil {
+		return "", m.fail
+	}
+	value, ok := m.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *memoryStore) Set(name, value string) error {
+	if m.fail != nil {
+		return m.fail
+	}
+	m.secrets[name] = value
+	return nil
+}
+
+func (m *memoryStore) Delete(name string) error {
+	if m.fail != nil {
+		return m.fail
+	}
+	delete(m.secrets, name)
+	return nil
+}
+
+func (m *memoryStore) Backend() string { return "memory" }
+
+func TestFallbackMovesSecrets(t *testing.T) {
+	keyring := &memoryStore{secrets: map[string]string{}}
+	file := &memoryStore{secrets: map[string]string{"GEMINI_API_KEY": "AIza-old"}}
+	s := &fallbackStore{preferred: keyring, others: []Store{file}}
+
+	// The keyring was down when the key was stored; it is read from the file
+	// and moved once the keyring answers
+	if value, err := s.Get("GEMINI_API_KEY"); err != nil || value != "AIza-old" {
+		t.Fatalf("Get = %q, %v", value, err)
+	}
+	if keyring.secrets["GEMINI_API_KEY"] != "AIza-old" {
+		t.Error("secret was not moved to the keyring")
+	}
+	if _, ok := file.secrets["GEMINI_API_KEY"]; ok {
+		t.Error("secret was left in the file")
+	}
+
+	// A keyring error is not a missing secret: the file copy is read but kept
+	keyring.fail = errors.New("keyring locked")
+	file.secrets["OPENAI_API_KEY"] = "sk-old"
+	if value, err := s.Get("OPENAI_API_KEY"); err != nil || value != "sk-old" {
+		t.Fatalf("Get with a failing keyring = %q, %v", value, err)
+	}
+	if _, ok := file.secrets["OPENAI_API_KEY"]; !ok {
+		t.Error("secret was removed from the file while the keyring failed")
+	}
+	if _, err := s.Get("MISSING"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing secret with a failing keyring: err = %v, want the keyring error", err)
+	}
+	keyring.fail = nil
+
+	// Delete removes every copy, so the secret does not come back
+	file.secrets["OPENAI_API_KEY"] = "sk-old"
+	keyring.secrets["OPENAI_API_KEY"] = "sk-new"
+	if err := s.Delete("OPENAI_API_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("OPENAI_API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v", err)
+	}
+}
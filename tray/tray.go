@@ -1,15 +1,21 @@
 package tray
 
 import (
+	"appblock/ai"
 	"appblock/autostart"
+	"appblock/catalog"
+	"appblock/classifier"
 	"appblock/config"
+	"appblock/events"
 	"appblock/gui"
 	"appblock/popup"
 	"appblock/utils"
 	_ "embed"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/getlantern/systray"
@@ -20,45 +26,180 @@ var iconData []byte
 
 // App holds references to application components
 type App struct {
-	config            *config.Config
-	isProductiveTime  bool
-	onToggleEnabled   func()
-	onReloadConfig    func()
-	onQuit            func()
+	config              *config.Config
+	isProductiveTime    bool
+	activeProfile       string
+	aiStatus            string
+	dashboardURL        string
+	nextTransition      func() (time.Time, bool)
+	classifier          *classifier.Classifier
+	onQuit              func()
 	openSettingsOnReady bool
-	
+	mu                  sync.Mutex
+
 	// Menu items
-	mStatus           *systray.MenuItem
-	mToggle           *systray.MenuItem
-	mSettings         *systray.MenuItem
-	mReloadConfig     *systray.MenuItem
-	mToggleAutostart  *systray.MenuItem
-	mQuit             *systray.MenuItem
+	mStatus          *systray.MenuItem
+	mAIStatus        *systray.MenuItem
+	mToggle          *systray.MenuItem
+	mSettings        *systray.MenuItem
+	mReloadConfig    *systray.MenuItem
+	mLogs            *systray.MenuItem
+	mDashboard       *systray.MenuItem
+	mProfile         *systray.MenuItem
+	profileItems     []*systray.MenuItem
+	profileNames     []string
+	mSuggestions     *systray.MenuItem
+	suggestionItems  []suggestionItem
+	suggestionNames  []string
+	mToggleAutostart *systray.MenuItem
+	mQuit            *systray.MenuItem
+}
+
+// suggestionItem is the submenu of one blocklist suggestion
+type suggestionItem struct {
+	item   *systray.MenuItem
+	block  *systray.MenuItem
+	ignore *systray.MenuItem
 }
 
-// NewApp creates a new tray app
+// NewApp creates a new tray app, which follows the scheduler through the
+// events package from then on
 func NewApp(cfg *config.Config) *App {
-	return &App{
+	a := &App{
 		config:              cfg,
 		isProductiveTime:    false,
+		activeProfile:       config.DefaultProfileName,
 		openSettingsOnReady: false,
 	}
+
+	events.Subscribe(func(e events.Event) {
+		switch e := e.(type) {
+		case events.ProductiveStarted:
+			a.UpdateProductiveStatus(true)
+		case events.ProductiveEnded:
+			a.UpdateProductiveStatus(false)
+		case events.ProfileChanged:
+			a.UpdateActiveProfile(e.Profile)
+		}
+	})
+	return a
 }
 
-// SetCallbacks sets the callback functions
-func (a *App) SetCallbacks(onToggleEnabled, onReloadConfig, onQuit func()) {
-	a.onToggleEnabled = onToggleEnabled
-	a.onReloadConfig = onReloadConfig
+// SetQuitCallback sets the function called when the user quits from the tray.
+// Config changes made from the tray reach other components through config.Subscribe.
+func (a *App) SetQuitCallback(onQuit func()) {
 	a.onQuit = onQuit
 }
 
 // UpdateProductiveStatus updates the productive time status
 func (a *App) UpdateProductiveStatus(isProductive bool) {
+	a.mu.Lock()
 	a.isProductiveTime = isProductive
+	ready := a.mStatus != nil
+	a.mu.Unlock()
+
+	if !ready {
+		return
+	}
+
 	a.updateTooltip()
 	a.updateStatusText()
 }
 
+// UpdateConfig replaces the config snapshot and refreshes the menu
+func (a *App) UpdateConfig(cfg *config.Config) {
+	a.mu.Lock()
+	a.config = cfg
+	ready := a.mStatus != nil
+	a.mu.Unlock()
+
+	// Menu items only exist once the tray is ready
+	if !ready {
+		return
+	}
+
+	a.updateToggleText()
+	a.updateAutostartText()
+	a.updateProfileItems()
+	a.updateTooltip()
+	a.updateStatusText()
+}
+
+// UpdateActiveProfile updates the profile shown in the menu and tooltip
+func (a *App) UpdateActiveProfile(name string) {
+	a.mu.Lock()
+	a.activeProfile = name
+	ready := a.mStatus != nil
+	a.mu.Unlock()
+
+	if !ready {
+		return
+	}
+
+	a.updateProfileTitle()
+	a.updateTooltip()
+}
+
+// UpdateAIStatus shows the AI provider status, e.g. while requests are paused
+// after repeated failures. An empty status hides the menu item.
+func (a *App) UpdateAIStatus(status string) {
+	a.mu.Lock()
+	a.aiStatus = status
+	ready := a.mStatus != nil
+	a.mu.Unlock()
+
+	if !ready {
+		return
+	}
+
+	a.updateAIStatusText()
+}
+
+// SetDashboardURL shows a menu item opening the web dashboard at url.
+// An empty url hides it.
+func (a *App) SetDashboardURL(url string) {
+	a.mu.Lock()
+	a.dashboardURL = url
+	ready := a.mStatus != nil
+	a.mu.Unlock()
+
+	if !ready {
+		return
+	}
+
+	a.updateDashboardItem()
+}
+
+// SetNextTransitionFunc sets the function reporting when productive time
+// next starts or ends, shown as a countdown in the tooltip and status item
+func (a *App) SetNextTransitionFunc(next func() (time.Time, bool)) {
+	a.mu.Lock()
+	a.nextTransition = next
+	a.mu.Unlock()
+}
+
+// SetClassifier sets the classifier whose blocklist suggestions are listed in the menu
+func (a *App) SetClassifier(c *classifier.Classifier) {
+	a.mu.Lock()
+	a.classifier = c
+	a.mu.Unlock()
+
+	a.UpdateSuggestions()
+}
+
+// UpdateSuggestions refreshes the blocklist suggestions in the menu
+func (a *App) UpdateSuggestions() {
+	a.mu.Lock()
+	ready := a.mStatus != nil
+	a.mu.Unlock()
+
+	if !ready {
+		return
+	}
+
+	a.updateSuggestionItems()
+}
+
 // Start starts the system tray
 func (a *App) Start() {
 	systray.Run(a.onReady, a.onExit)
@@ -76,28 +217,44 @@ func (a *App) onReady() {
 	a.updateTooltip()
 
 	// Create menu items
-	a.mStatus = systray.AddMenuItem("Status: Checking...", "Current status")
-	a.mStatus.Disable()
+	mStatus := systray.AddMenuItem("Status: Checking...", "Current status")
+	mStatus.Disable()
+	a.mAIStatus = systray.AddMenuItem("", "AI message provider status")
+	a.mAIStatus.Disable()
 
 	systray.AddSeparator()
 
 	a.mToggle = systray.AddMenuItem("Disable Blocking", "Toggle blocking on/off")
 	a.mSettings = systray.AddMenuItem("⚙️ Settings", "Open settings window")
 	a.mReloadConfig = systray.AddMenuItem("🔄 Reload Config", "Reload configuration from file")
-	
+	a.mLogs = systray.AddMenuItem("📜 Logs", "View the log and export a diagnostic bundle")
+	a.mDashboard = systray.AddMenuItem("🌐 Dashboard", "Open the web dashboard in the browser")
+	a.mProfile = systray.AddMenuItem("👤 Profile", "Switch blocking profile")
+	a.mSuggestions = systray.AddMenuItem("💡 Suggestions", "Programs the AI suggests blocking")
+
 	systray.AddSeparator()
-	
+
 	a.mToggleAutostart = systray.AddMenuItem("Enable Autostart", "Toggle autostart")
-	
+
 	systray.AddSeparator()
-	
+
 	a.mQuit = systray.AddMenuItem("Quit", "Exit APPBlock")
 
+	// Publishing mStatus marks the menu as ready for UpdateConfig/UpdateProductiveStatus
+	a.mu.Lock()
+	a.mStatus = mStatus
+	a.mu.Unlock()
+
 	// Update initial state
 	a.updateToggleText()
 	a.updateAutostartText()
+	a.updateProfileItems()
+	a.updateProfileTitle()
 	a.updateStatusText()
-	
+	a.updateAIStatusText()
+	a.updateDashboardItem()
+	a.updateSuggestionItems()
+
 	// Auto-open settings if requested (first run)
 	if a.openSettingsOnReady {
 		utils.LogInfo("Auto-opening settings window (first run)...")
@@ -111,6 +268,20 @@ func (a *App) onReady() {
 
 	// Handle menu clicks
 	go a.handleMenuClicks()
+
+	// Keep the countdown current
+	go a.refreshCountdown()
+}
+
+// refreshCountdown updates the tooltip and status item every minute
+func (a *App) refreshCountdown() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		a.updateTooltip()
+		a.updateStatusText()
+	}
 }
 
 // onExit is called when the systray is exiting
@@ -128,6 +299,10 @@ func (a *App) handleMenuClicks() {
 			a.handleSettings()
 		case <-a.mReloadConfig.ClickedCh:
 			a.handleReloadConfig()
+		case <-a.mLogs.ClickedCh:
+			a.handleLogs()
+		case <-a.mDashboard.ClickedCh:
+			a.handleDashboard()
 		case <-a.mToggleAutostart.ClickedCh:
 			a.handleToggleAutostart()
 		case <-a.mQuit.ClickedCh:
@@ -139,40 +314,115 @@ func (a *App) handleMenuClicks() {
 
 // handleToggle handles the toggle enable/disable
 func (a *App) handleToggle() {
-	if err := a.config.ToggleEnabled(); err != nil {
+	// The new config is published back to us through UpdateConfig
+	if err := config.ToggleEnabled(); err != nil {
 		utils.LogError("Failed to toggle enabled state: %v", err)
 		return
 	}
 
-	a.updateToggleText()
-	a.updateTooltip()
-
-	if a.onToggleEnabled != nil {
-		a.onToggleEnabled()
-	}
-
 	enabledText := "disabled"
-	if a.config.Enabled {
+	if a.getConfig().Enabled {
 		enabledText = "enabled"
 	}
 	utils.LogInfo("Blocking %s", enabledText)
 }
 
+// handleProfileClicks switches to the profile shown at index when its menu item is clicked
+func (a *App) handleProfileClicks(index int, item *systray.MenuItem) {
+	for range item.ClickedCh {
+		a.mu.Lock()
+		name := ""
+		if index < len(a.profileNames) {
+			name = a.profileNames[index]
+		}
+		a.mu.Unlock()
+
+		if name == "" {
+			continue
+		}
+
+		// The new config is published back to us through UpdateConfig
+		if err := config.SetActiveProfile(name); err != nil {
+			utils.LogError("Failed to switch profile: %v", err)
+			go popup.ShowInfo("Switch Profile Failed", fmt.Sprintf("Failed to switch profile:\n%v", err))
+			continue
+		}
+		utils.LogInfo("Profile %q selected from tray", name)
+	}
+}
+
+// handleSuggestionClicks approves or dismisses the suggestion shown at index
+func (a *App) handleSuggestionClicks(index int, s suggestionItem) {
+	for {
+		approve := false
+		select {
+		case <-s.block.ClickedCh:
+			approve = true
+		case <-s.ignore.ClickedCh:
+		}
+
+		a.mu.Lock()
+		c := a.classifier
+		name := ""
+		if index < len(a.suggestionNames) {
+			name = a.suggestionNames[index]
+		}
+		a.mu.Unlock()
+
+		if c == nil || name == "" {
+			continue
+		}
+
+		// The classifier reports the change back through UpdateSuggestions
+		var err error
+		if approve {
+			err = c.Approve(name)
+		} else {
+			err = c.Dismiss(name)
+		}
+		if err != nil {
+			utils.LogError("Failed to apply suggestion for %s: %v", name, err)
+			go popup.ShowInfo("Suggestion Failed", fmt.Sprintf("Failed to update %s:\n%v", name, err))
+		}
+	}
+}
+
 // handleSettings opens the settings window
 func (a *App) handleSettings() {
 	utils.LogInfo("Opening settings window...")
-	
+
 	// Run in same goroutine to prevent window issues
 	err := gui.ShowSettings(func() {
 		// On save callback - reload config
 		a.handleReloadConfig()
 	})
-	
+
 	if err != nil {
 		utils.LogError("Failed to show settings: %v", err)
 	}
 }
 
+// handleLogs opens the log viewer
+func (a *App) handleLogs() {
+	if err := gui.ShowLogViewer(); err != nil {
+		utils.LogError("Failed to show log viewer: %v", err)
+	}
+}
+
+// handleDashboard opens the web dashboard in the default browser
+func (a *App) handleDashboard() {
+	a.mu.Lock()
+	url := a.dashboardURL
+	a.mu.Unlock()
+
+	if url == "" {
+		return
+	}
+	if err := exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start(); err != nil {
+		utils.LogError("Failed to open dashboard: %v", err)
+	}
+}
+
 // SetOpenSettingsOnReady sets flag to open settings when tray is ready
 func (a *App) SetOpenSettingsOnReady(open bool) {
 	a.openSettingsOnReady = open
@@ -186,37 +436,33 @@ func (a *App) OpenSettings() {
 // handleReloadConfig reloads configuration from file
 func (a *App) handleReloadConfig() {
 	utils.LogInfo("Reloading configuration...")
-	
+
+	// Pick up an updated app catalog first so the new config resolves against it
+	if err := catalog.Load(); err != nil {
+		utils.LogWarning("Failed to reload app catalog: %v", err)
+	}
+	if err := ai.LoadTemplates(); err != nil {
+		utils.LogWarning("Failed to reload templates: %v", err)
+	}
+
 	// Reload config from file
 	if err := config.Load(); err != nil {
 		utils.LogError("Failed to reload config: %v", err)
 		go popup.ShowInfo("Reload Failed", fmt.Sprintf("Failed to reload config:\n%v", err))
 		return
 	}
-	
-	// Update local reference
-	a.config = config.Get()
-	
-	// Trigger callback to update other components
-	if a.onReloadConfig != nil {
-		a.onReloadConfig()
-	}
-	
-	// Update UI
-	a.updateToggleText()
-	a.updateAutostartText()
-	a.updateTooltip()
-	
+
+	// Load publishes the new config to all subscribers, including this tray
 	utils.LogInfo("Configuration reloaded successfully")
 	go popup.ShowInfo("Settings Applied ✅", "Configuration saved and applied!\n\nNew settings are now active.")
 }
 
 // handleToggleAutostart toggles autostart
 func (a *App) handleToggleAutostart() {
-	newValue := !a.config.Autostart
-	
+	newValue := !a.getConfig().Autostart
+
 	// Update config
-	if err := a.config.SetAutostart(newValue); err != nil {
+	if err := config.SetAutostart(newValue); err != nil {
 		utils.LogError("Failed to update autostart config: %v", err)
 		return
 	}
@@ -227,7 +473,6 @@ func (a *App) handleToggleAutostart() {
 		return
 	}
 
-	a.updateAutostartText()
 	utils.LogInfo("Autostart %s", map[bool]string{true: "enabled", false: "disabled"}[newValue])
 }
 
@@ -241,23 +486,35 @@ func (a *App) handleQuit() {
 
 // updateTooltip updates the system tray tooltip
 func (a *App) updateTooltip() {
+	cfg, isProductive := a.getState()
+
 	status := "OFF"
-	if a.config.Enabled {
+	if cfg.Enabled {
 		status = "ON"
 	}
 
 	productive := ""
-	if a.isProductiveTime {
+	if isProductive {
 		productive = " | Productive Time 📚"
 	}
 
-	tooltip := fmt.Sprintf("APPBlock [%s]%s", status, productive)
+	profile := ""
+	if name := a.getActiveProfile(); name != config.DefaultProfileName {
+		profile = " | " + name
+	}
+
+	countdown := ""
+	if text := a.transitionText(); text != "" {
+		countdown = " | " + text
+	}
+
+	tooltip := fmt.Sprintf("APPBlock [%s]%s%s%s", status, profile, productive, countdown)
 	systray.SetTooltip(tooltip)
 }
 
 // updateToggleText updates the toggle menu item text
 func (a *App) updateToggleText() {
-	if a.config.Enabled {
+	if a.getConfig().Enabled {
 		a.mToggle.SetTitle("Disable Blocking")
 	} else {
 		a.mToggle.SetTitle("Enable Blocking")
@@ -266,28 +523,231 @@ func (a *App) updateToggleText() {
 
 // updateAutostartText updates the autostart menu item text
 func (a *App) updateAutostartText() {
-	if a.config.Autostart {
+	if a.getConfig().Autostart {
 		a.mToggleAutostart.SetTitle("Disable Autostart")
 	} else {
 		a.mToggleAutostart.SetTitle("Enable Autostart")
 	}
 }
 
+// updateProfileItems syncs the profile submenu with the configured profiles
+// and checks the one selected manually (Default means schedule-based switching)
+func (a *App) updateProfileItems() {
+	cfg := a.getConfig()
+	names := cfg.ProfileNames()
+
+	selected := config.DefaultProfileName
+	if p := cfg.FindProfile(cfg.ActiveProfile); p != nil {
+		selected = p.Name
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.profileNames = names
+
+	// systray cannot remove items, so reuse existing ones and hide the rest
+	for i, name := range names {
+		if i >= len(a.profileItems) {
+			item := a.mProfile.AddSubMenuItemCheckbox(name, "Switch to profile "+name, false)
+			a.profileItems = append(a.profileItems, item)
+			go a.handleProfileClicks(i, item)
+		}
+
+		item := a.profileItems[i]
+		item.SetTitle(name)
+		item.Show()
+		if name == selected {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+
+	for _, item := range a.profileItems[len(names):] {
+		item.Hide()
+	}
+}
+
+// updateSuggestionItems syncs the suggestions submenu with the classifier's
+// pending suggestions, hiding the menu when there are none
+func (a *App) updateSuggestionItems() {
+	a.mu.Lock()
+	c := a.classifier
+	a.mu.Unlock()
+
+	var suggestions []classifier.Classification
+	if c != nil {
+		suggestions = c.Suggestions()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.suggestionNames = a.suggestionNames[:0]
+
+	// systray cannot remove items, so reuse existing ones and hide the rest
+	for i, s := range suggestions {
+		if i >= len(a.suggestionItems) {
+			item := a.mSuggestions.AddSubMenuItem("", "")
+			si := suggestionItem{
+				item:   item,
+				block:  item.AddSubMenuItem("🚫 Block", "Add to the blocklist"),
+				ignore: item.AddSubMenuItem("Ignore", "Dismiss this suggestion"),
+			}
+			a.suggestionItems = append(a.suggestionItems, si)
+			go a.handleSuggestionClicks(i, si)
+		}
+
+		title := s.Name
+		if s.Category != "" {
+			title += " (" + s.Category + ")"
+		}
+		item := a.suggestionItems[i].item
+		item.SetTitle(title)
+		item.SetTooltip(s.Reason)
+		item.Show()
+		a.suggestionNames = append(a.suggestionNames, s.Name)
+	}
+
+	for _, s := range a.suggestionItems[len(suggestions):] {
+		s.item.Hide()
+	}
+
+	if len(suggestions) == 0 {
+		a.mSuggestions.Hide()
+		return
+	}
+	a.mSuggestions.SetTitle(fmt.Sprintf("💡 Suggestions (%d)", len(suggestions)))
+	a.mSuggestions.Show()
+}
+
+// updateProfileTitle shows the profile currently in effect on the profile menu item
+func (a *App) updateProfileTitle() {
+	a.mProfile.SetTitle("👤 Profile: " + a.getActiveProfile())
+}
+
 // updateStatusText updates the status menu item text
 func (a *App) updateStatusText() {
 	var statusText string
-	
-	if !a.config.Enabled {
+	cfg, isProductive := a.getState()
+
+	if !cfg.Enabled {
 		statusText = "Status: Disabled"
-	} else if a.isProductiveTime {
+	} else if isProductive {
 		statusText = "Status: Blocking Active 🔒"
 	} else {
 		statusText = "Status: Idle (waiting)"
 	}
+	if text := a.transitionText(); text != "" && cfg.Enabled {
+		statusText += " - " + text
+	}
 
 	a.mStatus.SetTitle(statusText)
 }
 
+// transitionText describes the next transition, e.g. "Blocking ends in 42m"
+// or "Next block at 13:00". It is empty when there is none.
+func (a *App) transitionText() string {
+	a.mu.Lock()
+	nextTransition := a.nextTransition
+	isProductive := a.isProductiveTime
+	a.mu.Unlock()
+
+	if nextTransition == nil {
+		return ""
+	}
+	next, ok := nextTransition()
+	if !ok {
+		return ""
+	}
+
+	now := time.Now()
+	if isProductive {
+		return "Blocking ends in " + formatCountdown(next.Sub(now))
+	}
+	return nextBlockText(next, now)
+}
+
+// nextBlockText describes a block starting at next as seen at now. Days are
+// compared by calendar date, since a day across a DST change is not 24h.
+func nextBlockText(next, now time.Time) string {
+	next = next.In(now.Location())
+	tomorrow := now.AddDate(0, 0, 1)
+	switch {
+	case sameDay(next, now):
+		return "Next block at " + next.Format("15:04")
+	case sameDay(next, tomorrow):
+		return "Next block tomorrow " + next.Format("15:04")
+	default:
+		return "Next block " + next.Format("Mon 15:04")
+	}
+}
+
+// sameDay reports whether a and b fall on the same calendar date
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// formatCountdown formats d in whole minutes, rounded up: "42m", "1h 5m"
+func formatCountdown(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// updateAIStatusText updates the AI status menu item
+func (a *App) updateAIStatusText() {
+	a.mu.Lock()
+	status := a.aiStatus
+	a.mu.Unlock()
+
+	if status == "" {
+		a.mAIStatus.Hide()
+		return
+	}
+	a.mAIStatus.SetTitle("🤖 " + status)
+	a.mAIStatus.Show()
+}
+
+// updateDashboardItem shows the dashboard menu item while the dashboard runs
+func (a *App) updateDashboardItem() {
+	a.mu.Lock()
+	url := a.dashboardURL
+	a.mu.Unlock()
+
+	if url == "" {
+		a.mDashboard.Hide()
+		return
+	}
+	a.mDashboard.Show()
+}
+
+// getConfig returns the current config snapshot
+func (a *App) getConfig() *config.Config {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.config
+}
+
+// getActiveProfile returns the name of the profile currently in effect
+func (a *App) getActiveProfile() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.activeProfile
+}
+
+// getState returns the config snapshot and productive status together
+func (a *App) getState() (*config.Config, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.config, a.isProductiveTime
+}
+
 // getIcon returns the system tray icon
 // Uses embedded icon from binary
 func getIcon() []byte {
@@ -296,20 +756,20 @@ func getIcon() []byte {
 		utils.LogInfo("Using embedded tray icon (%d bytes)", len(iconData))
 		return iconData
 	}
-	
+
 	// Fallback: try to load from icon.ico file next to executable
 	exePath, err := os.Executable()
 	if err == nil {
 		exeDir := filepath.Dir(exePath)
 		iconPath := filepath.Join(exeDir, "icon.ico")
-		
+
 		data, err := os.ReadFile(iconPath)
 		if err == nil && len(data) > 0 {
 			utils.LogInfo("Loaded tray icon from: %s", iconPath)
 			return data
 		}
 	}
-	
+
 	// Last fallback to default icon
 	utils.LogInfo("Using default embedded tray icon")
 	return getDefaultIcon()
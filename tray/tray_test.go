@@ -0,0 +1,32 @@
+package tray
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBlockTextAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available:", err)
+	}
+
+	tests := []struct {
+		name      string
+		now, next time.Time
+		want      string
+	}{
+		// Clocks go back on 27 Oct 2024, so that day has 25 hours
+		{"today before fall back", time.Date(2024, 10, 27, 0, 30, 0, 0, loc), time.Date(2024, 10, 27, 23, 30, 0, 0, loc), "Next block at 23:30"},
+		{"tomorrow across fall back", time.Date(2024, 10, 26, 23, 30, 0, 0, loc), time.Date(2024, 10, 27, 23, 45, 0, 0, loc), "Next block tomorrow 23:45"},
+		// Clocks go forward on 31 Mar 2024, so that day has 23 hours
+		{"tomorrow across spring forward", time.Date(2024, 3, 30, 0, 30, 0, 0, loc), time.Date(2024, 3, 31, 0, 15, 0, 0, loc), "Next block tomorrow 00:15"},
+		{"two days across spring forward", time.Date(2024, 3, 30, 0, 30, 0, 0, loc), time.Date(2024, 4, 1, 0, 15, 0, 0, loc), "Next block Mon 00:15"},
+		{"later in the week", time.Date(2024, 3, 30, 12, 0, 0, 0, loc), time.Date(2024, 4, 2, 9, 0, 0, 0, loc), "Next block Tue 09:00"},
+	}
+	for _, tt := range tests {
+		if got := nextBlockText(tt.next, tt.now); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
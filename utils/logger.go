@@ -1,87 +1,247 @@
 package utils
 
 import (
+	"appblock/paths"
+	"context"
 	"fmt"
-	"log"
-	"os"
+	"io"
+	"log/slog"
 	"path/filepath"
+	"strings"
 	"sync"
-	"time"
 )
 
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Defaults for LogOptions fields left zero
+const (
+	defaultLogMaxSizeMB  = 5
+	defaultLogMaxAgeDays = 14
+	defaultLogMaxBackups = 10
+)
+
+// LogOptions configures the logger. Zero fields use the defaults.
+type LogOptions struct {
+	Level      string // Minimum level: debug, info (default), warn or error
+	Format     string // text (default) or json
+	MaxSizeMB  int    // Rotate app.log when it grows past this size (default 5)
+	MaxAgeDays int    // Delete rotated logs older than this (default 14)
+	MaxBackups int    // Keep at most this many rotated logs (default 10)
+}
+
 var (
-	logFile   *os.File
-	logger    *log.Logger
-	logMutex  sync.Mutex
-	logPath   string
+	logFile  *RotatingFile
+	logger   *slog.Logger
+	logLevel = new(slog.LevelVar)
+	logMutex sync.Mutex
+	logPath  string
 )
 
-// InitLogger initializes the logging system
+// InitLogger initializes the logging system with the default options. Call
+// ConfigureLogger once the config is loaded to apply the user's settings.
 func InitLogger() error {
-	exePath, err := os.Executable()
+	path, err := DefaultLogPath()
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return err
 	}
-	
-	exeDir := filepath.Dir(exePath)
-	logPath = filepath.Join(exeDir, "app.log")
-	
-	// Open log file in append mode
-	logFile, err = os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logPath = path
+
+	file, err := OpenRotatingFile(logPath, defaultLogMaxSizeMB<<20, defaultLogMaxAgeDays, defaultLogMaxBackups)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
-	
-	logger = log.New(logFile, "", 0) // No prefix, we'll add our own
-	
+
+	logMutex.Lock()
+	logFile = file
+	logger = slog.New(newHandler(file, LogFormatText))
+	logMutex.Unlock()
+
 	LogInfo("Logger initialized")
 	return nil
 }
 
+// DefaultLogPath returns the path of app.log in the state directory
+func DefaultLogPath() (string, error) {
+	dir := paths.StateDir()
+	if dir == "" {
+		return "", fmt.Errorf("no log directory")
+	}
+	return filepath.Join(dir, "app.log"), nil
+}
+
+// ConfigureLogger applies the level, format and rotation settings
+func ConfigureLogger(opts LogOptions) error {
+	level, err := ParseLogLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(opts.Format)
+	switch format {
+	case "":
+		format = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", opts.Format)
+	}
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+
+	logLevel.Set(level)
+	if logFile == nil {
+		return nil
+	}
+
+	logFile.SetLimits(
+		int64(withDefault(opts.MaxSizeMB, defaultLogMaxSizeMB))<<20,
+		withDefault(opts.MaxAgeDays, defaultLogMaxAgeDays),
+		withDefault(opts.MaxBackups, defaultLogMaxBackups),
+	)
+	logger = slog.New(newHandler(logFile, format))
+	return nil
+}
+
+// ParseLogLevel parses a level name; an empty name means info
+func ParseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", name)
+	}
+}
+
 // CloseLogger closes the log file
 func CloseLogger() {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+
 	if logFile != nil {
 		logFile.Close()
 	}
 }
 
+// Logger returns the structured logger, for messages with attributes.
+// Before InitLogger it discards everything.
+func Logger() *slog.Logger {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+
+	if logger == nil {
+		return slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return logger
+}
+
+// LogDebug logs a debug message, hidden unless the level is debug
+func LogDebug(format string, args ...interface{}) {
+	logMessage(slog.LevelDebug, format, args...)
+}
+
 // LogInfo logs an info message
 func LogInfo(format string, args ...interface{}) {
-	logMessage("INFO", format, args...)
+	logMessage(slog.LevelInfo, format, args...)
 }
 
 // LogError logs an error message
 func LogError(format string, args ...interface{}) {
-	logMessage("ERROR", format, args...)
+	logMessage(slog.LevelError, format, args...)
 }
 
 // LogWarning logs a warning message
 func LogWarning(format string, args ...interface{}) {
-	logMessage("WARN", format, args...)
+	logMessage(slog.LevelWarn, format, args...)
 }
 
 // LogBlocked logs when an app is blocked
 func LogBlocked(processName string) {
-	logMessage("BLOCK", "Terminated process: %s", processName)
+	Logger().Info("Terminated process: "+processName, "event", "blocked", "app", processName)
 }
 
 // logMessage is the internal logging function
-func logMessage(level, format string, args ...interface{}) {
-	logMutex.Lock()
-	defer logMutex.Unlock()
-	
-	if logger == nil {
+func logMessage(level slog.Level, format string, args ...interface{}) {
+	l := Logger()
+	if !l.Enabled(context.Background(), level) {
 		return
 	}
-	
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	message := fmt.Sprintf(format, args...)
-	logLine := fmt.Sprintf("[%s] [%s] %s", timestamp, level, message)
-	
-	logger.Println(logLine)
+	l.Log(context.Background(), level, fmt.Sprintf(format, args...))
 }
 
 // GetLogPath returns the log file path
 func GetLogPath() string {
 	return logPath
 }
+
+// newHandler creates the slog handler for a format, redacting secrets
+func newHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: logLevel}
+	if format == LogFormatJSON {
+		return &redactHandler{slog.NewJSONHandler(w, opts)}
+	}
+	return &redactHandler{slog.NewTextHandler(w, opts)}
+}
+
+// redactHandler removes secrets from messages and string attributes
+type redactHandler struct {
+	slog.Handler
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redactedRecord := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redactedRecord.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, redactedRecord)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = redactAttr(a)
+	}
+	return &redactHandler{h.Handler.WithAttrs(redactedAttrs)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr redacts string and error values, including inside groups
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redactedGroup := make([]slog.Attr, len(group))
+		for i, g := range group {
+			redactedGroup[i] = redactAttr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redactedGroup...)}
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// withDefault returns value, or def when value is not positive
+func withDefault(value, def int) int {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
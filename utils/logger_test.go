@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandlerRedactsAndFilters(t *testing.T) {
+	defer logLevel.Set(slog.LevelInfo)
+	logLevel.Set(slog.LevelWarn)
+
+	var buf bytes.Buffer
+	l := slog.New(newHandler(&buf, LogFormatJSON))
+
+	l.Info("hidden")
+	l.Warn("request to https://example.com/?key=abcdef123456 failed",
+		"err", errors.New("Bearer abcdef123456"), "app", "steam.exe")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want only the warning:\n%s", len(lines), buf.String())
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	if strings.Contains(lines[0], "abcdef123456") {
+		t.Errorf("secret not redacted: %s", lines[0])
+	}
+	if entry["level"] != "WARN" || entry["app"] != "steam.exe" {
+		t.Errorf("entry = %v, want level WARN and app attribute", entry)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{"": slog.LevelInfo, "DEBUG": slog.LevelDebug, "warning": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLogLevel(name); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("ParseLogLevel(verbose) succeeded")
+	}
+}
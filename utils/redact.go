@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in log lines
+const redacted = "[REDACTED]"
+
+// secretPatterns match well-known credential formats. Patterns with a
+// capture group only redact the group, keeping the surrounding context.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`),                                        // Google API keys
+	regexp.MustCompile(`sk-(?:proj-|ant-)?[0-9A-Za-z_\-]{20,}`),                         // OpenAI-style keys
+	regexp.MustCompile(`(?i)\bBearer\s+([0-9A-Za-z._~+/\-]+=*)`),                        // Authorization headers
+	regexp.MustCompile(`(?i)[?&](?:key|api_key|apikey|access_token|token)=([^&\s"']+)`), // Keys in URLs
+	regexp.MustCompile(`(?i)x-goog-api-key["':=\s]+([^\s"',]+)`),                        // Gemini key header
+}
+
+// minSecretLength avoids redacting short, common strings registered by mistake
+const minSecretLength = 8
+
+var (
+	secretValues []string
+	secretMu     sync.RWMutex
+)
+
+// RegisterSecret adds a value, such as an API key read at runtime, to be
+// redacted from every log line
+func RegisterSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secretMu.Lock()
+	defer secretMu.Unlock()
+
+	for _, v := range secretValues {
+		if v == value {
+			return
+		}
+	}
+	secretValues = append(secretValues, value)
+
+	// Replace longer values first so one secret containing another is fully redacted
+	sort.Slice(secretValues, func(i, j int) bool {
+		return len(secretValues[i]) > len(secretValues[j])
+	})
+}
+
+// Redact removes registered secret values and known credential patterns from s
+func Redact(s string) string {
+	secretMu.RLock()
+	for _, v := range secretValues {
+		s = strings.ReplaceAll(s, v, redacted)
+	}
+	secretMu.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			groups := pattern.FindStringSubmatchIndex(match)
+			if len(groups) < 4 || groups[2] < 0 {
+				return redacted
+			}
+			return match[:groups[2]] + redacted + match[groups[3]:]
+		})
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is the timestamp in rotated file names, e.g. app-2024-03-11T14-05-00.log.gz
+const rotatedTimeFormat = "2006-01-02T15-04-05"
+
+// RotatingFile is an append-only log file that is rotated when it grows past
+// a size limit or when a new day starts. Rotated files are gzip-compressed
+// and deleted once they are too old or too many.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+	day        string // day the current file was started, YYYY-MM-DD
+	compress   sync.WaitGroup
+	mu         sync.Mutex
+}
+
+// OpenRotatingFile opens path for appending, rotating it first if it is
+// already over the limits
+func OpenRotatingFile(path string, maxSize int64, maxAgeDays, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path}
+	r.SetLimits(maxSize, maxAgeDays, maxBackups)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetLimits changes the rotation limits, applied from the next write
+func (r *RotatingFile) SetLimits(maxSize int64, maxAgeDays, maxBackups int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxSize = maxSize
+	r.maxAge = time.Duration(maxAgeDays) * 24 * time.Hour
+	r.maxBackups = maxBackups
+}
+
+// Write appends p, rotating the file first when needed
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	if r.size > 0 && (r.size+int64(len(p)) > r.maxSize || r.day != today) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the file and waits for compression in progress
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	r.compress.Wait()
+	return err
+}
+
+// Backups returns the rotated files, newest first
+func (r *RotatingFile) Backups() []string {
+	files := LogFiles(r.path)
+	backups := files[:len(files)-1]
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// LogFiles returns the rotated files of the log at path, oldest first,
+// followed by path itself. Rotated files may be gzip-compressed (.gz).
+func LogFiles(path string) []string {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), base+"-*"+ext+"*"))
+
+	var files []string
+	for _, m := range matches {
+		if _, ok := backupTime(m, base, ext); ok {
+			files = append(files, m)
+		}
+	}
+	// The timestamp format sorts chronologically
+	sort.Strings(files)
+	return append(files, path)
+}
+
+// open opens the current file. Callers must hold mu.
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.day = time.Now().Format("2006-01-02")
+	if r.size > 0 {
+		// An existing file belongs to the day it was last written
+		r.day = info.ModTime().Format("2006-01-02")
+	}
+	return nil
+}
+
+// rotate renames the current file aside, opens a new one and compresses
+// the old one in the background. Callers must hold mu.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	base, ext := r.nameParts()
+	rotated := filepath.Join(filepath.Dir(r.path), base+"-"+time.Now().Format(rotatedTimeFormat)+ext)
+	if err := os.Rename(r.path, rotated); err != nil {
+		// Keep logging to the current file rather than losing lines
+		if openErr := r.open(); openErr != nil {
+			return openErr
+		}
+		return nil
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	maxAge, maxBackups := r.maxAge, r.maxBackups
+	r.compress.Add(1)
+	go func() {
+		defer r.compress.Done()
+		compressFile(rotated)
+		r.prune(maxAge, maxBackups)
+	}()
+	return nil
+}
+
+// prune deletes rotated files past the age or count limit
+func (r *RotatingFile) prune(maxAge time.Duration, maxBackups int) {
+	base, ext := r.nameParts()
+	cutoff := time.Now().Add(-maxAge)
+
+	for i, backup := range r.Backups() {
+		t, _ := backupTime(backup, base, ext)
+		if i >= maxBackups || (maxAge > 0 && t.Before(cutoff)) {
+			os.Remove(backup)
+		}
+	}
+}
+
+// nameParts splits the file name into base and extension, e.g. app and .log
+func (r *RotatingFile) nameParts() (string, string) {
+	name := filepath.Base(r.path)
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext), ext
+}
+
+// backupTime parses the rotation time from a rotated file name
+func backupTime(path, base, ext string) (time.Time, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".gz")
+	if !strings.HasPrefix(name, base+"-") || !strings.HasSuffix(name, ext) {
+		return time.Time{}, false
+	}
+
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, base+"-"), ext)
+	t, err := time.ParseInLocation(rotatedTimeFormat, stamp, time.Local)
+	return t, err == nil
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return
+	}
+
+	src.Close()
+	os.Remove(path)
+}
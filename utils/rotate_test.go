@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := OpenRotatingFile(path, 100, 14, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := strings.Repeat("a", 80) + "\n"
+	second := strings.Repeat("b", 40) + "\n"
+	r.Write([]byte(first))
+	r.Write([]byte(second)) // over 100 bytes - rotates first
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	current, _ := os.ReadFile(path)
+	if string(current) != second {
+		t.Errorf("current log = %q, want only the second line", current)
+	}
+
+	backups := r.Backups()
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("backups = %v, want one compressed file", backups)
+	}
+
+	f, err := os.Open(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != first {
+		t.Errorf("rotated log = %q, want the first line", data)
+	}
+}
+
+func TestRotatingFileRotatesNewDay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("yesterday\n"), 0644)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	os.Chtimes(path, yesterday, yesterday)
+
+	r, err := OpenRotatingFile(path, 1<<20, 14, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("today\n"))
+	r.Close()
+
+	current, _ := os.ReadFile(path)
+	if string(current) != "today\n" || len(r.Backups()) != 1 {
+		t.Errorf("current log = %q, backups = %v; want a new file and one backup", current, r.Backups())
+	}
+}
+
+func TestRotatingFilePrunesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	// Old backups: one past the age limit, three within it
+	for _, age := range []time.Duration{30 * 24 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour} {
+		name := "app-" + time.Now().Add(-age).Format(rotatedTimeFormat) + ".log.gz"
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "app-notes.log"), nil, 0644) // not a backup
+
+	r, err := OpenRotatingFile(path, 10, 14, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("0123456789"))
+	r.Write([]byte("x")) // rotates and prunes
+	r.Close()
+
+	if backups := r.Backups(); len(backups) != 2 {
+		t.Errorf("backups = %v, want the 2 newest", backups)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app-notes.log")); err != nil {
+		t.Errorf("unrelated file removed: %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+package utils
+
+// Version is the APPBlock version, set at build time with
+// -ldflags "-X appblock/utils.Version=1.2.0"
+var Version = "dev"
@@ -0,0 +1,267 @@
+package web
+
+import (
+	"appblock/config"
+	"appblock/history"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxBodySize bounds request bodies
+const maxBodySize = 64 << 10
+
+// api implements the JSON endpoints on top of the config and history packages
+type api struct {
+	now      func() time.Time // for tests; nil means time.Now
+	schedule Schedule         // nil means the config's time windows
+}
+
+// Status is the response of GET /api/status
+type Status struct {
+	Enabled        bool       `json:"enabled"`
+	Productive     bool       `json:"productive"`
+	Profile        string     `json:"profile"`         // Profile in effect now
+	ManualProfile  string     `json:"manual_profile"`  // Profile selected by hand, "" for schedule-based switching
+	Profiles       []string   `json:"profiles"`        // All profiles, starting with Default
+	NextTransition *time.Time `json:"next_transition"` // When productive time next starts or ends, null if never
+	BlockedToday   int        `json:"blocked_today"`
+	Now            time.Time  `json:"now"`
+}
+
+// AppCount is the number of blocked attempts of one app
+type AppCount struct {
+	App   string `json:"app"`
+	Count int    `json:"count"`
+}
+
+// TodayStats is the response of GET /api/stats/today
+type TodayStats struct {
+	Hours [24]int    `json:"hours"` // Blocked attempts per hour of the day
+	Apps  []AppCount `json:"apps"`  // Blocked attempts per app, most first
+	Total int        `json:"total"`
+}
+
+// Settings are the editable settings, returned by GET /api/config
+type Settings struct {
+	ActiveDays  []string            `json:"active_days"`
+	TimeWindows []config.TimeWindow `json:"time_windows"`
+	Blocklist   []string            `json:"blocklist"`
+}
+
+func (a *api) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// productiveAt and nextTransition ask the schedule, falling back to the config
+func (a *api) productiveAt(cfg *config.Config, t time.Time) bool {
+	if a.schedule != nil {
+		return a.schedule.IsProductiveAt(t)
+	}
+	return cfg.IsProductiveAt(t)
+}
+
+func (a *api) nextTransition(cfg *config.Config, now time.Time) (time.Time, bool) {
+	if a.schedule != nil {
+		return a.schedule.NextTransitionAt(now)
+	}
+	return cfg.NextTransitionAt(now)
+}
+
+func (a *api) status(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+	if cfg == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("config not loaded"))
+		return
+	}
+
+	now := a.clock()
+	status := Status{
+		Enabled:       cfg.Enabled,
+		Productive:    a.productiveAt(cfg, now),
+		Profile:       cfg.ProfileNameAt(now),
+		ManualProfile: cfg.ActiveProfile,
+		Profiles:      cfg.ProfileNames(),
+		BlockedToday:  len(blockedSince(midnight(now))),
+		Now:           now,
+	}
+	if next, ok := a.nextTransition(cfg, now); ok {
+		status.NextTransition = &next
+	}
+
+	writeJSON(w, status)
+}
+
+func (a *api) statsToday(w http.ResponseWriter, r *http.Request) {
+	var stats TodayStats
+	counts := make(map[string]int)
+	for _, e := range blockedSince(midnight(a.clock())) {
+		stats.Hours[e.Time.Hour()]++
+		counts[strings.ToLower(e.App)]++
+		stats.Total++
+	}
+
+	stats.Apps = []AppCount{}
+	for app, count := range counts {
+		stats.Apps = append(stats.Apps, AppCount{App: app, Count: count})
+	}
+	sort.Slice(stats.Apps, func(i, j int) bool {
+		if stats.Apps[i].Count != stats.Apps[j].Count {
+			return stats.Apps[i].Count > stats.Apps[j].Count
+		}
+		return stats.Apps[i].App < stats.Apps[j].App
+	})
+
+	writeJSON(w, stats)
+}
+
+func (a *api) config(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+	if cfg == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("config not loaded"))
+		return
+	}
+
+	writeJSON(w, Settings{
+		ActiveDays:  nonNil(cfg.ActiveDays),
+		TimeWindows: nonNil(cfg.TimeWindows),
+		Blocklist:   nonNil(cfg.Blocklist),
+	})
+}
+
+func (a *api) updateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ActiveDays  []string            `json:"active_days"`
+		TimeWindows []config.TimeWindow `json:"time_windows"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	for i := range req.TimeWindows {
+		req.TimeWindows[i].Start = strings.TrimSpace(req.TimeWindows[i].Start)
+		req.TimeWindows[i].End = strings.TrimSpace(req.TimeWindows[i].End)
+	}
+
+	update(w, func(c *config.Config) {
+		c.ActiveDays = nonNil(req.ActiveDays)
+		c.TimeWindows = nonNil(req.TimeWindows)
+	})
+}
+
+func (a *api) updateBlocklist(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Blocklist []string `json:"blocklist"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	update(w, func(c *config.Config) {
+		c.Blocklist = cleanBlocklist(req.Blocklist)
+	})
+}
+
+func (a *api) updateProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if err := config.SetActiveProfile(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.status(w, r)
+}
+
+// update applies a change to the config. config.Update validates it
+// before saving, so an invalid request leaves config.json untouched.
+func update(w http.ResponseWriter, mutate func(*config.Config)) {
+	if err := config.Update(mutate); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, config.ErrInvalid) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	saved := config.Get()
+	writeJSON(w, Settings{
+		ActiveDays:  nonNil(saved.ActiveDays),
+		TimeWindows: nonNil(saved.TimeWindows),
+		Blocklist:   nonNil(saved.Blocklist),
+	})
+}
+
+// cleanBlocklist trims names and drops empty and duplicate entries
+func cleanBlocklist(names []string) []string {
+	seen := make(map[string]bool)
+	cleaned := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		cleaned = append(cleaned, name)
+	}
+	return cleaned
+}
+
+// blockedSince returns the blocked-app events since t
+func blockedSince(t time.Time) []history.Event {
+	var blocked []history.Event
+	for _, e := range history.Events(t) {
+		if e.Kind == history.KindBlocked {
+			blocked = append(blocked, e)
+		}
+	}
+	return blocked
+}
+
+// midnight returns the start of t's day
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nonNil returns s, or an empty slice so it encodes as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// readJSON decodes the request body into v, writing an error response on failure
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid request: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as the response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes {"error": "..."} with the status code
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,225 @@
+// Package web serves the local dashboard: status, today's blocked attempts,
+// and schedule, blocklist and profile editing. It works through the config
+// and history packages only, so it runs the same on Windows and headless on
+// Linux. Every request needs the access token from LoadToken.
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// cookieName is the session cookie set after opening the dashboard link
+const cookieName = "appblock_token"
+
+//go:embed static
+var staticFiles embed.FS
+
+// Server serves the dashboard
+type Server struct {
+	http     *http.Server
+	listener net.Listener
+	token    string
+}
+
+// Schedule answers when productive time is, including sources beyond the
+// config such as a calendar
+type Schedule interface {
+	IsProductiveAt(t time.Time) bool
+	NextTransitionAt(now time.Time) (next time.Time, ok bool)
+}
+
+// Listen starts serving the dashboard on addr (host:port). Listening
+// happens before it returns, so a port in use is reported to the caller.
+// schedule may be nil to use the config's time windows only.
+func Listen(addr, token string, schedule Schedule) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+	s := &Server{
+		http: &http.Server{
+			Handler:           newHandler(token, host, &api{schedule: schedule}),
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		listener: listener,
+		token:    token,
+	}
+	go s.http.Serve(listener)
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// URL returns the dashboard link including the access token
+func (s *Server) URL() string {
+	return DashboardURL(s.Addr(), s.token)
+}
+
+// Close stops the server, waiting briefly for requests in progress
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := s.http.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return s.http.Close()
+	}
+	return err
+}
+
+// DashboardURL returns the link that opens the dashboard at addr. An
+// unspecified listen address (0.0.0.0) is reached through localhost.
+func DashboardURL(addr, token string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	u := url.URL{Scheme: "http", Host: host, Path: "/", RawQuery: url.Values{"token": {token}}.Encode()}
+	return u.String()
+}
+
+// NewHandler returns the dashboard handler. listenHost is the host the
+// server is bound to; requests naming another host are rejected unless it
+// listens on all interfaces, which stops DNS rebinding attacks.
+func NewHandler(token, listenHost string) http.Handler {
+	return newHandler(token, listenHost, &api{})
+}
+
+// newHandler builds the handler around api, which tests give a fixed clock
+func newHandler(token, listenHost string, api *api) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", api.status)
+	mux.HandleFunc("GET /api/stats/today", api.statsToday)
+	mux.HandleFunc("GET /api/config", api.config)
+	mux.HandleFunc("PUT /api/schedule", api.updateSchedule)
+	mux.HandleFunc("PUT /api/blocklist", api.updateBlocklist)
+	mux.HandleFunc("PUT /api/profile", api.updateProfile)
+
+	static, _ := fs.Sub(staticFiles, "static")
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	return &guard{token: token, listenHost: listenHost, next: mux}
+}
+
+// guard checks the host, the token and, for changes, the request origin
+type guard struct {
+	token      string
+	listenHost string
+	next       http.Handler
+}
+
+func (g *guard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+
+	if !g.allowedHost(r.Host) {
+		http.Error(w, "unknown host", http.StatusForbidden)
+		return
+	}
+
+	// Opening the link: keep the token in a cookie and drop it from the URL
+	if token := r.URL.Query().Get("token"); token != "" && r.Method == http.MethodGet {
+		if !g.validToken(token) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		query := r.URL.Query()
+		query.Del("token")
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		http.Redirect(w, r, target.String(), http.StatusSeeOther)
+		return
+	}
+
+	if !g.authorized(r) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		} else {
+			http.Error(w, "Open the dashboard with the link from \"appblock dashboard\" or the tray menu.", http.StatusUnauthorized)
+		}
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		// Plain forms cannot send JSON, and browsers always send Origin on
+		// cross-site requests
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("expected application/json"))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, errors.New("cross-origin request"))
+				return
+			}
+		}
+	}
+
+	g.next.ServeHTTP(w, r)
+}
+
+// authorized reports whether the request carries the token as a bearer
+// token or in the session cookie
+func (g *guard) authorized(r *http.Request) bool {
+	if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return g.validToken(auth)
+	}
+	if c, err := r.Cookie(cookieName); err == nil {
+		return g.validToken(c.Value)
+	}
+	return false
+}
+
+// validToken compares in constant time
+func (g *guard) validToken(token string) bool {
+	return g.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) == 1
+}
+
+// allowedHost reports whether the Host header names this machine or the
+// address the server is bound to
+func (g *guard) allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.Trim(host, "[]")
+
+	if ip := net.ParseIP(g.listenHost); g.listenHost == "" || (ip != nil && ip.IsUnspecified()) {
+		return true
+	}
+	if strings.EqualFold(host, "localhost") || strings.EqualFold(host, g.listenHost) {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,294 @@
+package web
+
+import (
+	"appblock/config"
+	"appblock/history"
+	"appblock/paths"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "appblock-web")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv(paths.EnvHome, dir)
+	if err := config.Init(); err != nil {
+		panic(err)
+	}
+	if err := history.Init(dir); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// monday10 is a Monday morning inside the default 09:00-12:00 window
+var monday10 = time.Date(2024, 3, 11, 10, 30, 0, 0, time.Local)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	if err := config.Update(func(c *config.Config) {
+		c.Enabled = true
+		c.ActiveDays = []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+		c.TimeWindows = []config.TimeWindow{{Start: "09:00", End: "12:00"}, {Start: "13:00", End: "17:00"}}
+		c.Blocklist = []string{"steam.exe"}
+		c.Profiles = []config.Profile{{Name: "Study", Blocklist: []string{"discord.exe"}}}
+		c.ActiveProfile = ""
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(newHandler(testToken, "127.0.0.1", &api{now: func() time.Time { return monday10 }}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func call(t *testing.T, srv *httptest.Server, method, path, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func TestAuth(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/api/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without token: status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/?token=wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d", resp.StatusCode)
+	}
+
+	// The link sets a cookie and redirects to a URL without the token
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = client.Get(srv.URL + "/?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther || strings.Contains(resp.Header.Get("Location"), "token") {
+		t.Fatalf("link: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Value != testToken || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	req.AddCookie(cookies[0])
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), "APPBlock Dashboard") {
+		t.Errorf("with cookie: status %d", resp.StatusCode)
+	}
+}
+
+func TestHostCheck(t *testing.T) {
+	srv := newTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/status", nil)
+	req.Host = "evil.example:80"
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign host: status %d", resp.StatusCode)
+	}
+
+	g := &guard{listenHost: "0.0.0.0"}
+	if !g.allowedHost("workstation-01:9465") {
+		t.Error("any host should be allowed when listening on all interfaces")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, body := call(t, srv, http.MethodGet, "/api/status", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+
+	var s Status
+	if err := json.Unmarshal(body, &s); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Enabled || !s.Productive || s.Profile != config.DefaultProfileName {
+		t.Errorf("status = %+v", s)
+	}
+	if len(s.Profiles) != 2 || s.Profiles[1] != "Study" {
+		t.Errorf("profiles = %v", s.Profiles)
+	}
+	// Windows include their end minute
+	want := time.Date(2024, 3, 11, 12, 1, 0, 0, time.Local)
+	if s.NextTransition == nil || !s.NextTransition.Equal(want) {
+		t.Errorf("next transition = %v, want %v", s.NextTransition, want)
+	}
+}
+
+// focusSchedule is productive all day, as if a calendar held a focus block
+type focusSchedule struct{ end time.Time }
+
+func (f focusSchedule) IsProductiveAt(t time.Time) bool { return t.Before(f.end) }
+
+func (f focusSchedule) NextTransitionAt(now time.Time) (time.Time, bool) { return f.end, true }
+
+func TestStatusSchedule(t *testing.T) {
+	newTestServer(t)
+	end := time.Date(2024, 3, 11, 18, 0, 0, 0, time.Local)
+	evening := func() time.Time { return time.Date(2024, 3, 11, 17, 30, 0, 0, time.Local) }
+	srv := httptest.NewServer(newHandler(testToken, "127.0.0.1", &api{now: evening, schedule: focusSchedule{end}}))
+	defer srv.Close()
+
+	_, body := call(t, srv, http.MethodGet, "/api/status", "")
+	var s Status
+	if err := json.Unmarshal(body, &s); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Productive || s.NextTransition == nil || !s.NextTransition.Equal(end) {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestUpdateSchedule(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, body := call(t, srv, http.MethodPut, "/api/schedule",
+		`{"active_days":["Mon","Sat"],"time_windows":[{"start":"08:00","end":"10:00"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	cfg := config.Get()
+	if len(cfg.ActiveDays) != 2 || cfg.TimeWindows[0].Start != "08:00" {
+		t.Errorf("saved schedule = %v %v", cfg.ActiveDays, cfg.TimeWindows)
+	}
+
+	for _, invalid := range []string{
+		`{"active_days":["Funday"],"time_windows":[]}`,
+		`{"active_days":["Mon"],"time_windows":[{"start":"25:00","end":"26:00"}]}`,
+		`{"active_days":["Mon"],"time_windows":[{"start":"17:00","end":"09:00"}]}`,
+		`{"active_days":["Mon"],"unknown":true}`,
+	} {
+		resp, body := call(t, srv, http.MethodPut, "/api/schedule", invalid)
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "error") {
+			t.Errorf("%s: status %d: %s", invalid, resp.StatusCode, body)
+		}
+	}
+	if got := config.Get().TimeWindows[0].Start; got != "08:00" {
+		t.Errorf("invalid request changed the config: %s", got)
+	}
+}
+
+func TestUpdateBlocklist(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, body := call(t, srv, http.MethodPut, "/api/blocklist", `{"blocklist":[" Steam.exe ","","steam.exe","game.exe"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if got := strings.Join(config.Get().Blocklist, ","); got != "Steam.exe,game.exe" {
+		t.Errorf("blocklist = %s", got)
+	}
+
+	// Changes must be JSON, which a cross-site form cannot send
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/blocklist", strings.NewReader("blocklist=x"))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("form post: status %d", resp.StatusCode)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, body := call(t, srv, http.MethodPut, "/api/profile", `{"name":"study"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if got := config.Get().ActiveProfile; got != "Study" {
+		t.Errorf("active profile = %q", got)
+	}
+
+	resp, _ = call(t, srv, http.MethodPut, "/api/profile", `{"name":"Nope"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown profile: status %d", resp.StatusCode)
+	}
+
+	call(t, srv, http.MethodPut, "/api/profile", `{"name":"Default"}`)
+	if got := config.Get().ActiveProfile; got != "" {
+		t.Errorf("active profile after Default = %q", got)
+	}
+}
+
+func TestDashboardURL(t *testing.T) {
+	if got := DashboardURL("0.0.0.0:9465", "abc"); got != "http://localhost:9465/?token=abc" {
+		t.Errorf("DashboardURL = %s", got)
+	}
+	if got := DashboardURL("[::1]:9465", "abc"); got != "http://[::1]:9465/?token=abc" {
+		t.Errorf("DashboardURL = %s", got)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	dir := t.TempDir()
+	first, err := LoadToken(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 64 {
+		t.Errorf("token length %d", len(first))
+	}
+	second, err := LoadToken(dir)
+	if err != nil || second != first {
+		t.Errorf("token not reused: %q, %v", second, err)
+	}
+}
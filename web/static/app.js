@@ -0,0 +1,238 @@
+"use strict";
+
+const DAYS = ["Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"];
+const DAY_NAMES = { Mon: "Sen", Tue: "Sel", Wed: "Rab", Thu: "Kam", Fri: "Jum", Sat: "Sab", Sun: "Min" };
+const REFRESH_MS = 15000;
+
+const $ = (id) => document.getElementById(id);
+
+async function request(method, path, body) {
+  const options = { method, headers: {}, credentials: "same-origin" };
+  if (body !== undefined) {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  const resp = await fetch(path, options);
+  const data = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    throw new Error(data.error || resp.statusText);
+  }
+  return data;
+}
+
+function formatTime(date) {
+  return date.toLocaleTimeString("id-ID", { hour: "2-digit", minute: "2-digit" });
+}
+
+function formatDuration(ms) {
+  const minutes = Math.max(0, Math.round(ms / 60000));
+  if (minutes < 60) return minutes + "m";
+  const hours = Math.floor(minutes / 60);
+  return hours + "j " + (minutes % 60) + "m";
+}
+
+function showMessage(id, text, ok) {
+  const el = $(id);
+  el.textContent = text;
+  el.className = "message " + (ok ? "ok" : "error");
+}
+
+// Status
+
+function renderStatus(s) {
+  const state = $("state");
+  if (!s.enabled) {
+    state.textContent = "⏸ Blocking dinonaktifkan";
+    state.className = "state disabled";
+  } else if (s.productive) {
+    state.textContent = "🔴 Blocking aktif";
+    state.className = "state active";
+  } else {
+    state.textContent = "🟢 Di luar jam produktif";
+    state.className = "state idle";
+  }
+
+  if (s.next_transition) {
+    const next = new Date(s.next_transition);
+    const left = formatDuration(next - new Date(s.now));
+    $("next").textContent = s.productive
+      ? "Blocking selesai dalam " + left + " (" + formatTime(next) + ")"
+      : "Blocking berikutnya " + formatTime(next) + " (" + left + " lagi)";
+  } else {
+    $("next").textContent = "";
+  }
+
+  $("blocked-today").textContent = s.blocked_today + " percobaan diblokir hari ini";
+
+  const select = $("profile");
+  if (document.activeElement !== select) {
+    select.replaceChildren();
+    s.profiles.forEach((name) => {
+      const option = document.createElement("option");
+      option.value = name;
+      option.textContent = name;
+      select.appendChild(option);
+    });
+    select.value = s.manual_profile || "Default";
+  }
+  $("profile-hint").textContent = s.manual_profile
+    ? "Dipilih manual"
+    : "Otomatis sesuai jadwal (sekarang: " + s.profile + ")";
+}
+
+async function loadStatus() {
+  try {
+    renderStatus(await request("GET", "/api/status"));
+  } catch (err) {
+    $("state").textContent = "Gagal memuat status: " + err.message;
+  }
+}
+
+$("profile").addEventListener("change", async (e) => {
+  try {
+    renderStatus(await request("PUT", "/api/profile", { name: e.target.value }));
+  } catch (err) {
+    alert("Gagal mengganti profile: " + err.message);
+  }
+});
+
+// Today's chart
+
+function renderStats(stats) {
+  const max = Math.max(1, ...stats.hours);
+  const chart = $("chart");
+  chart.replaceChildren();
+  stats.hours.forEach((count, hour) => {
+    const bar = document.createElement("div");
+    bar.className = "bar" + (count === 0 ? " empty" : "");
+    bar.style.height = (count / max) * 100 + "%";
+    bar.title = String(hour).padStart(2, "0") + ":00 - " + count + " percobaan";
+    chart.appendChild(bar);
+  });
+
+  let labels = chart.nextElementSibling;
+  if (!labels || !labels.classList.contains("chart-labels")) {
+    labels = document.createElement("div");
+    labels.className = "chart-labels";
+    for (let hour = 0; hour < 24; hour++) {
+      const label = document.createElement("span");
+      label.textContent = hour % 3 === 0 ? String(hour) : "";
+      labels.appendChild(label);
+    }
+    chart.after(labels);
+  }
+
+  const apps = $("apps");
+  apps.replaceChildren();
+  stats.apps.slice(0, 5).forEach((a) => {
+    const item = document.createElement("li");
+    item.textContent = a.app + " - " + a.count + "x";
+    apps.appendChild(item);
+  });
+}
+
+async function loadStats() {
+  try {
+    renderStats(await request("GET", "/api/stats/today"));
+  } catch (err) {
+    $("apps").textContent = "Gagal memuat statistik: " + err.message;
+  }
+}
+
+// Schedule
+
+function addWindowRow(window) {
+  const row = document.createElement("tr");
+  ["start", "end"].forEach((field) => {
+    const cell = document.createElement("td");
+    const input = document.createElement("input");
+    input.type = "time";
+    input.required = true;
+    input.value = window[field];
+    input.dataset.field = field;
+    cell.appendChild(input);
+    row.appendChild(cell);
+  });
+
+  const cell = document.createElement("td");
+  const remove = document.createElement("button");
+  remove.type = "button";
+  remove.textContent = "✕";
+  remove.title = "Hapus";
+  remove.addEventListener("click", () => row.remove());
+  cell.appendChild(remove);
+  row.appendChild(cell);
+
+  $("windows").tBodies[0].appendChild(row);
+}
+
+function renderSettings(settings) {
+  const days = $("days");
+  days.replaceChildren();
+  DAYS.forEach((day) => {
+    const label = document.createElement("label");
+    const box = document.createElement("input");
+    box.type = "checkbox";
+    box.value = day;
+    box.checked = settings.active_days.includes(day);
+    label.append(box, " " + DAY_NAMES[day]);
+    days.appendChild(label);
+  });
+
+  $("windows").tBodies[0].replaceChildren();
+  settings.time_windows.forEach(addWindowRow);
+
+  $("blocklist").value = settings.blocklist.join("\n");
+}
+
+async function loadSettings() {
+  try {
+    renderSettings(await request("GET", "/api/config"));
+  } catch (err) {
+    showMessage("schedule-message", "Gagal memuat config: " + err.message, false);
+  }
+}
+
+$("add-window").addEventListener("click", () => addWindowRow({ start: "09:00", end: "12:00" }));
+
+$("save-schedule").addEventListener("click", async () => {
+  const activeDays = [...$("days").querySelectorAll("input:checked")].map((box) => box.value);
+  const windows = [...$("windows").tBodies[0].rows].map((row) => {
+    const window = {};
+    row.querySelectorAll("input").forEach((input) => { window[input.dataset.field] = input.value; });
+    return window;
+  });
+
+  try {
+    renderSettings(await request("PUT", "/api/schedule", { active_days: activeDays, time_windows: windows }));
+    showMessage("schedule-message", "Jadwal disimpan ✅", true);
+    loadStatus();
+  } catch (err) {
+    showMessage("schedule-message", err.message, false);
+  }
+});
+
+$("save-blocklist").addEventListener("click", async () => {
+  const blocklist = $("blocklist").value.split("\n");
+  try {
+    renderSettings(await request("PUT", "/api/blocklist", { blocklist }));
+    showMessage("blocklist-message", "Blocklist disimpan ✅", true);
+  } catch (err) {
+    showMessage("blocklist-message", err.message, false);
+  }
+});
+
+// Clock and refresh
+
+function tick() {
+  $("clock").textContent = new Date().toLocaleString("id-ID", {
+    weekday: "long", hour: "2-digit", minute: "2-digit",
+  });
+}
+
+tick();
+loadStatus();
+loadStats();
+loadSettings();
+setInterval(tick, 1000);
+setInterval(() => { loadStatus(); loadStats(); }, REFRESH_MS);
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"appblock/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tokenFile holds the dashboard access token in the config directory
+const tokenFile = "dashboard.token"
+
+// LoadToken returns the dashboard access token stored in dir, creating a
+// random one on first use. The token is registered for redaction, so it
+// never appears in the log.
+func LoadToken(dir string) (string, error) {
+	path := filepath.Join(dir, tokenFile)
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); len(token) >= 32 {
+			utils.RegisterSecret(token)
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read dashboard token: %w", err)
+	}
+
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	if err := utils.WriteFileAtomic(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save dashboard token: %w", err)
+	}
+
+	utils.RegisterSecret(token)
+	return token, nil
+}
+
+// NewToken returns a random 256-bit token, hex-encoded
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate dashboard token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,321 @@
+package website
+
+import (
+	"appblock/config"
+	"appblock/popup"
+	"appblock/scheduler"
+	"appblock/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocker enforces the domain blocklist during productive time through a
+// managed section of the hosts file
+type Blocker struct {
+	config    *config.Config
+	scheduler *scheduler.Scheduler
+	backupDir string
+	ticker    *time.Ticker
+	stopChan  chan bool
+	applied   []string      // domains currently written to the hosts file
+	retryAt   time.Time     // After a failed update, no retries before this
+	backoff   time.Duration // Wait after the last failure, doubling up to maxRetryBackoff
+	lastErr   string        // Last failure logged, so a lasting one is logged once
+	mu        sync.Mutex
+}
+
+// Retry delays after a failed hosts file update, e.g. when not run as administrator
+const (
+	minRetryBackoff = 30 * time.Second
+	maxRetryBackoff = 30 * time.Minute
+)
+
+// backupName is the copy of the hosts file without the APPBlock section
+const backupName = "hosts.appblock.bak"
+
+// NewBlocker creates a new website blocker instance.
+// The original hosts file is backed up to backupDir before the first change.
+func NewBlocker(cfg *config.Config, sched *scheduler.Scheduler, backupDir string) *Blocker {
+	return &Blocker{
+		config:    cfg,
+		scheduler: sched,
+		backupDir: backupDir,
+		stopChan:  make(chan bool),
+	}
+}
+
+// Start removes any section left over from a previous run and starts the sync loop
+func (b *Blocker) Start() {
+	if err := Restore(b.backupDir); err != nil {
+		utils.LogWarning("Failed to clean hosts file on start: %v", err)
+	}
+
+	b.sync()
+
+	cfg := b.getConfig()
+	b.ticker = time.NewTicker(time.Duration(cfg.ScanIntervalSeconds) * time.Second)
+
+	utils.LogInfo("Website blocker started (hosts file: %s)", hostsPath())
+
+	go func() {
+		for {
+			select {
+			case <-b.ticker.C:
+				b.sync()
+			case <-b.stopChan:
+				b.ticker.Stop()
+				utils.LogInfo("Website blocker stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sync loop and removes the blocked domains from the hosts file
+func (b *Blocker) Stop() {
+	if b.ticker != nil {
+		b.stopChan <- true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.applied) > 0 {
+		if err := b.apply(nil); err != nil {
+			utils.LogError("Failed to restore hosts file on stop: %v", err)
+		}
+	}
+}
+
+// sync writes or removes the domain blocklist to match the productive state.
+// After a failure it backs off, logging the error only when it changes.
+func (b *Blocker) sync() {
+	var domains []string
+	if b.scheduler.IsProductive() {
+		cfg := b.getConfig().Effective(time.Now())
+		domains = expandDomains(cfg.BlockedDomains)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sameDomains(domains, b.applied) || time.Now().Before(b.retryAt) {
+		return
+	}
+
+	if err := b.apply(domains); err != nil {
+		b.backoff = min(max(2*b.backoff, minRetryBackoff), maxRetryBackoff)
+		b.retryAt = time.Now().Add(b.backoff)
+		if err.Error() != b.lastErr {
+			b.lastErr = err.Error()
+			utils.LogError("Failed to update hosts file: %v", err)
+		} else {
+			utils.LogDebug("Failed to update hosts file again, next try in %s", b.backoff)
+		}
+		return
+	}
+	if b.lastErr != "" {
+		utils.LogInfo("Hosts file updated again after earlier failures")
+	}
+	b.backoff, b.retryAt, b.lastErr = 0, time.Time{}, ""
+
+	if len(domains) > 0 {
+		utils.LogInfo("Blocking %d domains via hosts file", len(domains))
+		go b.showBlockedNotification(domains)
+	} else {
+		utils.LogInfo("Website blocking lifted - hosts file restored")
+	}
+}
+
+// apply rewrites the hosts file section with domains. Callers must hold b.mu.
+func (b *Blocker) apply(domains []string) error {
+	path := hostsPath()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	original, err := originalHosts(string(data), b.backupDir)
+	if err != nil {
+		return err
+	}
+	if err := b.backup(original); err != nil {
+		return err
+	}
+
+	if err := writeHosts(path, withSection(original, domains), b.backupDir); err != nil {
+		return err
+	}
+
+	b.applied = domains
+	flushDNS()
+	return nil
+}
+
+// backup saves the hosts file without the APPBlock section, so a damaged
+// section can be repaired. It is refreshed whenever the original changes.
+func (b *Blocker) backup(original string) error {
+	if b.backupDir == "" {
+		return nil
+	}
+
+	backupPath := filepath.Join(b.backupDir, backupName)
+	if saved, err := os.ReadFile(backupPath); err == nil && string(saved) == original {
+		return nil
+	}
+
+	if err := utils.WriteFileAtomic(backupPath, []byte(original), 0644); err != nil {
+		return fmt.Errorf("failed to back up hosts file: %w", err)
+	}
+
+	utils.LogInfo("Original hosts file backed up to %s", backupPath)
+	return nil
+}
+
+// showBlockedNotification tells the user which websites are now blocked
+func (b *Blocker) showBlockedNotification(domains []string) {
+	// Only list the base domains, not the www./m. variants
+	var names []string
+	for _, domain := range domains {
+		if !strings.HasPrefix(domain, "www.") && !strings.HasPrefix(domain, "m.") {
+			names = append(names, "• "+domain)
+		}
+	}
+
+	message := fmt.Sprintf("Website berikut diblokir selama waktu produktif:\n\n%s\n\nTutup tab yang sudah terbuka dan tetap fokus!",
+		strings.Join(names, "\n"))
+
+	if err := popup.ShowInfo("APPBlock - Website Diblokir 🌐", message); err != nil {
+		utils.LogError("Failed to show popup: %v", err)
+	}
+}
+
+// getConfig returns the current config snapshot
+func (b *Blocker) getConfig() *config.Config {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
+// UpdateConfig updates the website blocker configuration
+func (b *Blocker) UpdateConfig(cfg *config.Config) {
+	b.mu.Lock()
+	b.config = cfg
+	b.retryAt = time.Time{} // Try right away, the change may fix a failure
+	if b.ticker != nil {
+		b.ticker.Reset(time.Duration(cfg.ScanIntervalSeconds) * time.Second)
+	}
+	b.mu.Unlock()
+
+	b.sync()
+}
+
+// Restore removes the APPBlock section from the hosts file, if present.
+// A damaged section is repaired from the backup in backupDir.
+func Restore(backupDir string) error {
+	path := hostsPath()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	restored, err := originalHosts(string(data), backupDir)
+	if err != nil {
+		return err
+	}
+	if restored == string(data) {
+		return nil
+	}
+
+	if err := writeHosts(path, restored, backupDir); err != nil {
+		return err
+	}
+
+	flushDNS()
+	utils.LogInfo("Removed APPBlock section from hosts file")
+	return nil
+}
+
+// originalHosts returns the hosts file content without the APPBlock
+// section. When the section markers are damaged, lines around them may be
+// part of the section, so the backup is used instead if there is one.
+func originalHosts(content, backupDir string) (string, error) {
+	rest, intact := splitSection(content)
+	if intact || backupDir == "" {
+		return rest, nil
+	}
+
+	backup, err := os.ReadFile(filepath.Join(backupDir, backupName))
+	if os.IsNotExist(err) {
+		utils.LogWarning("APPBlock section of the hosts file is damaged and there is no backup - removing it as far as possible")
+		return rest, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("hosts file section is damaged and the backup is unreadable: %w", err)
+	}
+
+	utils.LogWarning("APPBlock section of the hosts file is damaged - restoring it from %s", backupName)
+	return stripSection(string(backup)), nil
+}
+
+// writeHosts replaces the hosts file content in place. Replacing the file
+// by renaming a new one over it would drop its owner and ACL (Windows) or
+// SELinux label, and fails on a bind-mounted /etc/hosts in containers. If
+// the write fails part way, the backup in backupDir is written back.
+func writeHosts(path, content, backupDir string) error {
+	err := overwrite(path, content)
+	if err == nil {
+		return nil
+	}
+	if os.IsPermission(err) {
+		return fmt.Errorf("permission denied writing %s - run APPBlock as administrator to block websites", path)
+	}
+
+	if backupDir != "" {
+		if backup, readErr := os.ReadFile(filepath.Join(backupDir, backupName)); readErr == nil {
+			if restoreErr := overwrite(path, string(backup)); restoreErr == nil {
+				utils.LogWarning("Writing the hosts file failed - restored it from %s", backupName)
+			}
+		}
+	}
+	return fmt.Errorf("failed to write hosts file: %w", err)
+}
+
+// overwrite writes content over the file at path, keeping the file itself
+func overwrite(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt([]byte(content), 0)
+	if err == nil {
+		err = f.Truncate(int64(len(content)))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// sameDomains reports whether two domain lists are identical
+func sameDomains(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package website
+
+import "os/exec"
+
+// flushDNS clears the systemd-resolved cache when available; other resolvers
+// read the hosts file directly
+func flushDNS() {
+	if path, err := exec.LookPath("resolvectl"); err == nil {
+		exec.Command(path, "flush-caches").Run()
+	}
+}
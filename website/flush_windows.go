@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package website
+
+import (
+	"appblock/utils"
+	"os/exec"
+	"syscall"
+)
+
+// flushDNS clears the Windows DNS cache so hosts changes apply immediately
+func flushDNS() {
+	cmd := exec.Command("ipconfig", "/flushdns")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if err := cmd.Run(); err != nil {
+		utils.LogWarning("Failed to flush DNS cache: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package website
+
+import (
+	"appblock/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	sectionBegin = "# BEGIN APPBlock - managed section, do not edit"
+	sectionEnd   = "# END APPBlock"
+)
+
+// hostsPath is the location of the system hosts file; tests point it elsewhere
+var hostsPath = defaultHostsPath
+
+// defaultHostsPath returns the location of the system hosts file
+func defaultHostsPath() string {
+	if runtime.GOOS == "windows" {
+		root := os.Getenv("SystemRoot")
+		if root == "" {
+			root = `C:\Windows`
+		}
+		return filepath.Join(root, "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+// stripSection removes the APPBlock section from hosts file content.
+// An unterminated section (e.g. from a crash mid-edit) is removed up to the end of file.
+func stripSection(content string) string {
+	rest, _ := splitSection(content)
+	return rest
+}
+
+// splitSection removes the APPBlock section from hosts file content. intact
+// is false when the markers do not form a single section - a begin marker
+// without an end, an end without a begin, or more than one section - in
+// which case lines may have been lost and the backup is the better source.
+func splitSection(content string) (rest string, intact bool) {
+	lines := strings.SplitAfter(content, "\n")
+
+	var out strings.Builder
+	intact = true
+	inSection := false
+	sections := 0
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == sectionBegin:
+			if inSection {
+				intact = false
+			}
+			inSection = true
+			sections++
+		case trimmed == sectionEnd:
+			if !inSection {
+				intact = false
+			}
+			inSection = false
+		case !inSection:
+			out.WriteString(line)
+		}
+	}
+
+	return out.String(), intact && !inSection && sections <= 1
+}
+
+// buildSection returns the APPBlock section that points domains at a blackhole address
+func buildSection(domains []string, newline string) string {
+	var b strings.Builder
+	b.WriteString(sectionBegin + newline)
+	for _, domain := range domains {
+		fmt.Fprintf(&b, "0.0.0.0 %s%s", domain, newline)
+		fmt.Fprintf(&b, ":: %s%s", domain, newline)
+	}
+	b.WriteString(sectionEnd + newline)
+	return b.String()
+}
+
+// withSection returns hosts content with the APPBlock section replaced by one
+// blocking domains, or removed entirely when domains is empty
+func withSection(content string, domains []string) string {
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+
+	result := stripSection(content)
+	if len(domains) == 0 {
+		return result
+	}
+
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += newline
+	}
+	return result + buildSection(domains, newline)
+}
+
+// expandDomains normalizes entries like "https://www.YouTube.com/feed" to host
+// names and adds the common www. and m. variants, since hosts has no wildcards
+func expandDomains(entries []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		if !seen[host] {
+			seen[host] = true
+			result = append(result, host)
+		}
+	}
+
+	for _, entry := range entries {
+		host := config.NormalizeDomain(entry)
+		if host == "" {
+			continue
+		}
+
+		base := strings.TrimPrefix(strings.TrimPrefix(host, "www."), "m.")
+		add(base)
+		add("www." + base)
+		add("m." + base)
+	}
+
+	return result
+}
@@ -0,0 +1,178 @@
+package website
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	begin = sectionBegin + "\n"
+	end   = sectionEnd + "\n"
+)
+
+func TestStripSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		intact  bool
+	}{
+		{"no section", "127.0.0.1 localhost\n", "127.0.0.1 localhost\n", true},
+		{"section at end", "127.0.0.1 localhost\n" + begin + "0.0.0.0 youtube.com\n" + end, "127.0.0.1 localhost\n", true},
+		{"section in the middle", "a\n" + begin + "0.0.0.0 x.com\n" + end + "b\n", "a\nb\n", true},
+		{"crlf", "a\r\n" + sectionBegin + "\r\n0.0.0.0 x.com\r\n" + sectionEnd + "\r\nb\r\n", "a\r\nb\r\n", true},
+		{"no trailing newline", "a\n" + begin + "0.0.0.0 x.com\n" + sectionEnd, "a\n", true},
+		{"unterminated", "a\n" + begin + "0.0.0.0 x.com\nb\n", "a\n", false},
+		{"end without begin", "a\n" + end + "b\n", "a\nb\n", false},
+		{"two sections", "a\n" + begin + end + "b\n" + begin + end, "a\nb\n", false},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, intact := splitSection(tt.content)
+			if got != tt.want || intact != tt.intact {
+				t.Errorf("splitSection = %q, %v, want %q, %v", got, intact, tt.want, tt.intact)
+			}
+			if got := stripSection(tt.content); got != tt.want {
+				t.Errorf("stripSection = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		domains []string
+		want    string
+	}{
+		{"add", "127.0.0.1 localhost\n", []string{"x.com"},
+			"127.0.0.1 localhost\n" + begin + "0.0.0.0 x.com\n:: x.com\n" + end},
+		{"replace", "a\n" + begin + "0.0.0.0 old.com\n" + end + "b\n", []string{"new.com"},
+			"a\nb\n" + begin + "0.0.0.0 new.com\n:: new.com\n" + end},
+		{"remove", "a\n" + begin + "0.0.0.0 old.com\n" + end, nil, "a\n"},
+		{"crlf", "127.0.0.1 localhost\r\n", []string{"x.com"},
+			"127.0.0.1 localhost\r\n" + sectionBegin + "\r\n0.0.0.0 x.com\r\n:: x.com\r\n" + sectionEnd + "\r\n"},
+		{"no trailing newline", "127.0.0.1 localhost", []string{"x.com"},
+			"127.0.0.1 localhost\n" + begin + "0.0.0.0 x.com\n:: x.com\n" + end},
+		{"crlf without trailing newline", "a\r\nb", []string{"x.com"},
+			"a\r\nb\r\n" + sectionBegin + "\r\n0.0.0.0 x.com\r\n:: x.com\r\n" + sectionEnd + "\r\n"},
+		{"empty file", "", []string{"x.com"}, begin + "0.0.0.0 x.com\n:: x.com\n" + end},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withSection(tt.content, tt.domains)
+			if got != tt.want {
+				t.Errorf("withSection =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandDomains(t *testing.T) {
+	tests := []struct {
+		entries []string
+		want    []string
+	}{
+		{[]string{"youtube.com"}, []string{"youtube.com", "www.youtube.com", "m.youtube.com"}},
+		{[]string{"https://www.YouTube.com/feed"}, []string{"youtube.com", "www.youtube.com", "m.youtube.com"}},
+		{[]string{"m.reddit.com", "reddit.com"}, []string{"reddit.com", "www.reddit.com", "m.reddit.com"}},
+		{[]string{"", "  ", "bad host", "#youtube.com"}, nil},
+		// A line break would write the rest of the entry as its own hosts line
+		{[]string{"x.com\n1.2.3.4 bank.example", "y.com\r\n:: bank.example", "z.com\r1.2.3.4 bank.example"}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := expandDomains(tt.entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandDomains(%q) = %q, want %q", tt.entries, got, tt.want)
+		}
+	}
+}
+
+// useHostsFile points the package at a temporary hosts file with content
+func useHostsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hostsPath = func() string { return path }
+	t.Cleanup(func() { hostsPath = defaultHostsPath })
+	return path
+}
+
+func TestRestoreWritesInPlace(t *testing.T) {
+	path := useHostsFile(t, "127.0.0.1 localhost\n"+begin+"0.0.0.0 x.com\n"+end)
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "127.0.0.1 localhost\n" {
+		t.Errorf("hosts = %q", data)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("hosts file was replaced instead of rewritten")
+	}
+}
+
+func TestRestoreDamagedSectionFromBackup(t *testing.T) {
+	backupDir := t.TempDir()
+	original := "127.0.0.1 localhost\n10.0.0.5 nas.lan\n"
+	if err := os.WriteFile(filepath.Join(backupDir, backupName), []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The end marker was lost, so the user's line after it looks like part of the section
+	path := useHostsFile(t, "127.0.0.1 localhost\n"+begin+"0.0.0.0 x.com\n10.0.0.5 nas.lan\n")
+	if err := Restore(backupDir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != original {
+		t.Errorf("hosts = %q, want the backup %q", data, original)
+	}
+}
+
+func TestApplyRefreshesBackup(t *testing.T) {
+	backupDir := t.TempDir()
+	path := useHostsFile(t, "127.0.0.1 localhost\r\n")
+
+	b := &Blocker{backupDir: backupDir}
+	if err := b.apply([]string{"x.com"}); err != nil {
+		t.Fatal(err)
+	}
+	backup, _ := os.ReadFile(filepath.Join(backupDir, backupName))
+	if string(backup) != "127.0.0.1 localhost\r\n" {
+		t.Errorf("backup = %q", backup)
+	}
+
+	// The user edits the hosts file while blocking
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append([]byte("10.0.0.5 nas.lan\r\n"), data...), 0644)
+	if err := b.apply(nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "10.0.0.5 nas.lan\r\n127.0.0.1 localhost\r\n"
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("hosts = %q, want %q", data, want)
+	}
+	if backup, _ := os.ReadFile(filepath.Join(backupDir, backupName)); string(backup) != want {
+		t.Errorf("backup = %q, want %q", backup, want)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"appblock/ai"
+	"appblock/config"
+	"appblock/popup"
+	"appblock/report"
+	"appblock/utils"
+	"context"
+	"time"
+)
+
+// reportCheckInterval is how often the tray app checks whether last week's report is due
+const reportCheckInterval = time.Hour
+
+// runWeeklyReports saves the report of the previous week once a new week
+// has started, checking periodically until stop is closed
+func runWeeklyReports(stop <-chan struct{}) {
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		saveWeeklyReport()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// saveWeeklyReport writes last week's report if it does not exist yet and
+// shows it when anything was blocked
+func saveWeeklyReport() {
+	start := report.LastWeek(time.Now())
+	if report.Exists(start) {
+		return
+	}
+
+	cfg := config.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*ai.DefaultTimeout)
+	defer cancel()
+
+	r, err := report.Generate(ctx, reportCompleter(cfg.AI), cfg, start)
+	if err != nil {
+		utils.LogWarning("Failed to write weekly report: %v", err)
+		return
+	}
+
+	if r.Blocked > 0 {
+		popup.ShowInfo("Ringkasan Mingguan 📊", r.Summary)
+	}
+}